@@ -38,8 +38,27 @@ func main() {
 		return
 	}
 
+	// Reuse the default venue created by the migrations, or create one
+	venues, err := db.ListVenues(ctx)
+	if err != nil {
+		fmt.Printf("ListVenues error: %v\n", err)
+		os.Exit(1)
+	}
+	var venueID int64
+	if len(venues) > 0 {
+		venueID = venues[0].ID
+	} else {
+		fmt.Println("Creating venue...")
+		v, err := db.CreateVenue(ctx, repo.NewVenue{Name: "Main Venue", Slug: "main"})
+		if err != nil {
+			fmt.Printf("CreateVenue error: %v\n", err)
+			os.Exit(1)
+		}
+		venueID = v.ID
+	}
+
 	fmt.Println("Creating facility...")
-	err = db.CreateFacility(ctx, "Badminton Court 1", "badminton")
+	err = db.CreateFacility(ctx, venueID, "Badminton Court 1", "badminton")
 	if err != nil {
 		fmt.Printf("CreateFacility error: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,4 @@
+-- 审计日志查询索引（中文注释）：按操作人与时间范围检索
+
+CREATE INDEX IF NOT EXISTS idx_audit_actor ON audit_logs(actor_user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_created_at ON audit_logs(created_at DESC);
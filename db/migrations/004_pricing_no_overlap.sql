@@ -0,0 +1,7 @@
+-- 价格规则防重叠（中文注释）：同设施类型、同星期的小时段不能相交，与接口层校验保持一致
+
+ALTER TABLE pricing_rules ADD CONSTRAINT pricing_rules_no_overlap EXCLUDE USING gist (
+  facility_type WITH =,
+  day_of_week WITH =,
+  int4range(start_hour, end_hour) WITH &&
+);
@@ -0,0 +1,19 @@
+-- 封场扩展（中文注释）：开始/结束生成列便于查询，支持每周重复封场
+
+ALTER TABLE blackouts ADD COLUMN IF NOT EXISTS start_time TIMESTAMPTZ GENERATED ALWAYS AS (lower(time_range)) STORED;
+ALTER TABLE blackouts ADD COLUMN IF NOT EXISTS end_time TIMESTAMPTZ GENERATED ALWAYS AS (upper(time_range)) STORED;
+
+-- recurrence 为空表示一次性封场；weekly 表示自首次时间段起每 7 天重复，直到 recurrence_until（为空则不截止）
+ALTER TABLE blackouts ADD COLUMN IF NOT EXISTS recurrence TEXT NULL CHECK (recurrence IN ('weekly'));
+ALTER TABLE blackouts ADD COLUMN IF NOT EXISTS recurrence_until TIMESTAMPTZ NULL;
+
+DO $$ BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM pg_constraint WHERE conname = 'blackouts_scope_check'
+  ) THEN
+    ALTER TABLE blackouts ADD CONSTRAINT blackouts_scope_check CHECK ((facility_id IS NULL) <> (resource_unit_id IS NULL));
+  END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_blackouts_unit ON blackouts(resource_unit_id, start_time);
+CREATE INDEX IF NOT EXISTS idx_blackouts_facility ON blackouts(facility_id, start_time);
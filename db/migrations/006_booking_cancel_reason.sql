@@ -0,0 +1,3 @@
+-- 预约取消原因（中文注释）：封场处理等场景记录取消原因
+
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS cancel_reason TEXT NULL;
@@ -0,0 +1,8 @@
+-- 单元属性（中文注释）：排序、地面材质、室内/室外、灯光
+
+ALTER TABLE resource_units ADD COLUMN IF NOT EXISTS sort_order INT NOT NULL DEFAULT 0;
+ALTER TABLE resource_units ADD COLUMN IF NOT EXISTS surface_type TEXT NULL;
+ALTER TABLE resource_units ADD COLUMN IF NOT EXISTS is_indoor BOOLEAN NULL;
+ALTER TABLE resource_units ADD COLUMN IF NOT EXISTS has_lighting BOOLEAN NULL;
+
+CREATE INDEX IF NOT EXISTS idx_resource_units_facility_sort ON resource_units(facility_id, sort_order);
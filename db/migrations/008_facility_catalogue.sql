@@ -0,0 +1,22 @@
+-- 设施目录信息（中文注释）：简介、地址与坐标、联系方式、设施标签、照片
+
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS description TEXT NULL;
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS address TEXT NULL;
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION NULL CHECK (latitude BETWEEN -90 AND 90);
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION NULL CHECK (longitude BETWEEN -180 AND 180);
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS contact_phone TEXT NULL;
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS contact_email TEXT NULL;
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS amenities TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_facilities_amenities ON facilities USING gin (amenities);
+
+-- 设施照片：文件保存在存储后端，表中记录对象 key
+CREATE TABLE IF NOT EXISTS facility_photos (
+  id BIGSERIAL PRIMARY KEY,
+  facility_id BIGINT NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+  storage_key TEXT NOT NULL,
+  thumbnail_key TEXT NOT NULL,
+  content_type TEXT NOT NULL,
+  sort_order INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_facility_photos_facility ON facility_photos(facility_id, sort_order);
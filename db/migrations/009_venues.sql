@@ -0,0 +1,47 @@
+-- 多场馆租户（中文注释）：设施、价格规则、预约策略归属场馆；场馆管理员仅能管理其场馆的数据
+
+CREATE TABLE IF NOT EXISTS venues (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  slug TEXT NOT NULL UNIQUE,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- 场馆管理员：user_id 为 Supabase auth.users.id
+CREATE TABLE IF NOT EXISTS venue_admins (
+  user_id UUID NOT NULL,
+  venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, venue_id)
+);
+CREATE INDEX IF NOT EXISTS idx_venue_admins_venue ON venue_admins(venue_id);
+
+-- 默认场馆：已有数据迁移到该场馆
+INSERT INTO venues (name, slug) VALUES ('Main Venue', 'main') ON CONFLICT (slug) DO NOTHING;
+
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS venue_id BIGINT REFERENCES venues(id);
+UPDATE facilities SET venue_id = (SELECT id FROM venues WHERE slug = 'main') WHERE venue_id IS NULL;
+ALTER TABLE facilities ALTER COLUMN venue_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_facilities_venue ON facilities(venue_id);
+
+ALTER TABLE pricing_rules ADD COLUMN IF NOT EXISTS venue_id BIGINT REFERENCES venues(id);
+UPDATE pricing_rules SET venue_id = (SELECT id FROM venues WHERE slug = 'main') WHERE venue_id IS NULL;
+ALTER TABLE pricing_rules ALTER COLUMN venue_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_pricing_rules_venue ON pricing_rules(venue_id, facility_type);
+
+-- 价格规则防重叠改为按场馆区分
+ALTER TABLE pricing_rules DROP CONSTRAINT IF EXISTS pricing_rules_no_overlap;
+ALTER TABLE pricing_rules ADD CONSTRAINT pricing_rules_no_overlap EXCLUDE USING gist (
+  venue_id WITH =,
+  facility_type WITH =,
+  day_of_week WITH =,
+  int4range(start_hour, end_hour) WITH &&
+);
+
+ALTER TABLE reservation_policies ADD COLUMN IF NOT EXISTS venue_id BIGINT REFERENCES venues(id);
+UPDATE reservation_policies SET venue_id = (SELECT id FROM venues WHERE slug = 'main') WHERE venue_id IS NULL;
+ALTER TABLE reservation_policies ALTER COLUMN venue_id SET NOT NULL;
+ALTER TABLE reservation_policies DROP CONSTRAINT IF EXISTS reservation_policies_facility_type_key;
+ALTER TABLE reservation_policies ADD CONSTRAINT reservation_policies_venue_type_key UNIQUE (venue_id, facility_type);
@@ -0,0 +1,41 @@
+-- 设施类型目录（中文注释）：替代 facilities / pricing_rules / reservation_policies 上的 CHECK 列表
+-- 新增类型（如 pickleball、squash）只需在目录中插入一行，无需迁移
+
+CREATE TABLE IF NOT EXISTS facility_types (
+  code TEXT PRIMARY KEY CHECK (code ~ '^[a-z][a-z0-9_]{1,31}$'),
+  display_name TEXT NOT NULL,
+  display_name_zh TEXT NULL,
+  icon TEXT NULL,
+  sort_order INT NOT NULL DEFAULT 0,
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  -- 默认预约策略（场馆未在 reservation_policies 中单独配置时使用）
+  min_duration_minutes INT NOT NULL DEFAULT 60 CHECK (min_duration_minutes > 0),
+  max_duration_minutes INT NOT NULL DEFAULT 120 CHECK (max_duration_minutes >= min_duration_minutes),
+  slot_granularity_minutes INT NOT NULL DEFAULT 30 CHECK (slot_granularity_minutes >= 5),
+  advance_booking_days INT NOT NULL DEFAULT 30 CHECK (advance_booking_days >= 0),
+  cancellation_cutoff_minutes INT NOT NULL DEFAULT 120 CHECK (cancellation_cutoff_minutes >= 0),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO facility_types (code, display_name, display_name_zh, icon, sort_order,
+  min_duration_minutes, max_duration_minutes, slot_granularity_minutes, advance_booking_days, cancellation_cutoff_minutes)
+VALUES
+  ('badminton',    'Badminton',         '羽毛球', 'badminton', 10, 60, 120, 30, 30, 120),
+  ('tennis',       'Tennis',            '网球',   'tennis',    20, 60, 120, 30, 30, 120),
+  ('gym',          'Gym',               '健身房', 'gym',       30, 30, 240, 30, 14,  60),
+  ('multipurpose', 'Multipurpose Hall', '多功能厅', 'hall',    40, 60, 360, 60, 30, 240),
+  ('other',        'Other',             '其它',   'other',     90, 60, 180, 30, 14, 120)
+ON CONFLICT (code) DO NOTHING;
+
+-- 去掉硬编码 CHECK，改为引用目录
+ALTER TABLE facilities DROP CONSTRAINT IF EXISTS facilities_type_check;
+ALTER TABLE facilities ADD CONSTRAINT facilities_type_fkey
+  FOREIGN KEY (type) REFERENCES facility_types(code) ON UPDATE CASCADE;
+
+ALTER TABLE pricing_rules DROP CONSTRAINT IF EXISTS pricing_rules_facility_type_check;
+ALTER TABLE pricing_rules ADD CONSTRAINT pricing_rules_facility_type_fkey
+  FOREIGN KEY (facility_type) REFERENCES facility_types(code) ON UPDATE CASCADE;
+
+ALTER TABLE reservation_policies DROP CONSTRAINT IF EXISTS reservation_policies_facility_type_check;
+ALTER TABLE reservation_policies ADD CONSTRAINT reservation_policies_facility_type_fkey
+  FOREIGN KEY (facility_type) REFERENCES facility_types(code) ON UPDATE CASCADE;
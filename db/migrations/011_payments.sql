@@ -0,0 +1,38 @@
+-- 支付（中文注释）：需要付费的预约先以 pending 占位，支付成功后确认；支付与退款记录独立存表
+
+CREATE TABLE IF NOT EXISTS payments (
+  id BIGSERIAL PRIMARY KEY,
+  booking_id BIGINT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL, -- Supabase auth.users.id
+  provider TEXT NOT NULL,
+  provider_ref TEXT NOT NULL UNIQUE, -- 渠道侧支付意图 ID
+  amount NUMERIC(10,2) NOT NULL CHECK (amount > 0),
+  refunded_amount NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0 AND refunded_amount <= amount),
+  currency TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('requires_payment','succeeded','failed','cancelled','partially_refunded','refunded')),
+  failure_reason TEXT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_payments_booking ON payments(booking_id);
+-- 释放超时占位时按状态与创建时间扫描
+CREATE INDEX IF NOT EXISTS idx_payments_open ON payments(created_at) WHERE status = 'requires_payment';
+
+CREATE TABLE IF NOT EXISTS payment_refunds (
+  id BIGSERIAL PRIMARY KEY,
+  payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+  booking_id BIGINT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+  amount NUMERIC(10,2) NOT NULL CHECK (amount > 0),
+  provider_ref TEXT NOT NULL,
+  status TEXT NOT NULL,
+  reason TEXT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_booking ON payment_refunds(booking_id);
+
+-- 取消的预约（含支付失败或超时释放的占位）不再占用时段
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_no_overlap;
+ALTER TABLE bookings ADD CONSTRAINT bookings_no_overlap EXCLUDE USING gist (
+  resource_unit_id WITH =,
+  time_range WITH &&
+) WHERE (status <> 'cancelled');
@@ -0,0 +1,11 @@
+-- 退款规则（中文注释）：按设施类型配置，开场前 refund_full_hours 小时以上全额退款，
+-- refund_partial_hours 小时以上按 refund_partial_percent 退款，之后不退
+
+ALTER TABLE facility_types ADD COLUMN IF NOT EXISTS refund_full_hours INT NOT NULL DEFAULT 24 CHECK (refund_full_hours >= 0);
+ALTER TABLE facility_types ADD COLUMN IF NOT EXISTS refund_partial_hours INT NOT NULL DEFAULT 6 CHECK (refund_partial_hours >= 0);
+ALTER TABLE facility_types ADD COLUMN IF NOT EXISTS refund_partial_percent INT NOT NULL DEFAULT 50 CHECK (refund_partial_percent BETWEEN 0 AND 100);
+ALTER TABLE facility_types ADD CONSTRAINT facility_types_refund_window_check CHECK (refund_partial_hours <= refund_full_hours);
+
+-- 退款记录：命中的规则与比例（full/partial/override/manual）
+ALTER TABLE payment_refunds ADD COLUMN IF NOT EXISTS policy TEXT NULL;
+ALTER TABLE payment_refunds ADD COLUMN IF NOT EXISTS percent INT NULL CHECK (percent BETWEEN 0 AND 100);
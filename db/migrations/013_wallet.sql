@@ -0,0 +1,65 @@
+-- 钱包（中文注释）：只追加的流水账，余额由流水累计；扣款与确认预约在数据库函数中原子完成
+
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+  id BIGSERIAL PRIMARY KEY,
+  user_id UUID NOT NULL, -- Supabase auth.users.id
+  type TEXT NOT NULL CHECK (type IN ('topup','booking_charge','refund','adjustment')),
+  amount NUMERIC(10,2) NOT NULL CHECK (amount <> 0), -- 入账为正、扣款为负
+  balance_after NUMERIC(10,2) NOT NULL CHECK (balance_after >= 0),
+  booking_id BIGINT NULL REFERENCES bookings(id) ON DELETE SET NULL,
+  note TEXT NULL,
+  created_by UUID NULL, -- 操作人（管理员充值/调整时）
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_user ON wallet_transactions(user_id, id DESC);
+
+-- 只追加：禁止修改与删除
+CREATE OR REPLACE FUNCTION wallet_transactions_append_only() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  RAISE EXCEPTION 'wallet_transactions is append-only';
+END $$;
+
+DROP TRIGGER IF EXISTS wallet_transactions_no_update ON wallet_transactions;
+CREATE TRIGGER wallet_transactions_no_update
+  BEFORE UPDATE OR DELETE ON wallet_transactions
+  FOR EACH ROW EXECUTE FUNCTION wallet_transactions_append_only();
+
+-- 追加流水：按用户加事务级咨询锁，串行计算余额；余额不足抛出 P0402
+CREATE OR REPLACE FUNCTION wallet_post(
+  p_user_id UUID, p_type TEXT, p_amount NUMERIC, p_booking_id BIGINT, p_note TEXT, p_created_by UUID
+) RETURNS SETOF wallet_transactions
+LANGUAGE plpgsql AS $$
+DECLARE
+  v_balance NUMERIC;
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtextextended('wallet:' || p_user_id::text, 0));
+  SELECT COALESCE(SUM(amount), 0) INTO v_balance FROM wallet_transactions WHERE user_id = p_user_id;
+  IF v_balance + p_amount < 0 THEN
+    RAISE EXCEPTION 'insufficient wallet balance' USING ERRCODE = 'P0402';
+  END IF;
+  RETURN QUERY
+    INSERT INTO wallet_transactions (user_id, type, amount, balance_after, booking_id, note, created_by)
+    VALUES (p_user_id, p_type, p_amount, v_balance + p_amount, p_booking_id, NULLIF(p_note, ''), p_created_by)
+    RETURNING *;
+END $$;
+
+-- 钱包支付占位预约：扣款、确认预约、写入支付记录在同一事务中
+CREATE OR REPLACE FUNCTION wallet_pay_booking(
+  p_booking_id BIGINT, p_user_id UUID, p_amount NUMERIC, p_currency TEXT
+) RETURNS SETOF payments
+LANGUAGE plpgsql AS $$
+DECLARE
+  v_tx wallet_transactions;
+BEGIN
+  SELECT * INTO v_tx FROM wallet_post(p_user_id, 'booking_charge', -p_amount, p_booking_id, 'booking payment', p_user_id);
+  UPDATE bookings SET status = 'confirmed'
+    WHERE id = p_booking_id AND user_id = p_user_id AND status = 'pending';
+  IF NOT FOUND THEN
+    RAISE EXCEPTION 'booking % is not awaiting payment', p_booking_id;
+  END IF;
+  RETURN QUERY
+    INSERT INTO payments (booking_id, user_id, provider, provider_ref, amount, currency, status)
+    VALUES (p_booking_id, p_user_id, 'wallet', 'wallet_tx_' || v_tx.id, p_amount, p_currency, 'succeeded')
+    RETURNING *;
+END $$;
@@ -0,0 +1,29 @@
+-- 会员（中文注释）：会员方案定义折扣与预约特权，用户在有效期内持有方案
+-- venue_id 为空表示全平台通用方案；预约策略覆盖项为空时沿用场馆/设施类型默认值
+
+CREATE TABLE IF NOT EXISTS membership_plans (
+  id BIGSERIAL PRIMARY KEY,
+  venue_id BIGINT NULL REFERENCES venues(id),
+  name TEXT NOT NULL,
+  description TEXT NULL,
+  discount_percent INT NOT NULL DEFAULT 0 CHECK (discount_percent BETWEEN 0 AND 100), -- 在价格规则计算结果上打折
+  advance_booking_days INT NULL CHECK (advance_booking_days >= 0), -- 覆盖可提前预订天数
+  max_duration_minutes INT NULL CHECK (max_duration_minutes > 0), -- 覆盖单次最长时长
+  duration_days INT NOT NULL DEFAULT 30 CHECK (duration_days > 0), -- 发放会员时的默认有效天数
+  is_active BOOLEAN NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_membership_plans_venue ON membership_plans(venue_id);
+
+CREATE TABLE IF NOT EXISTS user_memberships (
+  id BIGSERIAL PRIMARY KEY,
+  user_id UUID NOT NULL, -- Supabase auth.users.id
+  plan_id BIGINT NOT NULL REFERENCES membership_plans(id),
+  starts_at TIMESTAMPTZ NOT NULL,
+  ends_at TIMESTAMPTZ NOT NULL,
+  created_by UUID NULL, -- 发放的管理员
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (ends_at >= starts_at) -- 撤销时将 ends_at 提前，未开始的会员撤销后为空区间
+);
+-- 预约时按用户与有效期查询当前会员
+CREATE INDEX IF NOT EXISTS idx_user_memberships_user ON user_memberships(user_id, ends_at);
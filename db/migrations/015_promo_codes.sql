@@ -0,0 +1,60 @@
+-- 优惠码（中文注释）：按比例或固定金额优惠，支持有效期、设施类型限制、总次数与每人次数上限
+-- 核销记录与预约关联；预约取消时核销被撤销，不再占用次数
+
+CREATE TABLE IF NOT EXISTS promo_codes (
+  id BIGSERIAL PRIMARY KEY,
+  code TEXT NOT NULL UNIQUE, -- 统一存大写
+  description TEXT NULL,
+  venue_id BIGINT NULL REFERENCES venues(id), -- 为空表示全平台通用
+  discount_type TEXT NOT NULL CHECK (discount_type IN ('percent','fixed')),
+  discount_value NUMERIC(10,2) NOT NULL CHECK (discount_value > 0),
+  facility_types TEXT[] NULL, -- 为空表示不限设施类型
+  starts_at TIMESTAMPTZ NULL,
+  ends_at TIMESTAMPTZ NULL,
+  max_redemptions INT NULL CHECK (max_redemptions > 0), -- 总次数上限
+  per_user_limit INT NULL CHECK (per_user_limit > 0), -- 每人次数上限
+  is_active BOOLEAN NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (discount_type <> 'percent' OR discount_value <= 100),
+  CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+  id BIGSERIAL PRIMARY KEY,
+  promo_code_id BIGINT NOT NULL REFERENCES promo_codes(id),
+  booking_id BIGINT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL, -- Supabase auth.users.id
+  discount_amount NUMERIC(10,2) NOT NULL CHECK (discount_amount >= 0),
+  status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active','reversed')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  reversed_at TIMESTAMPTZ NULL,
+  UNIQUE (promo_code_id, booking_id)
+);
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_code ON promo_redemptions(promo_code_id, user_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_booking ON promo_redemptions(booking_id);
+
+-- 核销：按优惠码加事务级咨询锁，串行校验次数上限；超限抛出 P0409
+CREATE OR REPLACE FUNCTION promo_redeem(
+  p_code_id BIGINT, p_booking_id BIGINT, p_user_id UUID, p_discount NUMERIC
+) RETURNS SETOF promo_redemptions
+LANGUAGE plpgsql AS $$
+DECLARE
+  v_code promo_codes;
+  v_total INT;
+  v_user INT;
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtextextended('promo:' || p_code_id::text, 0));
+  SELECT * INTO v_code FROM promo_codes WHERE id = p_code_id;
+  SELECT count(*), count(*) FILTER (WHERE user_id = p_user_id) INTO v_total, v_user
+    FROM promo_redemptions WHERE promo_code_id = p_code_id AND status = 'active';
+  IF v_code.max_redemptions IS NOT NULL AND v_total >= v_code.max_redemptions THEN
+    RAISE EXCEPTION 'promo code usage limit reached' USING ERRCODE = 'P0409';
+  END IF;
+  IF v_code.per_user_limit IS NOT NULL AND v_user >= v_code.per_user_limit THEN
+    RAISE EXCEPTION 'promo code already used' USING ERRCODE = 'P0409';
+  END IF;
+  RETURN QUERY
+    INSERT INTO promo_redemptions (promo_code_id, booking_id, user_id, discount_amount)
+    VALUES (p_code_id, p_booking_id, p_user_id, p_discount)
+    RETURNING *;
+END $$;
@@ -0,0 +1,25 @@
+-- 分摊支付（中文注释）：预约金额按人数拆分为份额，每份通过独立链接（token）支付；
+-- 全部份额付清或发起人补足剩余金额后确认预约，截止时间前未付清则释放预约并退回已付份额
+
+CREATE TABLE IF NOT EXISTS booking_shares (
+  id BIGSERIAL PRIMARY KEY,
+  booking_id BIGINT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+  user_id UUID NULL, -- 已注册用户
+  email TEXT NULL, -- 未注册的受邀人
+  is_organizer BOOLEAN NOT NULL DEFAULT false,
+  amount NUMERIC(10,2) NOT NULL CHECK (amount > 0),
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','paid','covered','released')),
+  token TEXT NOT NULL UNIQUE, -- 支付链接凭证
+  deadline TIMESTAMPTZ NOT NULL,
+  paid_at TIMESTAMPTZ NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (user_id IS NOT NULL OR email IS NOT NULL)
+);
+CREATE INDEX IF NOT EXISTS idx_booking_shares_booking ON booking_shares(booking_id);
+-- 释放逾期份额时按截止时间扫描
+CREATE INDEX IF NOT EXISTS idx_booking_shares_open ON booking_shares(deadline) WHERE status = 'pending';
+
+-- 支付记录区分整单、份额与发起人补足；份额可由未注册用户支付
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'booking' CHECK (kind IN ('booking','share','cover'));
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS share_id BIGINT NULL REFERENCES booking_shares(id) ON DELETE SET NULL;
+ALTER TABLE payments ALTER COLUMN user_id DROP NOT NULL;
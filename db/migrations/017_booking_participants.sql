@@ -0,0 +1,41 @@
+-- 预约参与人（中文注释）：已注册用户或具名访客；人数（预订人 + 参与人）受单元上限约束，
+-- 参与人可在“我的预约”中看到该预约，签到按此人数统计
+
+-- 单元人数上限（含预订人）；NULL 表示不限
+ALTER TABLE resource_units ADD COLUMN IF NOT EXISTS max_participants INT NULL CHECK (max_participants >= 1);
+
+CREATE TABLE IF NOT EXISTS booking_participants (
+  id BIGSERIAL PRIMARY KEY,
+  booking_id BIGINT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+  user_id UUID NULL, -- 已注册用户
+  guest_name TEXT NULL, -- 未注册访客
+  added_by UUID NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK ((user_id IS NULL) <> (guest_name IS NULL))
+);
+CREATE INDEX IF NOT EXISTS idx_booking_participants_booking ON booking_participants(booking_id);
+-- 同一用户在同一预约中只出现一次；“我的预约”按用户查询
+CREATE UNIQUE INDEX IF NOT EXISTS idx_booking_participants_user ON booking_participants(user_id, booking_id) WHERE user_id IS NOT NULL;
+
+-- 添加参与人：按预约加事务级咨询锁，串行校验单元人数上限；超限抛出 P0409
+CREATE OR REPLACE FUNCTION booking_participant_add(
+  p_booking_id BIGINT, p_user_id UUID, p_guest_name TEXT, p_added_by UUID
+) RETURNS SETOF booking_participants
+LANGUAGE plpgsql AS $$
+DECLARE
+  v_max INT;
+  v_count INT;
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtextextended('participants:' || p_booking_id::text, 0));
+  SELECT u.max_participants INTO v_max
+    FROM bookings b JOIN resource_units u ON u.id = b.resource_unit_id
+    WHERE b.id = p_booking_id;
+  SELECT count(*) INTO v_count FROM booking_participants WHERE booking_id = p_booking_id;
+  IF v_max IS NOT NULL AND v_count + 1 >= v_max THEN
+    RAISE EXCEPTION 'participant limit reached' USING ERRCODE = 'P0409';
+  END IF;
+  RETURN QUERY
+    INSERT INTO booking_participants (booking_id, user_id, guest_name, added_by)
+    VALUES (p_booking_id, p_user_id, p_guest_name, p_added_by)
+    RETURNING *;
+END $$;
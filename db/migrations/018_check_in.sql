@@ -0,0 +1,39 @@
+-- 签到与爽约（中文注释）：记录签到时间与到场人数；开场后宽限期内无人签到的已确认预约标记为 no_show，
+-- 剩余时段释放给其他人预约；一段时间内爽约次数达到阈值的用户被临时禁止预约
+
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_status_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_status_check CHECK (status IN ('pending','confirmed','cancelled','no_show'));
+
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS checked_in_at TIMESTAMPTZ NULL;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS checked_in_by UUID NULL; -- 签到操作人（工作人员或本人）
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS checked_in_count INT NULL CHECK (checked_in_count >= 1); -- 到场人数
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS no_show_at TIMESTAMPTZ NULL;
+
+-- 爽约的预约不再占用时段
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_no_overlap;
+ALTER TABLE bookings ADD CONSTRAINT bookings_no_overlap EXCLUDE USING gist (
+  resource_unit_id WITH =,
+  time_range WITH &&
+) WHERE (status NOT IN ('cancelled','no_show'));
+
+-- 爽约扫描：已确认且未签到的预约按开场时间扫描
+CREATE INDEX IF NOT EXISTS idx_bookings_check_in_due ON bookings(start_time) WHERE status = 'confirmed' AND checked_in_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_bookings_no_show_user ON bookings(user_id, no_show_at) WHERE status = 'no_show';
+
+-- 单元签到码：场地张贴的二维码内容，自助签到时提交
+ALTER TABLE resource_units ADD COLUMN IF NOT EXISTS check_in_code TEXT NOT NULL DEFAULT md5(random()::text || clock_timestamp()::text);
+
+-- 预约禁令：爽约达到阈值时自动创建，管理员可提前解除
+CREATE TABLE IF NOT EXISTS booking_bans (
+  id BIGSERIAL PRIMARY KEY,
+  user_id UUID NOT NULL,
+  reason TEXT NOT NULL,
+  no_show_count INT NOT NULL DEFAULT 0,
+  starts_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  ends_at TIMESTAMPTZ NOT NULL,
+  lifted_at TIMESTAMPTZ NULL,
+  lifted_by UUID NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (ends_at > starts_at)
+);
+CREATE INDEX IF NOT EXISTS idx_booking_bans_user ON booking_bans(user_id, ends_at DESC);
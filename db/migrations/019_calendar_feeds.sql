@@ -0,0 +1,14 @@
+-- 日历订阅（中文注释）：每个用户一条个人预约订阅，管理员可为所管设施创建占用时段订阅；
+-- token 即订阅地址中的凭证，更换后旧地址失效
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+  id BIGSERIAL PRIMARY KEY,
+  token TEXT NOT NULL UNIQUE,
+  user_id UUID NOT NULL, -- 订阅所有者
+  facility_id BIGINT NULL REFERENCES facilities(id) ON DELETE CASCADE, -- NULL 为个人预约订阅
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at TIMESTAMPTZ NULL
+);
+-- 每个用户（或每个用户每个设施）只有一条有效订阅
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_user ON calendar_feeds(user_id) WHERE facility_id IS NULL AND revoked_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_facility ON calendar_feeds(user_id, facility_id) WHERE facility_id IS NOT NULL AND revoked_at IS NULL;
@@ -0,0 +1,60 @@
+-- 邮件通知（中文注释）：业务事件渲染为邮件后写入发件箱，后台任务投递；
+-- 投递失败按指数退避重试，超过次数上限标记为 failed，管理员可手动重新投递
+
+-- 通知语言（zh / en）；邮箱默认取 Supabase 用户邮箱，profiles.email 可覆盖
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'zh';
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS email TEXT NULL;
+DO $$ BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM pg_constraint WHERE conname = 'profiles_locale_check'
+  ) THEN
+    ALTER TABLE profiles ADD CONSTRAINT profiles_locale_check CHECK (locale IN ('zh','en'));
+  END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS notification_outbox (
+  id BIGSERIAL PRIMARY KEY,
+  user_id UUID NOT NULL,
+  kind TEXT NOT NULL, -- 通知类型，如 booking_confirmed
+  booking_id BIGINT NULL REFERENCES bookings(id) ON DELETE SET NULL,
+  locale TEXT NOT NULL,
+  recipient TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  body TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','sent','failed')),
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_error TEXT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  sent_at TIMESTAMPTZ NULL
+);
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_user ON notification_outbox(user_id, created_at DESC);
+
+-- 收件人：profiles.email 优先，否则取 auth.users.email；语言缺省为 zh
+CREATE OR REPLACE FUNCTION notification_contact(p_user_id UUID)
+RETURNS TABLE (email TEXT, locale TEXT)
+LANGUAGE sql STABLE SECURITY DEFINER SET search_path = public, auth AS $$
+  SELECT COALESCE(p.email, u.email)::TEXT, COALESCE(p.locale, 'zh')
+  FROM (SELECT p_user_id AS id) k
+  LEFT JOIN auth.users u ON u.id = k.id
+  LEFT JOIN profiles p ON p.user_id = k.id;
+$$;
+
+-- 领取待投递邮件：SKIP LOCKED 保证多实例不重复领取；领取即计一次尝试，
+-- 并把 next_attempt_at 推后一个租期，实例中途退出时租期过后由其它实例重新领取
+CREATE OR REPLACE FUNCTION notification_outbox_claim(p_limit INT, p_lease_seconds INT)
+RETURNS SETOF notification_outbox
+LANGUAGE sql AS $$
+  UPDATE notification_outbox o
+  SET attempts = o.attempts + 1,
+      next_attempt_at = now() + make_interval(secs => p_lease_seconds)
+  WHERE o.id IN (
+    SELECT id FROM notification_outbox
+    WHERE status = 'pending' AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT p_limit
+    FOR UPDATE SKIP LOCKED
+  )
+  RETURNING o.*;
+$$;
@@ -0,0 +1,47 @@
+-- 预约提醒（中文注释）：开场前 24 小时、2 小时提醒与结束后评分邀请由进程内定时任务发送；
+-- 多实例部署时经 scheduler_leases 选出一个实例执行扫描，booking_reminders 保证每种提醒只发送一次
+
+-- 用户退订：reminder_opt_out 关闭开场前提醒，rating_opt_out 关闭评分邀请
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS reminder_opt_out BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS rating_opt_out BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS booking_reminders (
+  booking_id BIGINT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL, -- booking_reminder_24h / booking_reminder_2h / rating_prompt
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (booking_id, kind)
+);
+
+-- 定时任务租约：持有者在 expires_at 前续约，过期后其它实例可接管
+CREATE TABLE IF NOT EXISTS scheduler_leases (
+  name TEXT PRIMARY KEY,
+  holder TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- 获取或续约租约：无人持有、已过期或本实例持有时成功
+CREATE OR REPLACE FUNCTION scheduler_acquire(p_name TEXT, p_holder TEXT, p_ttl_seconds INT)
+RETURNS BOOLEAN
+LANGUAGE sql AS $$
+  WITH up AS (
+    INSERT INTO scheduler_leases (name, holder, expires_at)
+    VALUES (p_name, p_holder, now() + make_interval(secs => p_ttl_seconds))
+    ON CONFLICT (name) DO UPDATE
+      SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+      WHERE scheduler_leases.holder = EXCLUDED.holder OR scheduler_leases.expires_at < now()
+    RETURNING 1
+  )
+  SELECT EXISTS (SELECT 1 FROM up);
+$$;
+
+-- 收件人信息增加退订设置（返回列变化需先删除旧函数）
+DROP FUNCTION IF EXISTS notification_contact(UUID);
+CREATE FUNCTION notification_contact(p_user_id UUID)
+RETURNS TABLE (email TEXT, locale TEXT, reminder_opt_out BOOLEAN, rating_opt_out BOOLEAN)
+LANGUAGE sql STABLE SECURITY DEFINER SET search_path = public, auth AS $$
+  SELECT COALESCE(p.email, u.email)::TEXT, COALESCE(p.locale, 'zh'),
+         COALESCE(p.reminder_opt_out, false), COALESCE(p.rating_opt_out, false)
+  FROM (SELECT p_user_id AS id) k
+  LEFT JOIN auth.users u ON u.id = k.id
+  LEFT JOIN profiles p ON p.user_id = k.id;
+$$;
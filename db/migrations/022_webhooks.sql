@@ -0,0 +1,63 @@
+-- 对外 Webhook（中文注释）：平台管理员登记回调地址并订阅事件类型；预约与封场写操作发生后
+-- 为每个订阅的地址写入一条投递记录，后台任务签名后 POST，失败按指数退避重试，每次尝试都会记录
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+  id BIGSERIAL PRIMARY KEY,
+  url TEXT NOT NULL,
+  secret TEXT NOT NULL, -- HMAC-SHA256 签名密钥，仅创建时返回
+  event_types TEXT[] NOT NULL, -- booking.created / booking.cancelled / booking.rescheduled / blackout.created
+  description TEXT NULL,
+  is_active BOOLEAN NOT NULL DEFAULT true,
+  created_by UUID NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_event_types ON webhook_endpoints USING GIN (event_types) WHERE is_active;
+
+-- 投递记录：同一事件投递到每个地址各一条；event_id 在重放时保持不变，接收方可据此去重
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  endpoint_id BIGINT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+  event_id TEXT NOT NULL,
+  event_type TEXT NOT NULL,
+  payload JSONB NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','succeeded','failed')),
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_status_code INT NULL,
+  last_error TEXT NULL,
+  replay_of BIGINT NULL REFERENCES webhook_deliveries(id) ON DELETE SET NULL, -- 手动重放时指向原投递
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  delivered_at TIMESTAMPTZ NULL
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_id, created_at DESC);
+
+-- 每次投递尝试：响应状态码（无响应时为空）、错误与耗时
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+  id BIGSERIAL PRIMARY KEY,
+  delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+  attempt INT NOT NULL,
+  status_code INT NULL,
+  error TEXT NULL,
+  duration_ms INT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts(delivery_id, attempt);
+
+-- 领取待投递记录：与 notification_outbox_claim 相同，SKIP LOCKED 防止多实例重复领取，
+-- 领取即计一次尝试并推后一个租期
+CREATE OR REPLACE FUNCTION webhook_deliveries_claim(p_limit INT, p_lease_seconds INT)
+RETURNS SETOF webhook_deliveries
+LANGUAGE sql AS $$
+  UPDATE webhook_deliveries d
+  SET attempts = d.attempts + 1,
+      next_attempt_at = now() + make_interval(secs => p_lease_seconds)
+  WHERE d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT p_limit
+    FOR UPDATE SKIP LOCKED
+  )
+  RETURNING d.*;
+$$;
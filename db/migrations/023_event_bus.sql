@@ -0,0 +1,10 @@
+-- 跨实例事件总线（中文注释）：EVENT_BROKER=db 时每个实例把本实例产生的领域事件写入 bus_events，
+-- 并按 seq 轮询其它实例写入的事件，转发给本实例的实时订阅（如可用时段推送）；只保留最近几分钟的数据
+
+CREATE TABLE IF NOT EXISTS bus_events (
+  seq BIGSERIAL PRIMARY KEY,
+  origin TEXT NOT NULL, -- 发出事件的实例，轮询时跳过本实例
+  event JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_bus_events_created ON bus_events(created_at);
@@ -0,0 +1,82 @@
+-- 预约变更流（中文注释）：bookings 的每次新增、修改与删除由触发器追加一条 booking_events，
+-- 覆盖应用写入与数据库函数（钱包支付、签到等）的全部路径；seq 单调递增，消费方按 GET /events?after= 断点续读；
+-- changes 为新增时的整行或修改时变化的列，按 seq 依次合并即可重建预约当前状态
+
+CREATE TABLE IF NOT EXISTS booking_events (
+  seq BIGSERIAL PRIMARY KEY,
+  booking_id BIGINT NOT NULL, -- 不设外键，预约删除后变更记录保留
+  venue_id BIGINT NULL, -- 写入时所属场馆，用于租户范围过滤
+  event_type TEXT NOT NULL, -- booking.created / confirmed / cancelled / rescheduled / checked_in / no_show / updated / deleted / snapshot
+  changes JSONB NULL, -- 删除时为空
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_booking_events_booking ON booking_events(booking_id, seq);
+CREATE INDEX IF NOT EXISTS idx_booking_events_venue ON booking_events(venue_id, seq);
+
+-- 只追加：禁止修改与删除
+CREATE OR REPLACE FUNCTION booking_events_append_only() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  RAISE EXCEPTION 'booking_events is append-only';
+END $$;
+
+DROP TRIGGER IF EXISTS booking_events_no_update ON booking_events;
+CREATE TRIGGER booking_events_no_update
+  BEFORE UPDATE OR DELETE ON booking_events
+  FOR EACH ROW EXECUTE FUNCTION booking_events_append_only();
+
+-- 追加变更：加事务级咨询锁串行写入，保证提交顺序与 seq 一致，消费方按 seq 读取不会跳过稍后提交的较小序号
+CREATE OR REPLACE FUNCTION booking_events_record() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+  v_row bookings%ROWTYPE;
+  v_type TEXT;
+  v_changes JSONB;
+  v_venue_id BIGINT;
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtext('booking_events'));
+  IF TG_OP = 'DELETE' THEN
+    v_row := OLD;
+    v_type := 'booking.deleted';
+  ELSIF TG_OP = 'INSERT' THEN
+    v_row := NEW;
+    v_type := 'booking.created';
+    v_changes := to_jsonb(NEW) - 'time_range';
+  ELSE
+    v_row := NEW;
+    SELECT jsonb_object_agg(n.key, n.value) INTO v_changes
+      FROM jsonb_each(to_jsonb(NEW) - 'time_range') n
+      WHERE n.value IS DISTINCT FROM (to_jsonb(OLD) -> n.key);
+    IF v_changes IS NULL THEN
+      RETURN NULL; -- 无变化的更新不记录
+    END IF;
+    v_type := CASE
+      WHEN NEW.status IS DISTINCT FROM OLD.status AND NEW.status = 'confirmed' THEN 'booking.confirmed'
+      WHEN NEW.status IS DISTINCT FROM OLD.status AND NEW.status = 'cancelled' THEN 'booking.cancelled'
+      WHEN NEW.status IS DISTINCT FROM OLD.status AND NEW.status = 'no_show' THEN 'booking.no_show'
+      WHEN NEW.checked_in_at IS NOT NULL AND OLD.checked_in_at IS NULL THEN 'booking.checked_in'
+      WHEN NEW.time_range IS DISTINCT FROM OLD.time_range OR NEW.resource_unit_id IS DISTINCT FROM OLD.resource_unit_id THEN 'booking.rescheduled'
+      ELSE 'booking.updated'
+    END;
+  END IF;
+  SELECT f.venue_id INTO v_venue_id
+    FROM resource_units u JOIN facilities f ON f.id = u.facility_id
+    WHERE u.id = v_row.resource_unit_id;
+  INSERT INTO booking_events (booking_id, venue_id, event_type, changes)
+    VALUES (v_row.id, v_venue_id, v_type, v_changes);
+  RETURN NULL;
+END $$;
+
+DROP TRIGGER IF EXISTS bookings_record_events ON bookings;
+CREATE TRIGGER bookings_record_events
+  AFTER INSERT OR UPDATE OR DELETE ON bookings
+  FOR EACH ROW EXECUTE FUNCTION booking_events_record();
+
+-- 已有预约补一条 booking.snapshot（整行），重建时以此为起点；仅在变更流为空时执行
+INSERT INTO booking_events (booking_id, venue_id, event_type, changes)
+SELECT b.id, f.venue_id, 'booking.snapshot', to_jsonb(b) - 'time_range'
+  FROM bookings b
+  JOIN resource_units u ON u.id = b.resource_unit_id
+  JOIN facilities f ON f.id = u.facility_id
+  WHERE NOT EXISTS (SELECT 1 FROM booking_events)
+  ORDER BY b.id;
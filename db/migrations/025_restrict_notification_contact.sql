@@ -0,0 +1,6 @@
+-- 收紧收件人查询（中文注释）：notification_contact 以 SECURITY DEFINER 读取 auth.users 邮箱，
+-- 函数默认对 PUBLIC 开放执行，持有 anon key 即可经 rpc/notification_contact 查询任意用户邮箱；
+-- 只允许 service_role 调用，后端通过 SUPABASE_SERVICE_ROLE_KEY 访问
+
+REVOKE EXECUTE ON FUNCTION notification_contact(UUID) FROM PUBLIC, anon, authenticated;
+GRANT EXECUTE ON FUNCTION notification_contact(UUID) TO service_role;
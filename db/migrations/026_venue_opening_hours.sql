@@ -0,0 +1,7 @@
+-- 场馆营业时间（中文注释）：按场馆本地时间（venues.timezone）配置每天的开门与关门时间，
+-- 可用时段按场馆时区计算当天的营业窗口；closes_at 可为 24:00 表示营业到午夜
+
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS opens_at TIME NOT NULL DEFAULT '08:00';
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS closes_at TIME NOT NULL DEFAULT '22:00';
+ALTER TABLE venues DROP CONSTRAINT IF EXISTS venues_opening_hours_check;
+ALTER TABLE venues ADD CONSTRAINT venues_opening_hours_check CHECK (opens_at < closes_at);
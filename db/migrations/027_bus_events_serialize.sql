@@ -0,0 +1,17 @@
+-- 跨实例事件串行写入（中文注释）：BIGSERIAL 在写入时分配 seq，并发事务可能先提交较大的序号，
+-- 轮询方已越过的较小序号稍后提交时会被跳过；写入前加事务级咨询锁并在锁内重新分配 seq，
+-- 保证提交顺序与 seq 一致（同 024_booking_events.sql）
+
+CREATE OR REPLACE FUNCTION bus_events_assign_seq() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtext('bus_events'));
+  -- 列默认值在触发器之前求值，需在持锁后重新取号
+  NEW.seq := nextval(pg_get_serial_sequence('bus_events', 'seq'));
+  RETURN NEW;
+END $$;
+
+DROP TRIGGER IF EXISTS bus_events_serialize ON bus_events;
+CREATE TRIGGER bus_events_serialize
+  BEFORE INSERT ON bus_events
+  FOR EACH ROW EXECUTE FUNCTION bus_events_assign_seq();
@@ -0,0 +1,10 @@
+-- 收紧钱包记账（中文注释）：wallet_post 与 wallet_pay_booking 默认对 PUBLIC 开放执行，
+-- 持有公开的 anon key 即可经 rpc/wallet_post 以 topup 为任意用户充值；流水表同样不允许客户端直接写入。
+-- 只允许 service_role 执行，后端通过 SUPABASE_SERVICE_ROLE_KEY 调用
+
+REVOKE EXECUTE ON FUNCTION wallet_post(UUID, TEXT, NUMERIC, BIGINT, TEXT, UUID) FROM PUBLIC, anon, authenticated;
+REVOKE EXECUTE ON FUNCTION wallet_pay_booking(BIGINT, UUID, NUMERIC, TEXT) FROM PUBLIC, anon, authenticated;
+GRANT EXECUTE ON FUNCTION wallet_post(UUID, TEXT, NUMERIC, BIGINT, TEXT, UUID) TO service_role;
+GRANT EXECUTE ON FUNCTION wallet_pay_booking(BIGINT, UUID, NUMERIC, TEXT) TO service_role;
+
+REVOKE INSERT, UPDATE, DELETE ON wallet_transactions FROM anon, authenticated;
@@ -1,11 +1,16 @@
 -- 初始化基础设施与球场（中文注释）：按需求创建 8 个羽毛球、8 个网球等
 
-INSERT INTO facilities (name, type) VALUES
+-- 默认场馆由 009_venues 迁移创建
+INSERT INTO facilities (venue_id, name, type)
+SELECT v.id, x.name, x.type
+FROM venues v CROSS JOIN (VALUES
   ('Badminton', 'badminton'),
   ('Tennis', 'tennis'),
   ('Gym', 'gym'),
   ('Multipurpose Hall', 'multipurpose'),
   ('Other Sport', 'other')
+) AS x(name, type)
+WHERE v.slug = 'main'
 ON CONFLICT DO NOTHING;
 
 -- 选择插入的 id（假设顺序插入）；真实环境建议使用名称查询
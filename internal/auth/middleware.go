@@ -1,6 +1,7 @@
 package auth
 
 import (
+    "errors"
     "net/http"
     "strings"
 
@@ -27,35 +28,43 @@ func NewJWTMiddleware(jwtSecret string) gin.HandlerFunc {
         }
         tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
-        token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
-            // Supabase 默认 HS256
-            if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-                return nil, jwt.ErrTokenUnverifiable
-            }
-            return []byte(jwtSecret), nil
-        })
-        if err != nil || !token.Valid {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-            return
-        }
-        claims, ok := token.Claims.(jwt.MapClaims)
-        if !ok {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid claims"})
-            return
-        }
-        sub, _ := claims["sub"].(string)
-        if sub == "" {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing sub"})
+        sub, role, err := ParseToken(tokenStr, jwtSecret)
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
             return
         }
         c.Set(ContextKeyUserID, sub)
-        if role, _ := claims["role"].(string); role != "" {
+        if role != "" {
             c.Set(ContextKeyUserRole, role)
         }
         c.Next()
     }
 }
 
+// ParseToken 校验 JWT 并返回 sub 与 role（中文说明：供中间件与限流等无需中断请求的场景复用）
+func ParseToken(tokenStr, jwtSecret string) (sub, role string, err error) {
+    token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
+        // Supabase 默认 HS256
+        if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+            return nil, jwt.ErrTokenUnverifiable
+        }
+        return []byte(jwtSecret), nil
+    })
+    if err != nil || !token.Valid {
+        return "", "", errors.New("invalid token")
+    }
+    claims, ok := token.Claims.(jwt.MapClaims)
+    if !ok {
+        return "", "", errors.New("invalid claims")
+    }
+    sub, _ = claims["sub"].(string)
+    if sub == "" {
+        return "", "", errors.New("missing sub")
+    }
+    role, _ = claims["role"].(string)
+    return sub, role, nil
+}
+
 // GetUserID 从上下文获取用户ID（中文说明：快捷读取中间件设置的用户ID）
 func GetUserID(c *gin.Context) (string, bool) {
     v, ok := c.Get(ContextKeyUserID)
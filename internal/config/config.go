@@ -3,26 +3,68 @@ package config
 import (
 	"errors"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // Config 用于保存服务运行所需的环境配置
 // 中文说明：从系统环境变量读取 Supabase 连接、JWT 密钥、服务端口等
 type Config struct {
-	Port              string
-	SupabaseDBURL     string
-	SupabaseJWTSecret string
-	SupabaseURL       string
-	SupabaseAnonKey   string
+	Port               string
+	TrustedProxies     []string // 可信反向代理（IP 或 CIDR，逗号分隔），为空时不信任 X-Forwarded-For
+	SupabaseDBURL      string
+	SupabaseJWTSecret  string
+	SupabaseURL        string
+	SupabaseAnonKey    string
+	SupabaseServiceKey string // service_role key，仅用于读取用户邮箱等受限函数
+	StorageDir         string // 本地文件存储目录（设施照片等）
+	MediaBaseURL       string // 文件对外访问前缀
+	PaymentProvider    string // 支付渠道：none（默认，不走支付）或 fake（本地，须设置签名密钥）
+	PaymentSecret      string // Webhook 签名密钥
+	PaymentSimulate    bool   // 开放模拟支付接口（仅开发环境，需 fake 渠道）
+	PaymentCurrency    string // 币种（ISO 4217）
+	TicketSecret       string // 预约票据签名密钥，未设置时使用 JWT 密钥派生
+	CheckInOpensMin    int    // 开场前多少分钟开放自助签到
+	NoShowGraceMin     int    // 开场后多少分钟无人签到判为爽约
+	NoShowBanCount     int    // 统计窗口内爽约次数达到该值时暂停预约，0 表示不处罚
+	NoShowWindowDays   int    // 爽约次数统计窗口（天）
+	NoShowBanDays      int    // 暂停预约天数
+	SMTPHost           string // 邮件服务器；为空时不发送邮件，通知仅记录日志
+	SMTPPort           int
+	SMTPUsername       string // 为空时不认证（本地邮件捕获工具）
+	SMTPPassword       string
+	SMTPFrom           string // 发件地址
+	EventBroker        string // 跨实例事件转发：local（单实例）或 db（经 bus_events 表轮询）
 }
 
 // Load 读取并校验配置
 func Load() (Config, error) {
 	cfg := Config{
-		Port:              getenvDefault("PORT", "8080"),
-		SupabaseDBURL:     firstNonEmpty(os.Getenv("SUPABASE_DB_URL"), os.Getenv("DATABASE_URL")),
-		SupabaseJWTSecret: os.Getenv("SUPABASE_JWT_SECRET"),
-		SupabaseURL:       os.Getenv("SUPABASE_URL"),
-		SupabaseAnonKey:   os.Getenv("SUPABASE_ANON_KEY"),
+		Port:               getenvDefault("PORT", "8080"),
+		TrustedProxies:     getenvList("TRUSTED_PROXIES"),
+		SupabaseDBURL:      firstNonEmpty(os.Getenv("SUPABASE_DB_URL"), os.Getenv("DATABASE_URL")),
+		SupabaseJWTSecret:  os.Getenv("SUPABASE_JWT_SECRET"),
+		SupabaseURL:        os.Getenv("SUPABASE_URL"),
+		SupabaseAnonKey:    os.Getenv("SUPABASE_ANON_KEY"),
+		SupabaseServiceKey: os.Getenv("SUPABASE_SERVICE_ROLE_KEY"),
+		StorageDir:         getenvDefault("STORAGE_DIR", "uploads"),
+		MediaBaseURL:       getenvDefault("MEDIA_BASE_URL", "/media"),
+		PaymentProvider:    getenvDefault("PAYMENT_PROVIDER", "none"),
+		PaymentSecret:      os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+		PaymentSimulate:    os.Getenv("PAYMENT_SIMULATE") == "true",
+		PaymentCurrency:    getenvDefault("PAYMENT_CURRENCY", "MYR"),
+		TicketSecret:       os.Getenv("TICKET_SECRET"),
+		CheckInOpensMin:    getenvInt("CHECK_IN_OPENS_MINUTES", 30),
+		NoShowGraceMin:     getenvInt("NO_SHOW_GRACE_MINUTES", 15),
+		NoShowBanCount:     getenvInt("NO_SHOW_BAN_THRESHOLD", 3),
+		NoShowWindowDays:   getenvInt("NO_SHOW_WINDOW_DAYS", 30),
+		NoShowBanDays:      getenvInt("NO_SHOW_BAN_DAYS", 7),
+		SMTPHost:           os.Getenv("SMTP_HOST"),
+		SMTPPort:           getenvInt("SMTP_PORT", 587),
+		SMTPUsername:       os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:       os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:           getenvDefault("SMTP_FROM", "no-reply@localhost"),
+		EventBroker:        getenvDefault("EVENT_BROKER", "local"),
 	}
 	// 允许无 DB 情况启动（便于本地先跑起来），但提示缺失
 	if cfg.SupabaseDBURL == "" {
@@ -44,6 +86,26 @@ func getenvDefault(key, def string) string {
 	return def
 }
 
+// getenvInt 读取非负整数环境变量，缺失或非法时使用默认值
+func getenvInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || v < 0 {
+		return def
+	}
+	return v
+}
+
+// getenvList 读取逗号分隔的环境变量，忽略空项
+func getenvList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func firstNonEmpty(values ...string) string {
 	for _, v := range values {
 		if v != "" {
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Broker 跨实例转发事件（中文说明：Publish 发出本实例产生的事件；Receive 阻塞接收其它实例的事件并交给 deliver，
+// 直到 ctx 结束或出错；实现方需过滤掉本实例发出的事件）
+type Broker interface {
+	Publish(ctx context.Context, e Event) error
+	Receive(ctx context.Context, deliver func(Event)) error
+}
+
+// brokerRetryDelay Receive 出错后重新连接的等待时间
+const brokerRetryDelay = 5 * time.Second
+
+// Bus 进程内事件总线（中文说明：实现 Publisher；本实例的事件立即分发给订阅者并经 Broker 转发，
+// 其它实例的事件由 Run 接收后分发；未配置 Broker 时仅在本实例内分发）
+type Bus struct {
+	broker Broker
+
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBus 创建事件总线（broker 可为 nil）
+func NewBus(broker Broker) *Bus {
+	return &Bus{broker: broker, subs: map[*Subscription]struct{}{}}
+}
+
+// Publish 实现 Publisher 接口
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.deliver(e)
+	if b.broker == nil {
+		return
+	}
+	if err := b.broker.Publish(ctx, e); err != nil {
+		slog.Warn("broker publish failed", "event", e.Type, "err", err)
+	}
+}
+
+// Run 接收其它实例的事件，直到 ctx 结束（中文说明：Broker 出错时等待后重连）
+func (b *Bus) Run(ctx context.Context) {
+	if b.broker == nil {
+		return
+	}
+	for {
+		err := b.broker.Receive(ctx, b.deliver)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("broker receive failed", "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(brokerRetryDelay):
+		}
+	}
+}
+
+// deliver 分发给全部订阅者（中文说明：不阻塞发布方，订阅者缓冲区已满时丢弃并标记 Lost）
+func (b *Bus) deliver(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs {
+		select {
+		case s.ch <- e:
+		default:
+			s.lost.Store(true)
+		}
+	}
+}
+
+// Subscribe 订阅全部事件（buffer 为缓冲区大小）；用完须调用 Close
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	s := &Subscription{bus: b, ch: make(chan Event, buffer)}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Subscription 事件订阅
+type Subscription struct {
+	bus  *Bus
+	ch   chan Event
+	lost atomic.Bool
+	once sync.Once
+}
+
+// Events 事件通道（Close 后关闭）
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Lost 返回并清除“缓冲区已满丢弃过事件”的标记（中文说明：为 true 时订阅方应全量刷新）
+func (s *Subscription) Lost() bool {
+	return s.lost.Swap(false)
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.ch)
+	})
+}
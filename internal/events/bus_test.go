@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipeHub 测试用的多实例转发：一个 broker 发布的事件由同一 hub 上的其它 broker 收到
+type pipeHub struct {
+	mu      sync.Mutex
+	brokers []*pipeBroker
+}
+
+type pipeBroker struct {
+	hub *pipeHub
+	in  chan Event
+}
+
+func (h *pipeHub) broker() *pipeBroker {
+	b := &pipeBroker{hub: h, in: make(chan Event, 8)}
+	h.mu.Lock()
+	h.brokers = append(h.brokers, b)
+	h.mu.Unlock()
+	return b
+}
+
+func (b *pipeBroker) Publish(ctx context.Context, e Event) error {
+	b.hub.mu.Lock()
+	defer b.hub.mu.Unlock()
+	for _, o := range b.hub.brokers {
+		if o != b {
+			o.in <- e
+		}
+	}
+	return nil
+}
+
+func (b *pipeBroker) Receive(ctx context.Context, deliver func(Event)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e := <-b.in:
+			deliver(e)
+		}
+	}
+}
+
+func receive(t *testing.T, s *Subscription) Event {
+	t.Helper()
+	select {
+	case e := <-s.Events():
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return Event{}
+	}
+}
+
+// 测试本实例发布的事件同时分发给本实例订阅者和其它实例，且不会回流
+func TestBusAcrossInstances(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := &pipeHub{}
+	a, b := NewBus(hub.broker()), NewBus(hub.broker())
+	go a.Run(ctx)
+	go b.Run(ctx)
+	subA, subB := a.Subscribe(4), b.Subscribe(4)
+	defer subA.Close()
+	defer subB.Close()
+
+	a.Publish(ctx, Event{Type: BookingCreated})
+	if e := receive(t, subA); e.Type != BookingCreated {
+		t.Fatalf("local subscriber got %q", e.Type)
+	}
+	if e := receive(t, subB); e.Type != BookingCreated {
+		t.Fatalf("remote subscriber got %q", e.Type)
+	}
+	select {
+	case e := <-subA.Events():
+		t.Fatalf("event echoed back to origin: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// 测试缓冲区已满时丢弃事件并标记 Lost，不阻塞发布方
+func TestBusLostOnFullBuffer(t *testing.T) {
+	bus := NewBus(nil)
+	s := bus.Subscribe(1)
+	bus.Publish(context.Background(), Event{Type: BookingCreated})
+	bus.Publish(context.Background(), Event{Type: BookingCancelled})
+	if !s.Lost() {
+		t.Fatal("expected lost flag after overflow")
+	}
+	if s.Lost() {
+		t.Fatal("lost flag should reset after being read")
+	}
+	if e := receive(t, s); e.Type != BookingCreated {
+		t.Fatalf("expected first event kept, got %q", e.Type)
+	}
+	s.Close()
+	s.Close()
+	if _, ok := <-s.Events(); ok {
+		t.Fatal("expected channel closed")
+	}
+	bus.Publish(context.Background(), Event{Type: BookingCreated})
+}
+
+// 测试时段相交判断：重复封场总是相交
+func TestTargetOverlaps(t *testing.T) {
+	day := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	next := day.Add(24 * time.Hour)
+	in := Target{Start: day.Add(10 * time.Hour), End: day.Add(11 * time.Hour)}
+	before := Target{Start: day.Add(-2 * time.Hour), End: day}
+	if !in.Overlaps(day, next) || before.Overlaps(day, next) {
+		t.Fatal("unexpected overlap result")
+	}
+	before.Recurring = true
+	if !before.Overlaps(day, next) {
+		t.Fatal("recurring target should always overlap")
+	}
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// 事件类型（中文说明：与对外 Webhook 的 event type 一致）
+const (
+	BookingCreated     = "booking.created"
+	BookingConfirmed   = "booking.confirmed" // 占位预约支付完成
+	BookingCancelled   = "booking.cancelled"
+	BookingRescheduled = "booking.rescheduled" // 改签时间或迁移单元
+	BookingCheckedIn   = "booking.checked_in"
+	BookingNoShow      = "booking.no_show" // 爽约，剩余时段重新开放
+	BlackoutCreated    = "blackout.created"
+	BlackoutUpdated    = "blackout.updated"
+	BlackoutDeleted    = "blackout.deleted"
+)
+
+// Types 全部事件类型
+var Types = []string{
+	BookingCreated, BookingConfirmed, BookingCancelled, BookingRescheduled, BookingCheckedIn, BookingNoShow,
+	BlackoutCreated, BlackoutUpdated, BlackoutDeleted,
+}
+
+// ValidType 是否为已知的事件类型
+func ValidType(t string) bool {
+	for _, v := range Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Target 事件影响的单元或设施与时段（中文说明：订阅方据此过滤，无需解析 Data）
+type Target struct {
+	UnitID     int64     `json:"unit_id,omitempty"`
+	FacilityID int64     `json:"facility_id,omitempty"` // 设施级封场
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Recurring  bool      `json:"recurring,omitempty"` // 按周重复的封场，Start/End 为首次发生
+}
+
+// Overlaps 是否与 [from, to) 相交（中文说明：重复封场总是视为相交，由订阅方重新计算确认）
+func (t Target) Overlaps(from, to time.Time) bool {
+	return t.Recurring || (t.Start.Before(to) && t.End.After(from))
+}
+
+// Event 领域事件（中文说明：由 repo 写操作成功后发布；Data 为变更后的实体，Previous 为变更前的实体，新建时为 nil；
+// 改签时 Targets 同时包含新旧时段）
+type Event struct {
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data,omitempty"`
+	Previous   interface{} `json:"previous,omitempty"`
+	Targets    []Target    `json:"targets,omitempty"`
+}
+
+// Publisher 事件发布接口（中文说明：写操作已成功提交，发布失败由实现方自行记录，不影响调用方）
+type Publisher interface {
+	Publish(ctx context.Context, e Event)
+}
+
+// Publishers 依次发布到多个发布器
+type Publishers []Publisher
+
+// Publish 实现 Publisher 接口
+func (ps Publishers) Publish(ctx context.Context, e Event) {
+	for _, p := range ps {
+		p.Publish(ctx, e)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// actorContext 返回带操作人的请求上下文（中文说明：写操作使用，repo 据此记录审计日志）
+func actorContext(c *gin.Context) context.Context {
+	userID, _ := auth.GetUserID(c)
+	return repo.WithActor(c.Request.Context(), userID)
+}
+
+// RegisterAuditRoutes 注册审计日志查询路由
+func RegisterAuditRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 查询审计日志：?entity_type=booking&entity_id=1&actor=<uuid>&from=RFC3339&to=RFC3339&limit=100
+	r.GET("/admin/audit", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		f := repo.AuditFilter{
+			EntityType:  c.Query("entity_type"),
+			ActorUserID: c.Query("actor"),
+		}
+		if s := c.Query("entity_id"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entity_id"})
+				return
+			}
+			f.EntityID = id
+		}
+		if s := c.Query("from"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+				return
+			}
+			f.From = t
+		}
+		if s := c.Query("to"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+				return
+			}
+			f.To = t
+		}
+		if s := c.Query("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+				return
+			}
+			f.Limit = n
+		}
+
+		list, err := db.ListAuditLogs(c.Request.Context(), f)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+}
@@ -5,13 +5,22 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Juny09/sport_backend/internal/events"
 	"github.com/Juny09/sport_backend/internal/repo"
 	"github.com/Juny09/sport_backend/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterAvailabilityRoutes 注册可用性查询路由
-func RegisterAvailabilityRoutes(r *gin.Engine, db *repo.DB) {
+// 可用时段推送参数
+const (
+	streamDefaultDuration = 30 * time.Minute       // 未指定 duration 时的最短空闲段
+	streamDebounce        = 200 * time.Millisecond // 合并短时间内的多个事件，减少重复计算
+	streamHeartbeat       = 25 * time.Second       // 心跳注释，防止代理断开空闲连接
+	streamBuffer          = 64
+)
+
+// RegisterAvailabilityRoutes 注册可用性查询与实时推送路由
+func RegisterAvailabilityRoutes(r *gin.Engine, db *repo.DB, bus *events.Bus) {
 	r.GET("/availability", func(c *gin.Context) {
 		if db == nil {
 			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
@@ -35,8 +44,19 @@ func RegisterAvailabilityRoutes(r *gin.Engine, db *repo.DB) {
 			return
 		}
 
+		// 可选：仅查询指定场馆（复用租户范围过滤）
+		ctx := c.Request.Context()
+		if s := c.Query("venue_id"); s != "" {
+			venueID, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue_id"})
+				return
+			}
+			ctx = repo.WithScope(ctx, repo.Scope{VenueIDs: []int64{venueID}})
+		}
+
 		// 查询该类型下的所有激活单元
-		units, err := db.ListUnitsByFacilityType(c.Request.Context(), facilityType)
+		units, err := db.ListUnitsByFacilityType(ctx, facilityType)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -44,27 +64,13 @@ func RegisterAvailabilityRoutes(r *gin.Engine, db *repo.DB) {
 
 		// 查询当天的预订与封场
 		minDur := time.Duration(durationMin) * time.Minute
-		oh := service.OpeningHoursForDay(day) // 暴露函数名便于理解
 		resp := make([]gin.H, 0, len(units))
 		for _, u := range units {
-			bookings, err := db.ListBookingsForUnitOnDay(c.Request.Context(), u.ID, day)
+			free, err := service.UnitFreeRanges(ctx, db, u, day, minDur)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
-			blackouts, err := db.ListBlackoutsForUnitOrFacilityOnDay(c.Request.Context(), u.FacilityID, u.ID, day)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			var blocks []service.TimeRange
-			for _, b := range bookings {
-				blocks = append(blocks, service.TimeRange{Start: b.StartTime, End: b.EndTime})
-			}
-			for _, b := range blackouts {
-				blocks = append(blocks, service.TimeRange{Start: b.StartTime, End: b.EndTime})
-			}
-			free := service.SubtractRanges(service.TimeRange{Start: oh.Start, End: oh.End}, blocks, minDur)
 			resp = append(resp, gin.H{
 				"unit_id": u.ID,
 				"label":   u.Label,
@@ -73,4 +79,117 @@ func RegisterAvailabilityRoutes(r *gin.Engine, db *repo.DB) {
 		}
 		c.JSON(http.StatusOK, resp)
 	})
+
+	// 实时推送（SSE）：?facility_type=&date=YYYY-MM-DD&duration=30&venue_id=
+	// 先推送 snapshot（全部单元的空闲时段），之后当天的预约、取消、改签、爽约或封场变化时推送 availability（变化的单元）
+	r.GET("/availability/stream", func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		facilityType := c.Query("facility_type")
+		dateStr := c.Query("date")
+		if facilityType == "" || dateStr == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing params"})
+			return
+		}
+		day, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+			return
+		}
+		minDur := streamDefaultDuration
+		if s := c.Query("duration"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid duration"})
+				return
+			}
+			minDur = time.Duration(n) * time.Minute
+		}
+		ctx := c.Request.Context()
+		if s := c.Query("venue_id"); s != "" {
+			venueID, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue_id"})
+				return
+			}
+			ctx = repo.WithScope(ctx, repo.Scope{VenueIDs: []int64{venueID}})
+		}
+		units, err := db.ListUnitsByFacilityType(ctx, facilityType)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 先订阅再计算快照，快照期间发生的变化不会遗漏
+		sub := bus.Subscribe(streamBuffer)
+		defer sub.Close()
+		watch := service.NewAvailabilityWatch(db, units, day, minDur)
+		snapshot, err := watch.Refresh(ctx, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.SSEvent("snapshot", snapshot)
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+		var debounce <-chan time.Time
+		var pending []int64
+		seen := map[int64]bool{}
+		full := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				if sub.Lost() {
+					full = true
+				}
+				for _, id := range watch.Affected(e) {
+					if !seen[id] {
+						seen[id] = true
+						pending = append(pending, id)
+					}
+				}
+				if debounce == nil && (full || len(pending) > 0) {
+					debounce = time.After(streamDebounce)
+				}
+			case <-debounce:
+				ids := pending
+				if full {
+					ids = nil
+				}
+				debounce, pending, seen, full = nil, nil, map[int64]bool{}, false
+				changed, err := watch.Refresh(ctx, ids)
+				if err != nil {
+					c.SSEvent("error", gin.H{"error": err.Error()})
+					c.Writer.Flush()
+					continue
+				}
+				for _, u := range changed {
+					c.SSEvent("availability", u)
+				}
+				if len(changed) > 0 {
+					c.Writer.Flush()
+				}
+			case <-heartbeat.C:
+				if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+				c.Writer.Flush()
+			}
+		}
+	})
 }
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterBlackoutRoutes 注册封场管理路由（中文说明：均需管理员）
+func RegisterBlackoutRoutes(r *gin.Engine, db *repo.DB, jwtSecret string, notifier notify.Notifier, provider payment.Provider) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 封场列表：?facility_id=&resource_unit_id=&from=RFC3339&to=RFC3339
+	r.GET("/blackouts", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		var f repo.BlackoutFilter
+		var err error
+		if s := c.Query("facility_id"); s != "" {
+			if f.FacilityID, err = strconv.ParseInt(s, 10, 64); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid facility_id"})
+				return
+			}
+		}
+		if s := c.Query("resource_unit_id"); s != "" {
+			if f.ResourceUnitID, err = strconv.ParseInt(s, 10, 64); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid resource_unit_id"})
+				return
+			}
+		}
+		if s := c.Query("from"); s != "" {
+			if f.From, err = time.Parse(time.RFC3339, s); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+				return
+			}
+		}
+		if s := c.Query("to"); s != "" {
+			if f.To, err = time.Parse(time.RFC3339, s); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+				return
+			}
+		}
+		list, err := db.ListBlackouts(c.Request.Context(), f)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	r.GET("/blackouts/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		b, err := db.GetBlackoutByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusOK, b)
+	})
+
+	// 添加封场时间（中文说明：返回与之重叠的已确认预约，便于工作人员处理）
+	r.POST("/blackouts", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		var body repo.BlackoutRequest
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if _, _, _, err := body.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		b, err := db.CreateBlackout(actorContext(c), body)
+		if err != nil {
+			c.JSON(scopeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+			return
+		}
+		affected, err := db.ListBlackoutAffectedBookings(c.Request.Context(), *b)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"blackout": b, "affected_bookings": affected})
+	})
+
+	// 更新封场（整体替换）
+	r.PUT("/blackouts/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body repo.BlackoutRequest
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if _, _, _, err := body.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		b, err := db.UpdateBlackout(actorContext(c), id, body)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		affected, err := db.ListBlackoutAffectedBookings(c.Request.Context(), *b)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"blackout": b, "affected_bookings": affected})
+	})
+
+	// 删除封场
+	r.DELETE("/blackouts/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		if err := db.DeleteBlackout(actorContext(c), id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// 受影响预约列表
+	r.GET("/blackouts/:id/affected", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		b, err := db.GetBlackoutByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		affected, err := db.ListBlackoutAffectedBookings(c.Request.Context(), *b)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, affected)
+	})
+
+	// 处理受影响预约：取消（记录原因）或迁移到同设施空闲单元，并通知用户
+	r.POST("/blackouts/:id/resolve", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body struct {
+			Action         string  `json:"action"` // cancel | relocate
+			Reason         string  `json:"reason"`
+			CancelIfNoUnit bool    `json:"cancel_if_no_unit"`
+			BookingIDs     []int64 `json:"booking_ids"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if body.Action != service.ImpactCancel && body.Action != service.ImpactRelocate {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "action must be cancel or relocate"})
+			return
+		}
+		b, err := db.GetBlackoutByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+
+		outcomes, err := service.ResolveBlackoutImpact(actorContext(c), db, notifier, provider, *b, service.ImpactRequest{
+			Action:         body.Action,
+			Reason:         body.Reason,
+			CancelIfNoUnit: body.CancelIfNoUnit,
+			BookingIDs:     body.BookingIDs,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, outcomes)
+	})
+}
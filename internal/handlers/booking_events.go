@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 变更流分页大小
+const (
+	bookingEventsDefaultLimit = 100
+	bookingEventsMaxLimit     = 1000
+)
+
+// RegisterBookingEventRoutes 注册预约变更流路由
+func RegisterBookingEventRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 按序号读取预约变更：?after=<seq>&limit=100&booking_id=
+	// 返回 after 之后的记录与下次请求使用的 next_after，消费方保存 next_after 即可断点续读
+	r.GET("/events", authMW, func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		if !requireAdmin(c, db) {
+			return
+		}
+		f := repo.BookingEventFilter{Limit: bookingEventsDefaultLimit}
+		if s := c.Query("after"); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid after"})
+				return
+			}
+			f.After = n
+		}
+		if s := c.Query("booking_id"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking_id"})
+				return
+			}
+			f.BookingID = id
+		}
+		if s := c.Query("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 || n > bookingEventsMaxLimit {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+				return
+			}
+			f.Limit = n
+		}
+		list, err := db.ListBookingEvents(c.Request.Context(), f)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		next := f.After
+		if len(list) > 0 {
+			next = list[len(list)-1].Seq
+		}
+		c.JSON(http.StatusOK, gin.H{"events": list, "next_after": next, "has_more": len(list) == f.Limit})
+	})
+
+	// 按变更流重建预约并与当前状态比较
+	r.GET("/events/bookings/:id/verify", authMW, func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		v, err := service.VerifyBookingEvents(c.Request.Context(), db, id)
+		if errors.Is(err, service.ErrNoBookingEvents) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, v)
+	})
+}
@@ -1,17 +1,31 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
 	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterBookingRoutes 注册预约相关路由
-func RegisterBookingRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+// bookingCreated 创建预约响应（中文说明：保持预约字段在顶层；附带报价明细，需要支付时附带支付记录与 ClientSecret，分摊支付时附带份额与支付链接）
+type bookingCreated struct {
+	*repo.Booking
+	Payment      *repo.Payment  `json:"Payment,omitempty"`
+	ClientSecret string         `json:"ClientSecret,omitempty"`
+	Quote        *service.Quote `json:"Quote,omitempty"`
+	Shares       []shareView    `json:"Shares,omitempty"`
+}
+
+// RegisterBookingRoutes 注册预约相关路由（中文说明：provider 为 nil 时预约直接确认，不走支付）
+func RegisterBookingRoutes(r *gin.Engine, db *repo.DB, jwtSecret string, notifier notify.Notifier, provider payment.Provider, currency string) {
 	authMW := auth.NewJWTMiddleware(jwtSecret)
 
 	// 创建预约
@@ -22,33 +36,89 @@ func RegisterBookingRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
 			StartTime      string `json:"start_time"` // ISO8601
 			EndTime        string `json:"end_time"`   // ISO8601
 			Notes          string `json:"notes"`
+			PayWith        string `json:"pay_with"`   // 空为支付渠道，wallet 为钱包余额
+			PromoCode      string `json:"promo_code"` // 可选优惠码
+			Split          *struct {
+				Invitees []service.Invitee `json:"invitees"`
+				Deadline string            `json:"deadline"` // ISO8601，可选
+			} `json:"split"` // 可选：与受邀人分摊支付
 		}
 		if err := c.BindJSON(&body); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 			return
 		}
-		st, err := time.Parse(time.RFC3339, body.StartTime)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_time"})
+		if body.PayWith != service.PayWithProvider && body.PayWith != service.PayWithWallet {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "pay_with must be empty or wallet"})
 			return
 		}
-		et, err := time.Parse(time.RFC3339, body.EndTime)
+		st, et, ok := parseBookingWindow(c, body.StartTime, body.EndTime)
+		if !ok {
+			return
+		}
+		opt := service.PlaceOptions{PayWith: body.PayWith, PromoCode: body.PromoCode}
+		if body.Split != nil {
+			opt.Split = &service.SplitRequest{Invitees: body.Split.Invitees}
+			if body.Split.Deadline != "" {
+				d, err := time.Parse(time.RFC3339, body.Split.Deadline)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid split.deadline"})
+					return
+				}
+				opt.Split.Deadline = d
+			}
+		}
+		out, err := service.PlaceBooking(actorContext(c), db, notifier, provider, currency, repo.NewBooking{
+			ResourceUnitID: body.ResourceUnitID,
+			UserID:         userID,
+			Start:          st,
+			End:            et,
+			Notes:          body.Notes,
+		}, opt)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_time"})
+			c.JSON(bookingErrorStatus(err, http.StatusConflict), gin.H{"error": err.Error()})
+			return
+		}
+		res := bookingCreated{Booking: out.Booking, Payment: out.Payment, ClientSecret: out.ClientSecret, Quote: out.Quote}
+		if len(out.Shares) > 0 {
+			res.Shares = shareViews(out.Shares)
+		}
+		c.JSON(http.StatusCreated, res)
+	})
+
+	// 报价：与创建预约相同的请求体，返回原价、会员折扣、优惠码优惠与应付金额，不占用时段
+	r.POST("/bookings/quote", authMW, func(c *gin.Context) {
+		userID, _ := auth.GetUserID(c)
+		var body struct {
+			ResourceUnitID int64  `json:"resource_unit_id"`
+			StartTime      string `json:"start_time"` // ISO8601
+			EndTime        string `json:"end_time"`   // ISO8601
+			PromoCode      string `json:"promo_code"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		st, et, ok := parseBookingWindow(c, body.StartTime, body.EndTime)
+		if !ok {
 			return
 		}
-		b, err := db.CreateBooking(c.Request.Context(), body.ResourceUnitID, userID, st, et, body.Notes)
+		q, err := service.QuoteBooking(c.Request.Context(), db, repo.NewBooking{
+			ResourceUnitID: body.ResourceUnitID,
+			UserID:         userID,
+			Start:          st,
+			End:            et,
+		}, body.PromoCode, time.Now())
 		if err != nil {
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			c.JSON(bookingErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusCreated, b)
+		c.JSON(http.StatusOK, q)
 	})
 
-	// 获取单个预约
+	// 获取单个预约（参与人可查看）；/bookings/:id.ics 返回该预约的 iCalendar 文件
 	r.GET("/bookings/:id", authMW, func(c *gin.Context) {
-		userID, _ := auth.GetUserID(c)
-		id, err := parseIDParam(c.Param("id"))
+		param, ics := strings.CutSuffix(c.Param("id"), ".ics")
+		id, err := parseIDParam(param)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 			return
@@ -58,14 +128,23 @@ func RegisterBookingRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
 		}
-		if b.UserID != userID && !auth.IsAdmin(c) {
+		if !canViewBooking(c, db, b) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
+		if ics {
+			cal, err := service.BookingCalendar(c.Request.Context(), db, b)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			writeCalendar(c, cal, fmt.Sprintf("booking-%d.ics", b.ID))
+			return
+		}
 		c.JSON(http.StatusOK, b)
 	})
 
-	// 我的预约列表
+	// 我的预约列表（含作为参与人加入的预约）
 	r.GET("/bookings", authMW, func(c *gin.Context) {
 		if c.Query("mine") != "true" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "mine=true required"})
@@ -80,9 +159,8 @@ func RegisterBookingRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
 		c.JSON(http.StatusOK, list)
 	})
 
-	// 取消预约
+	// 取消预约（中文说明：按设施类型退款规则对已支付金额退款，响应包含退款结果）
 	r.PATCH("/bookings/:id/cancel", authMW, func(c *gin.Context) {
-		userID, _ := auth.GetUserID(c)
 		id, err := parseIDParam(c.Param("id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
@@ -93,20 +171,48 @@ func RegisterBookingRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
 		}
-		if b.UserID != userID && !auth.IsAdmin(c) {
+		if !canManageBooking(c, db, b) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
-		if err := db.CancelBooking(c.Request.Context(), id); err != nil {
+		// 可选：{reason, refund_percent}；refund_percent 仅管理员可用，覆盖退款规则
+		var body struct {
+			Reason        string `json:"reason"`
+			RefundPercent *int   `json:"refund_percent"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+				return
+			}
+		}
+		if body.RefundPercent != nil {
+			if !managesBooking(c, db, b) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "refund override requires admin"})
+				return
+			}
+			if *body.RefundPercent < 0 || *body.RefundPercent > 100 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "refund_percent must be between 0 and 100"})
+				return
+			}
+		}
+		out, err := service.CancelBookingWithRefund(actorContext(c), db, notifier, provider, b, time.Now(), service.CancelOptions{
+			Reason:          body.Reason,
+			OverridePercent: body.RefundPercent,
+		})
+		if errors.Is(err, service.ErrAlreadyCancelled) || errors.Is(err, service.ErrBookingAttended) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		c.Status(http.StatusOK)
+		c.JSON(http.StatusOK, out)
 	})
 
 	// 改签预约
 	r.PATCH("/bookings/:id/reschedule", authMW, func(c *gin.Context) {
-		userID, _ := auth.GetUserID(c)
 		id, err := parseIDParam(c.Param("id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
@@ -117,10 +223,14 @@ func RegisterBookingRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
 		}
-		if b.UserID != userID && !auth.IsAdmin(c) {
+		if !canManageBooking(c, db, b) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
+		if b.Status == "cancelled" || b.Status == "no_show" || b.CheckedInAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "booking can no longer be rescheduled"})
+			return
+		}
 		var body struct {
 			StartTime string `json:"start_time"`
 			EndTime   string `json:"end_time"`
@@ -139,14 +249,56 @@ func RegisterBookingRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_time"})
 			return
 		}
-		if err := db.RescheduleBooking(c.Request.Context(), id, st, et); err != nil {
+		// 场馆管理员改签不受预约策略限制；用户本人改签按其会员权益校验
+		if !managesBooking(c, db, b) {
+			if err := service.CheckBookingPolicy(c.Request.Context(), db, b.UserID, b.ResourceUnitID, st, et, time.Now()); err != nil {
+				c.JSON(scopeStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if err := db.RescheduleBooking(actorContext(c), id, st, et); err != nil {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 			return
 		}
+		moved := *b
+		moved.StartTime, moved.EndTime = st, et
+		service.NotifyBookingRescheduled(c.Request.Context(), notifier, moved, b.StartTime)
 		c.Status(http.StatusOK)
 	})
 }
 
+// parseBookingWindow 解析 RFC3339 开始与结束时间；失败时已写入响应
+func parseBookingWindow(c *gin.Context, start, end string) (time.Time, time.Time, bool) {
+	st, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_time"})
+		return time.Time{}, time.Time{}, false
+	}
+	et, err := time.Parse(time.RFC3339, end)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_time"})
+		return time.Time{}, time.Time{}, false
+	}
+	return st, et, true
+}
+
+// bookingErrorStatus 将下单/报价错误映射为 HTTP 状态码
+func bookingErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, repo.ErrInsufficientBalance):
+		return http.StatusPaymentRequired
+	case errors.Is(err, repo.ErrServiceRoleRequired):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, service.ErrPolicyViolation), errors.Is(err, service.ErrPromoInvalid), errors.Is(err, service.ErrSplitUnavailable):
+		return http.StatusBadRequest
+	case errors.Is(err, repo.ErrPromoLimitReached):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrBookingBanned):
+		return http.StatusForbidden
+	}
+	return fallback
+}
+
 func parseIDParam(s string) (int64, error) {
 	var id int64
 	_, err := fmt.Sscan(s, &id)
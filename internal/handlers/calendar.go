@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/ical"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// writeCalendar 以 text/calendar 输出日历
+func writeCalendar(c *gin.Context, cal ical.Calendar, filename string) {
+	var buf bytes.Buffer
+	if err := cal.Write(&buf, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="`+filename+`"`)
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+// feedURLs 订阅地址（中文说明：按请求的 Host 与 X-Forwarded-Proto 拼接，同时给出 webcal:// 形式便于日历应用直接订阅）
+func feedURLs(c *gin.Context, f *repo.CalendarFeed) gin.H {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if p := c.GetHeader("X-Forwarded-Proto"); p != "" {
+		scheme = p
+	}
+	path := "://" + c.Request.Host + "/calendar/" + f.Token + ".ics"
+	return gin.H{"url": scheme + path, "webcal_url": "webcal" + path, "feed": f}
+}
+
+// RegisterCalendarRoutes 注册日历订阅路由（中文说明：订阅地址以随机凭证鉴权，日历应用无需登录；重新生成后旧地址失效）
+func RegisterCalendarRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 个人预约订阅地址（不存在时创建）；rotate 生成新地址
+	personalFeed := func(rotate bool) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			userID, _ := auth.GetUserID(c)
+			f, err := service.CalendarFeed(c.Request.Context(), db, userID, nil, rotate)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, feedURLs(c, f))
+		}
+	}
+	r.GET("/me/calendar", authMW, personalFeed(false))
+	r.POST("/me/calendar/rotate", authMW, personalFeed(true))
+
+	// 设施占用订阅地址（仅管辖该设施的管理员）
+	facilityFeed := func(rotate bool) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			if !requireAdmin(c, db) {
+				return
+			}
+			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+				return
+			}
+			if _, err := db.GetFacilityByID(c.Request.Context(), id); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			userID, _ := auth.GetUserID(c)
+			f, err := service.CalendarFeed(c.Request.Context(), db, userID, &id, rotate)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, feedURLs(c, f))
+		}
+	}
+	r.GET("/facilities/:id/calendar", authMW, facilityFeed(false))
+	r.POST("/facilities/:id/calendar/rotate", authMW, facilityFeed(true))
+
+	// 订阅内容（公开，凭证即鉴权）：/calendar/<token>.ics
+	r.GET("/calendar/:file", func(c *gin.Context) {
+		token, ok := strings.CutSuffix(c.Param("file"), ".ics")
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		ctx := c.Request.Context()
+		f, err := db.GetCalendarFeedByToken(ctx, token)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		var cal ical.Calendar
+		if f.FacilityID == nil {
+			cal, err = service.UserCalendar(ctx, db, f.UserID, time.Now())
+		} else {
+			cal, err = service.FacilityBusyCalendar(ctx, db, *f.FacilityID, time.Now())
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		writeCalendar(c, cal, "bookings.ics")
+	})
+}
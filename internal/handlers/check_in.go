@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCheckInRoutes 注册签到、场地签到码与预约禁令路由
+// 中文说明：场馆管理员随时签到至预约结束；预订人或参与人扫描场地二维码（单元签到码）在签到窗口内自助签到
+func RegisterCheckInRoutes(r *gin.Engine, db *repo.DB, jwtSecret string, policy service.NoShowPolicy) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 签到：{code?, headcount?}；自助签到须提交 code，headcount 省略时取预订人加参与人数
+	r.POST("/bookings/:id/check_in", authMW, func(c *gin.Context) {
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		b, err := db.GetBookingByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		staff := managesBooking(c, db, b)
+		if !staff && !canViewBooking(c, db, b) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		var body struct {
+			Code      string `json:"code"`
+			Headcount int    `json:"headcount"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+				return
+			}
+		}
+		if body.Headcount < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "headcount must be positive"})
+			return
+		}
+		out, err := service.CheckIn(actorContext(c), db, policy, b, body.Code, body.Headcount, staff, time.Now())
+		switch {
+		case errors.Is(err, service.ErrInvalidCheckInCode):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, repo.ErrNotCheckInable), errors.Is(err, service.ErrCheckInWindow):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusOK, out)
+		}
+	})
+
+	// 单元签到码：场地二维码内容（管理员）
+	r.GET("/units/:id/check_in_code", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		code, err := db.GetUnitCheckInCode(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"unit_id": id, "code": code})
+	})
+
+	// 更换签到码（二维码泄露时使用，旧码随即失效）
+	r.POST("/units/:id/check_in_code/rotate", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		code, err := service.NewCheckInCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.SetUnitCheckInCode(actorContext(c), id, code); err != nil {
+			c.JSON(scopeStatus(err, http.StatusNotFound), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"unit_id": id, "code": code})
+	})
+
+	// 我的预约禁令
+	r.GET("/me/bans", authMW, func(c *gin.Context) {
+		userID, _ := auth.GetUserID(c)
+		list, err := db.ListBookingBans(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	// 预约禁令列表 ?user_id=（管理员）
+	r.GET("/admin/bans", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		list, err := db.ListBookingBans(c.Request.Context(), c.Query("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	// 提前解除禁令（平台管理员）
+	r.DELETE("/admin/bans/:id", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		ban, err := db.LiftBookingBan(actorContext(c), id, time.Now())
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, ban)
+	})
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/events"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// 运营看板推送参数
+const (
+	dashboardPing         = 30 * time.Second // 心跳消息，同时检查是否跨日
+	dashboardWriteTimeout = 10 * time.Second
+	dashboardBuffer       = 256
+)
+
+// dashboardProtocol 以 WebSocket 子协议传递 JWT 时的标记：new WebSocket(url, ["access_token", jwt])
+const dashboardProtocol = "access_token"
+
+// tokenFromProtocol 浏览器 WebSocket 无法设置 Authorization 请求头，允许经 Sec-WebSocket-Protocol 传递 JWT
+// 中文说明：不接受 ?access_token=，查询串会连同路径写入访问日志
+func tokenFromProtocol(c *gin.Context) {
+	if c.GetHeader("Authorization") == "" {
+		protocols := strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",")
+		if len(protocols) == 2 && strings.TrimSpace(protocols[0]) == dashboardProtocol {
+			c.Request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(protocols[1]))
+		}
+	}
+	c.Next()
+}
+
+// selectDashboardProtocol 握手时只回应 access_token 子协议，不回显 JWT
+func selectDashboardProtocol(config *websocket.Config, _ *http.Request) error {
+	var selected []string
+	for _, p := range config.Protocol {
+		if p == dashboardProtocol {
+			selected = []string{dashboardProtocol}
+		}
+	}
+	config.Protocol = selected
+	return nil
+}
+
+// RegisterDashboardRoutes 注册运营看板实时推送路由
+func RegisterDashboardRoutes(r *gin.Engine, db *repo.DB, jwtSecret string, bus *events.Bus) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 运营看板（WebSocket）：?facility_id=，JWT 经 Authorization 或 Sec-WebSocket-Protocol: access_token, <jwt> 传递
+	// 先推送 snapshot（管理范围内各设施当天每个单元的预约与封场），之后推送 update（预约新建、确认、取消、改签、签到、爽约与封场变化）；
+	// 每 30 秒推送 ping；丢失事件或跨过场馆时区的 0 点时重新推送 snapshot
+	r.GET("/admin/dashboard/ws", tokenFromProtocol, authMW, func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		if !requireAdmin(c, db) {
+			return
+		}
+		ctx := c.Request.Context()
+		var facilityID int64
+		if s := c.Query("facility_id"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid facility_id"})
+				return
+			}
+			if _, err := db.GetFacilityByID(ctx, id); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			facilityID = id
+		}
+
+		// 先订阅再生成快照，快照期间发生的变化不会遗漏
+		sub := bus.Subscribe(dashboardBuffer)
+		defer sub.Close()
+		dash := service.NewDashboard(db, facilityID)
+		snapshot, err := dash.Snapshot(ctx, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 使用 JWT 鉴权，不校验 Origin
+		server := websocket.Server{Handshake: selectDashboardProtocol, Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			// 升级后请求 context 不随连接关闭结束，由读循环在客户端断开时取消
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go func() {
+				defer cancel()
+				var discard []byte
+				for {
+					if err := websocket.Message.Receive(ws, &discard); err != nil {
+						return
+					}
+				}
+			}()
+			send := func(v interface{}) bool {
+				ws.SetWriteDeadline(time.Now().Add(dashboardWriteTimeout))
+				return websocket.JSON.Send(ws, v) == nil
+			}
+			resnapshot := func() bool {
+				snapshot, err := dash.Snapshot(ctx, time.Now())
+				if err != nil {
+					return send(gin.H{"type": "error", "error": err.Error()})
+				}
+				return send(snapshot)
+			}
+
+			if !send(snapshot) {
+				return
+			}
+			ping := time.NewTicker(dashboardPing)
+			defer ping.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case e, ok := <-sub.Events():
+					if !ok {
+						return
+					}
+					if sub.Lost() {
+						if !resnapshot() {
+							return
+						}
+						continue
+					}
+					if u, ok := dash.Update(e); ok && !send(u) {
+						return
+					}
+				case now := <-ping.C:
+					if dash.Expired(now) {
+						if !resnapshot() {
+							return
+						}
+						continue
+					}
+					if !send(gin.H{"type": "ping", "at": now.UTC()}) {
+						return
+					}
+				}
+			}
+		}}
+		server.ServeHTTP(c.Writer, c.Request)
+	})
+}
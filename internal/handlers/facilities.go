@@ -1,27 +1,81 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"sort"
 	"strconv"
 
 	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
 	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterFacilityRoutes 注册设施相关路由（中文说明：包含增查与单元管理）
-func RegisterFacilityRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+func RegisterFacilityRoutes(r *gin.Engine, db *repo.DB, jwtSecret string, notifier notify.Notifier, provider payment.Provider) {
 	// 公开路由：列表与详情
+	// 设施列表：?type=&amenity=&lat=&lng=&radius_km=
+	// 中文说明：提供 lat/lng 时按距离由近到远排序，radius_km 限定最大距离
 	r.GET("/facilities", func(c *gin.Context) {
 		if db == nil {
 			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
 			return
 		}
-		list, err := db.ListFacilities(c.Request.Context())
+		var (
+			lat, lng, radius float64
+			hasPoint         bool
+			err              error
+		)
+		if c.Query("lat") != "" || c.Query("lng") != "" {
+			lat, err = strconv.ParseFloat(c.Query("lat"), 64)
+			if err != nil || lat < -90 || lat > 90 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lat"})
+				return
+			}
+			lng, err = strconv.ParseFloat(c.Query("lng"), 64)
+			if err != nil || lng < -180 || lng > 180 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lng"})
+				return
+			}
+			hasPoint = true
+		}
+		if s := c.Query("radius_km"); s != "" {
+			radius, err = strconv.ParseFloat(s, 64)
+			if err != nil || radius <= 0 || !hasPoint {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid radius_km (requires lat/lng)"})
+				return
+			}
+		}
+
+		venueID, _ := strconv.ParseInt(c.Query("venue_id"), 10, 64)
+		list, err := db.ListFacilitiesFiltered(c.Request.Context(), repo.FacilityFilter{
+			VenueID: venueID,
+			Type:    c.Query("type"),
+			Amenity: c.Query("amenity"),
+		})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		if hasPoint {
+			located := make([]repo.Facility, 0, len(list))
+			for _, f := range list {
+				if f.Latitude == nil || f.Longitude == nil {
+					continue
+				}
+				dist := service.DistanceKm(lat, lng, *f.Latitude, *f.Longitude)
+				if radius > 0 && dist > radius {
+					continue
+				}
+				f.DistanceKm = &dist
+				located = append(located, f)
+			}
+			sort.Slice(located, func(i, j int) bool { return *located[i].DistanceKm < *located[j].DistanceKm })
+			list = located
+		}
 		c.JSON(http.StatusOK, list)
 	})
 	r.GET("/facilities/:id", func(c *gin.Context) {
@@ -63,33 +117,35 @@ func RegisterFacilityRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
 	// 管理路由：需要鉴权 + 管理员
 	authMW := auth.NewJWTMiddleware(jwtSecret)
 	r.POST("/facilities", authMW, func(c *gin.Context) {
-		if !auth.IsAdmin(c) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin only"})
+		if !requireAdmin(c, db) {
 			return
 		}
 		var body struct {
-			Name string `json:"name"`
-			Type string `json:"type"`
+			VenueID int64  `json:"venue_id"`
+			Name    string `json:"name"`
+			Type    string `json:"type"`
 		}
 		if err := c.BindJSON(&body); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 			return
 		}
-		if body.Name == "" || body.Type == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "name/type required"})
+		if body.VenueID <= 0 || body.Name == "" || body.Type == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "venue_id/name/type required"})
+			return
+		}
+		if !checkFacilityType(c, db, body.Type) {
 			return
 		}
 		// 简化：直接插入
-		if err := db.CreateFacility(c.Request.Context(), body.Name, body.Type); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := db.CreateFacility(actorContext(c), body.VenueID, body.Name, body.Type); err != nil {
+			c.JSON(scopeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 			return
 		}
 		c.Status(http.StatusCreated)
 	})
 
 	r.POST("/facilities/:id/units", authMW, func(c *gin.Context) {
-		if !auth.IsAdmin(c) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin only"})
+		if !requireAdmin(c, db) {
 			return
 		}
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -105,16 +161,64 @@ func RegisterFacilityRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
 			return
 		}
 
-		if err := db.CreateResourceUnit(c.Request.Context(), id, body.Label); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := db.CreateResourceUnit(actorContext(c), id, body.Label); err != nil {
+			c.JSON(scopeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 			return
 		}
 		c.Status(http.StatusCreated)
 	})
 
+	// 更新设施：重命名、变更类型、启用/停用，以及简介、地址坐标、联系方式与设施标签
+	// 中文说明：停用且存在未来已确认预约时需指定 policy（block/cancel/relocate）
+	r.PATCH("/facilities/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body struct {
+			repo.FacilityUpdate
+			Policy string `json:"policy"`
+			Reason string `json:"reason"`
+		}
+		if err := c.BindJSON(&body); err != nil || body.FacilityUpdate.Empty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if (body.Name != nil && *body.Name == "") || (body.Type != nil && *body.Type == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name/type cannot be empty"})
+			return
+		}
+		if err := body.FacilityUpdate.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if body.Type != nil && !checkFacilityType(c, db, *body.Type) {
+			return
+		}
+		deactivateFacility(c, db, notifier, provider, id, body.FacilityUpdate, body.Policy, body.Reason)
+	})
+
+	// 删除设施（中文说明：软删除，即停用；物理删除会级联删除历史预约）
+	r.DELETE("/facilities/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		inactive := false
+		deactivateFacility(c, db, notifier, provider, id, repo.FacilityUpdate{IsActive: &inactive}, c.Query("policy"), c.Query("reason"))
+	})
+
+	// 更新单元：名称、排序、场地属性（地面材质、室内/室外、灯光）、人数上限与启用状态
 	r.PATCH("/units/:id", authMW, func(c *gin.Context) {
-		if !auth.IsAdmin(c) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin only"})
+		if !requireAdmin(c, db) {
 			return
 		}
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -123,17 +227,82 @@ func RegisterFacilityRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
 			return
 		}
 		var body struct {
-			IsActive *bool `json:"is_active"`
+			repo.UnitUpdate
+			Policy string `json:"policy"`
+			Reason string `json:"reason"`
 		}
-		if err := c.BindJSON(&body); err != nil || body.IsActive == nil {
+		if err := c.BindJSON(&body); err != nil || body.UnitUpdate.Empty() {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 			return
 		}
+		if body.Label != nil && *body.Label == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid label"})
+			return
+		}
+		if body.MaxParticipants != nil && *body.MaxParticipants < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_participants must be at least 1"})
+			return
+		}
+		if !service.ValidPolicy(body.Policy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "policy must be block, cancel or relocate"})
+			return
+		}
+
+		var outcomes []service.ImpactOutcome
+		if body.IsActive != nil && !*body.IsActive {
+			outcomes, err = service.PrepareUnitDeactivation(actorContext(c), db, notifier, provider, id, body.Policy, body.Reason)
+			if writeFutureBookingsError(c, err) {
+				return
+			}
+		}
 
-		if err := db.UpdateResourceUnit(c.Request.Context(), id, *body.IsActive); err != nil {
+		unit, err := db.UpdateResourceUnit(actorContext(c), id, body.UnitUpdate)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		c.Status(http.StatusOK)
+		c.JSON(http.StatusOK, gin.H{"unit": unit, "outcomes": outcomes})
 	})
 }
+
+// deactivateFacility 更新设施；若为停用则先按策略处理未来预约
+func deactivateFacility(c *gin.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, id int64, upd repo.FacilityUpdate, policy, reason string) {
+	if !service.ValidPolicy(policy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "policy must be block, cancel or relocate"})
+		return
+	}
+	f, err := db.GetFacilityByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	var outcomes []service.ImpactOutcome
+	if upd.IsActive != nil && !*upd.IsActive && f.IsActive {
+		outcomes, err = service.PrepareFacilityDeactivation(actorContext(c), db, notifier, provider, *f, policy, reason)
+		if writeFutureBookingsError(c, err) {
+			return
+		}
+	}
+
+	updated, err := db.UpdateFacility(actorContext(c), id, upd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"facility": updated, "outcomes": outcomes})
+}
+
+// writeFutureBookingsError 写入停用失败响应；err 为 nil 时返回 false
+func writeFutureBookingsError(c *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	var fb *service.FutureBookingsError
+	if errors.As(err, &fb) {
+		c.JSON(http.StatusConflict, gin.H{"error": fb.Error(), "bookings": fb.Bookings, "outcomes": fb.Outcomes})
+		return true
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	return true
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxPhotoBytes = 10 << 20 // 单张照片上限 10MB
+	thumbnailSize = 320      // 缩略图最长边像素
+)
+
+// RegisterFacilityPhotoRoutes 注册设施照片与媒体文件路由
+func RegisterFacilityPhotoRoutes(r *gin.Engine, db *repo.DB, jwtSecret string, store storage.Store) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 媒体文件读取（中文说明：本地存储时由后端直接提供文件）
+	r.GET("/media/*key", func(c *gin.Context) {
+		key := c.Param("key")
+		rc, err := store.Open(c.Request.Context(), key)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer rc.Close()
+		ct := mime.TypeByExtension(path.Ext(key))
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		c.Header("Content-Type", ct)
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.Status(http.StatusOK)
+		_, _ = io.Copy(c.Writer, rc)
+	})
+
+	// 设施照片列表
+	r.GET("/facilities/:id/photos", func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		photos, err := db.ListFacilityPhotos(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i := range photos {
+			withPhotoURLs(store, &photos[i])
+		}
+		c.JSON(http.StatusOK, photos)
+	})
+
+	// 上传照片（multipart 字段 file，可选 sort_order）：保存原图并生成缩略图
+	r.POST("/facilities/:id/photos", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		if _, err := db.GetFacilityByID(c.Request.Context(), id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPhotoBytes+1<<20)
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file required"})
+			return
+		}
+		if fh.Size > maxPhotoBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file"})
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(f, maxPhotoBytes+1))
+		f.Close()
+		if err != nil || len(data) > maxPhotoBytes {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file"})
+			return
+		}
+		contentType := http.DetectContentType(data)
+		ext := map[string]string{"image/jpeg": ".jpg", "image/png": ".png"}[contentType]
+		if ext == "" {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "only jpeg/png supported"})
+			return
+		}
+		thumb, err := storage.Thumbnail(data, thumbnailSize)
+		if errors.Is(err, storage.ErrImageTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "image dimensions too large"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid image"})
+			return
+		}
+		sortOrder, _ := strconv.Atoi(c.PostForm("sort_order"))
+
+		name := randomHex(12)
+		photo := repo.FacilityPhoto{
+			FacilityID:   id,
+			StorageKey:   "facilities/" + strconv.FormatInt(id, 10) + "/" + name + ext,
+			ThumbnailKey: "facilities/" + strconv.FormatInt(id, 10) + "/" + name + "_thumb.jpg",
+			ContentType:  contentType,
+			SortOrder:    sortOrder,
+		}
+		ctx := c.Request.Context()
+		if err := store.Put(ctx, photo.StorageKey, bytes.NewReader(data), contentType); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := store.Put(ctx, photo.ThumbnailKey, bytes.NewReader(thumb), "image/jpeg"); err != nil {
+			_ = store.Delete(ctx, photo.StorageKey)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		created, err := db.CreateFacilityPhoto(actorContext(c), photo)
+		if err != nil {
+			_ = store.Delete(ctx, photo.StorageKey)
+			_ = store.Delete(ctx, photo.ThumbnailKey)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		withPhotoURLs(store, created)
+		c.JSON(http.StatusCreated, created)
+	})
+
+	// 删除照片
+	r.DELETE("/facilities/:id/photos/:photo_id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		photoID, err := strconv.ParseInt(c.Param("photo_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid photo_id"})
+			return
+		}
+		if _, err := db.GetFacilityByID(c.Request.Context(), id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		photo, err := db.GetFacilityPhotoByID(c.Request.Context(), photoID)
+		if err != nil || photo.FacilityID != id {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err := db.DeleteFacilityPhoto(actorContext(c), photoID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		_ = store.Delete(c.Request.Context(), photo.StorageKey)
+		_ = store.Delete(c.Request.Context(), photo.ThumbnailKey)
+		c.Status(http.StatusNoContent)
+	})
+}
+
+func withPhotoURLs(store storage.Store, p *repo.FacilityPhoto) {
+	p.URL = store.URL(p.StorageKey)
+	p.ThumbnailURL = store.URL(p.ThumbnailKey)
+}
+
+// randomHex 生成随机十六进制字符串（用于文件名）
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterFacilityTypeRoutes 注册设施类型目录路由（中文说明：查询公开；新增与修改仅平台管理员）
+func RegisterFacilityTypeRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 类型列表：?all=true 同时返回已停用类型
+	r.GET("/facility_types", func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		list, err := db.ListFacilityTypes(c.Request.Context(), c.Query("all") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	r.GET("/facility_types/:code", func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		t, err := db.GetFacilityType(c.Request.Context(), c.Param("code"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	})
+
+	// 新增类型
+	r.POST("/facility_types", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		var body struct {
+			Code                      string `json:"code"`
+			DisplayName               string `json:"display_name"`
+			DisplayNameZh             string `json:"display_name_zh"`
+			Icon                      string `json:"icon"`
+			SortOrder                 int    `json:"sort_order"`
+			MinDurationMinutes        int    `json:"min_duration_minutes"`
+			MaxDurationMinutes        int    `json:"max_duration_minutes"`
+			SlotGranularityMinutes    int    `json:"slot_granularity_minutes"`
+			AdvanceBookingDays        int    `json:"advance_booking_days"`
+			CancellationCutoffMinutes int    `json:"cancellation_cutoff_minutes"`
+			RefundFullHours           int    `json:"refund_full_hours"`
+			RefundPartialHours        int    `json:"refund_partial_hours"`
+			RefundPartialPercent      int    `json:"refund_partial_percent"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		ft := repo.FacilityType{
+			Code:                      body.Code,
+			DisplayName:               body.DisplayName,
+			DisplayNameZh:             body.DisplayNameZh,
+			Icon:                      body.Icon,
+			SortOrder:                 body.SortOrder,
+			IsActive:                  true,
+			MinDurationMinutes:        body.MinDurationMinutes,
+			MaxDurationMinutes:        body.MaxDurationMinutes,
+			SlotGranularityMinutes:    body.SlotGranularityMinutes,
+			AdvanceBookingDays:        body.AdvanceBookingDays,
+			CancellationCutoffMinutes: body.CancellationCutoffMinutes,
+			RefundFullHours:           body.RefundFullHours,
+			RefundPartialHours:        body.RefundPartialHours,
+			RefundPartialPercent:      body.RefundPartialPercent,
+		}
+		if err := ft.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := db.GetFacilityType(c.Request.Context(), ft.Code); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "facility type already exists"})
+			return
+		}
+		created, err := db.CreateFacilityType(actorContext(c), ft)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, created)
+	})
+
+	// 修改类型（显示名、图标、排序、启用状态、默认策略）
+	r.PATCH("/facility_types/:code", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		var body repo.FacilityTypeUpdate
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if _, err := db.GetFacilityType(c.Request.Context(), c.Param("code")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		t, err := db.UpdateFacilityType(actorContext(c), c.Param("code"), body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	})
+}
+
+// checkFacilityType 校验设施类型在目录中且启用；失败时已写入响应
+func checkFacilityType(c *gin.Context, db *repo.DB, code string) bool {
+	err := db.CheckFacilityType(c.Request.Context(), code)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, repo.ErrUnknownFacilityType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error() + ": " + code})
+	} else {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterMembershipRoutes 注册会员路由（中文说明：方案查询公开；场馆管理员管理本场馆方案，通用方案仅平台管理员）
+func RegisterMembershipRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 方案列表：?venue_id= 返回该场馆可用方案（含通用方案）
+	r.GET("/membership_plans", func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		venueID, _ := strconv.ParseInt(c.Query("venue_id"), 10, 64)
+		list, err := db.ListMembershipPlans(c.Request.Context(), venueID, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	// 管理端方案列表（含已停用，按管理范围过滤）
+	r.GET("/admin/membership_plans", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		venueID, _ := strconv.ParseInt(c.Query("venue_id"), 10, 64)
+		list, err := db.ListMembershipPlans(c.Request.Context(), venueID, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	// 新增方案：venue_id 省略时为通用方案
+	r.POST("/membership_plans", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		var body struct {
+			VenueID            *int64 `json:"venue_id"`
+			Name               string `json:"name"`
+			Description        string `json:"description"`
+			DiscountPercent    int    `json:"discount_percent"`
+			AdvanceBookingDays *int   `json:"advance_booking_days"`
+			MaxDurationMinutes *int   `json:"max_duration_minutes"`
+			DurationDays       int    `json:"duration_days"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		plan := repo.MembershipPlan{
+			VenueID:            body.VenueID,
+			Name:               body.Name,
+			Description:        body.Description,
+			DiscountPercent:    body.DiscountPercent,
+			AdvanceBookingDays: body.AdvanceBookingDays,
+			MaxDurationMinutes: body.MaxDurationMinutes,
+			DurationDays:       body.DurationDays,
+			IsActive:           true,
+		}
+		if plan.DurationDays == 0 {
+			plan.DurationDays = 30
+		}
+		if err := plan.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		created, err := db.CreateMembershipPlan(actorContext(c), plan)
+		if err != nil {
+			c.JSON(scopeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, created)
+	})
+
+	// 修改方案（折扣、覆盖项、有效天数、启用状态）
+	r.PATCH("/membership_plans/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body repo.MembershipPlanUpdate
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if _, err := db.GetMembershipPlan(c.Request.Context(), id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		plan, err := db.UpdateMembershipPlan(actorContext(c), id, body)
+		if err != nil {
+			c.JSON(scopeStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, plan)
+	})
+
+	// 我的会员记录（含已过期）
+	r.GET("/me/memberships", authMW, func(c *gin.Context) {
+		userID, _ := auth.GetUserID(c)
+		list, err := db.ListMemberships(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	// 查询用户会员：?user_id=
+	r.GET("/admin/memberships", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		userID := c.Query("user_id")
+		if userID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+			return
+		}
+		list, err := db.ListMemberships(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	// 发放会员：{user_id, plan_id, starts_at?, ends_at?}；省略时从现在开始，按方案有效天数结束
+	r.POST("/admin/memberships", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		var body struct {
+			UserID   string `json:"user_id"`
+			PlanID   int64  `json:"plan_id"`
+			StartsAt string `json:"starts_at"` // ISO8601
+			EndsAt   string `json:"ends_at"`   // ISO8601
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if body.UserID == "" || body.PlanID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id and plan_id required"})
+			return
+		}
+		plan, err := db.GetMembershipPlan(c.Request.Context(), body.PlanID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "membership plan not found"})
+			return
+		}
+		start := time.Now().UTC()
+		if body.StartsAt != "" {
+			if start, err = time.Parse(time.RFC3339, body.StartsAt); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid starts_at"})
+				return
+			}
+		}
+		end := start.AddDate(0, 0, plan.DurationDays)
+		if body.EndsAt != "" {
+			if end, err = time.Parse(time.RFC3339, body.EndsAt); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ends_at"})
+				return
+			}
+		}
+		m, err := db.GrantMembership(actorContext(c), body.UserID, plan.ID, start, end)
+		if err != nil {
+			c.JSON(scopeStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, m)
+	})
+
+	// 撤销会员（提前结束，保留记录）
+	r.DELETE("/admin/memberships/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		m, err := db.RevokeMembership(actorContext(c), id, time.Now())
+		if err != nil {
+			c.JSON(scopeStatus(err, http.StatusNotFound), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, m)
+	})
+}
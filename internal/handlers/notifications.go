@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterNotificationRoutes 注册通知设置与发件箱路由
+func RegisterNotificationRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 我的通知设置：收件邮箱、语言与提醒退订
+	r.GET("/me/notification_settings", authMW, func(c *gin.Context) {
+		userID, _ := auth.GetUserID(c)
+		contact, err := db.GetNotificationContact(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, contact)
+	})
+
+	// 修改通知设置 {locale?: zh|en, reminder_opt_out?, rating_opt_out?}
+	r.PATCH("/me/notification_settings", authMW, func(c *gin.Context) {
+		var body repo.NotificationSettingsUpdate
+		if err := c.BindJSON(&body); err != nil || body.IsEmpty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+			return
+		}
+		if body.Locale != nil && !notify.ValidLocale(*body.Locale) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "locale must be zh or en"})
+			return
+		}
+		userID, _ := auth.GetUserID(c)
+		if err := db.UpdateNotificationSettings(c.Request.Context(), userID, body); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		contact, err := db.GetNotificationContact(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, contact)
+	})
+
+	// 发件箱：?status=pending|sent|failed&limit=100（平台管理员）
+	r.GET("/admin/notifications", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		status := c.Query("status")
+		switch status {
+		case "", repo.OutboxPending, repo.OutboxSent, repo.OutboxFailed:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, sent or failed"})
+			return
+		}
+		limit := 0
+		if s := c.Query("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+				return
+			}
+			limit = n
+		}
+		list, err := db.ListOutbox(c.Request.Context(), status, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	// 重新投递失败的邮件（平台管理员）
+	r.POST("/admin/notifications/:id/retry", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		m, err := db.RequeueOutbox(actorContext(c), id)
+		if errors.Is(err, repo.ErrOutboxNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no failed notification with this id"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, m)
+	})
+}
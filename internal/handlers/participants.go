@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterParticipantRoutes 注册预约参与人路由（中文说明：预订人或管理员增删参与人，参与人可查看名单并自行退出）
+func RegisterParticipantRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 参与人名单：人数（含预订人）、单元上限与参与人列表
+	r.GET("/bookings/:id/participants", authMW, func(c *gin.Context) {
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		b, err := db.GetBookingByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if !canViewBooking(c, db, b) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		list, err := db.ListBookingParticipants(c.Request.Context(), b.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		unit, err := db.GetResourceUnitByID(c.Request.Context(), b.ResourceUnitID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"headcount":        repo.Headcount(list),
+			"max_participants": unit.MaxParticipants,
+			"participants":     list,
+		})
+	})
+
+	// 添加参与人：{user_id} 为已注册用户，{guest_name} 为访客；人数不超过单元上限
+	r.POST("/bookings/:id/participants", authMW, func(c *gin.Context) {
+		b, ok := loadManagedBooking(c, db)
+		if !ok {
+			return
+		}
+		var body repo.NewParticipant
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		body = body.Normalize()
+		if err := body.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if b.Status == "cancelled" || b.Status == "no_show" || !b.EndTime.After(time.Now()) {
+			c.JSON(http.StatusConflict, gin.H{"error": "booking is no longer active"})
+			return
+		}
+		if body.UserID != "" && body.UserID == b.UserID {
+			c.JSON(http.StatusConflict, gin.H{"error": repo.ErrAlreadyParticipant.Error()})
+			return
+		}
+		p, err := db.AddBookingParticipant(actorContext(c), b.ID, body)
+		if errors.Is(err, repo.ErrParticipantLimit) || errors.Is(err, repo.ErrAlreadyParticipant) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, p)
+	})
+
+	// 移除参与人：预订人或管理员可移除任何参与人，参与人可移除自己
+	r.DELETE("/bookings/:id/participants/:pid", authMW, func(c *gin.Context) {
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		pid, err := parseIDParam(c.Param("pid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid participant id"})
+			return
+		}
+		b, err := db.GetBookingByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		list, err := db.ListBookingParticipants(c.Request.Context(), b.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		var target *repo.BookingParticipant
+		for i := range list {
+			if list[i].ID == pid {
+				target = &list[i]
+			}
+		}
+		userID, _ := auth.GetUserID(c)
+		self := target != nil && target.UserID != "" && target.UserID == userID
+		if !self && !canManageBooking(c, db, b) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		if target == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "participant not found"})
+			return
+		}
+		if err := db.RemoveBookingParticipant(actorContext(c), b.ID, pid); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
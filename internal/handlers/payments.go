@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPaymentRoutes 注册支付路由（中文说明：Webhook 验签后更新支付与预约状态；退款需管理员）
+func RegisterPaymentRoutes(r *gin.Engine, db *repo.DB, jwtSecret string, notifier notify.Notifier, provider payment.Provider, simulate bool) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 预约的支付与退款记录（本人或管理员）
+	r.GET("/bookings/:id/payments", authMW, func(c *gin.Context) {
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		b, err := db.GetBookingByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if !canManageBooking(c, db, b) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		payments, err := db.ListPaymentsByBooking(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		refunds, err := db.ListRefundsByBooking(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"payments": payments, "refunds": refunds})
+	})
+
+	r.GET("/payments/:id", authMW, func(c *gin.Context) {
+		p, ok := loadPayment(c, db)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, p)
+	})
+
+	// 支付渠道回调（中文说明：无需登录，依赖签名校验；处理失败返回 5xx 以便渠道重试）
+	r.POST("/payments/webhook", func(c *gin.Context) {
+		if db == nil || provider == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "payments not configured"})
+			return
+		}
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		ev, err := provider.ParseWebhook(body, c.Request.Header)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.HandlePaymentEvent(c.Request.Context(), db, notifier, provider, *ev); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"received": true})
+	})
+
+	// 退款：{amount, reason}，amount 省略时退回全部剩余金额（管理员）
+	r.POST("/payments/:id/refund", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body struct {
+			Amount float64 `json:"amount"`
+			Reason string  `json:"reason"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+				return
+			}
+		}
+		p, err := db.GetPaymentByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		refund, err := service.RefundPayment(actorContext(c), db, provider, p, body.Amount, body.Reason)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, refund)
+	})
+
+	// 本地渠道：模拟支付结果 {outcome: succeeded|failed}（中文说明：生成签名事件并走 Webhook 同一处理流程；
+	// 仅在显式开启 PAYMENT_SIMULATE 时注册，否则任何用户都能免费确认自己的预约）
+	if fake, ok := provider.(*payment.FakeProvider); ok && simulate {
+		r.POST("/payments/:id/simulate", authMW, func(c *gin.Context) {
+			p, ok := loadPayment(c, db)
+			if !ok {
+				return
+			}
+			simulatePayment(c, db, notifier, fake, p)
+		})
+	}
+}
+
+// simulatePayment 读取 {outcome: succeeded|failed}，生成签名事件并走 Webhook 同一处理流程，返回更新后的支付记录
+func simulatePayment(c *gin.Context, db *repo.DB, notifier notify.Notifier, fake *payment.FakeProvider, p *repo.Payment) {
+	var body struct {
+		Outcome string `json:"outcome"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+	ev := payment.Event{Type: payment.EventPaymentSucceeded, PaymentRef: p.ProviderRef, Amount: p.Amount}
+	switch body.Outcome {
+	case "", "succeeded":
+	case "failed":
+		ev.Type = payment.EventPaymentFailed
+		ev.FailureReason = "card_declined"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "outcome must be succeeded or failed"})
+		return
+	}
+	payload, header, err := fake.SignedEvent(ev)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	parsed, err := fake.ParseWebhook(payload, header)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := service.HandlePaymentEvent(c.Request.Context(), db, notifier, fake, *parsed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := db.GetPaymentByID(c.Request.Context(), p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// loadPayment 读取路径中的支付记录并校验本人或管理员；失败时已写入响应
+func loadPayment(c *gin.Context, db *repo.DB) (*repo.Payment, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	p, err := db.GetPaymentByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	b, err := db.GetBookingByID(c.Request.Context(), p.BookingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	if !canManageBooking(c, db, b) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return nil, false
+	}
+	return p, true
+}
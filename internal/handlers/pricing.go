@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPricingRoutes 注册价格规则路由（中文说明：查询公开，增改删需管理员）
+func RegisterPricingRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 价格规则列表
+	r.GET("/pricing_rules", func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		venueID, _ := strconv.ParseInt(c.Query("venue_id"), 10, 64)
+		list, err := db.ListPricingRules(c.Request.Context(), venueID, c.Query("facility_type"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	// 价格表：按星期展示每小时有效价格
+	r.GET("/pricing_rules/grid", func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		facilityType := c.Query("facility_type")
+		venueID, _ := strconv.ParseInt(c.Query("venue_id"), 10, 64)
+		if facilityType == "" || venueID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "venue_id and facility_type required"})
+			return
+		}
+		rules, err := db.ListPricingRules(c.Request.Context(), venueID, facilityType)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, service.PriceGrid(rules))
+	})
+
+	r.GET("/pricing_rules/:id", func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		rule, err := db.GetPricingRuleByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusOK, rule)
+	})
+
+	// 添加价格规则
+	r.POST("/pricing_rules", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		var body repo.PricingRule
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		body.ID = 0
+		if !checkPricingRule(c, db, body) {
+			return
+		}
+
+		rule, err := db.CreatePricingRule(actorContext(c), body)
+		if err != nil {
+			c.JSON(pricingErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, rule)
+	})
+
+	// 更新价格规则（整体替换）
+	r.PUT("/pricing_rules/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body repo.PricingRule
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		body.ID = id
+		if !checkPricingRule(c, db, body) {
+			return
+		}
+
+		rule, err := db.UpdatePricingRule(actorContext(c), id, body)
+		if err != nil {
+			c.JSON(pricingErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rule)
+	})
+
+	// 删除价格规则
+	r.DELETE("/pricing_rules/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		if err := db.DeletePricingRule(actorContext(c), id); err != nil {
+			c.JSON(pricingErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// pricingErrorStatus 价格规则写入错误的状态码（中文说明：不存在或超出管理范围 404，时段重叠 409，其它 500）
+func pricingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repo.ErrPricingRuleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repo.ErrPricingRuleOverlap):
+		return http.StatusConflict
+	}
+	return scopeStatus(err, http.StatusInternalServerError)
+}
+
+// checkPricingRule 校验字段并检查与同场馆同类型同星期规则的时段冲突；失败时已写入响应
+func checkPricingRule(c *gin.Context, db *repo.DB, rule repo.PricingRule) bool {
+	if err := service.ValidatePricingRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if rule.VenueID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VenueID required"})
+		return false
+	}
+	if !checkFacilityType(c, db, rule.FacilityType) {
+		return false
+	}
+	existing, err := db.ListPricingRules(c.Request.Context(), rule.VenueID, rule.FacilityType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := service.FindPricingOverlap(rule, existing); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPricingOverlap) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
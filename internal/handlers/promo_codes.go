@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPromoCodeRoutes 注册优惠码管理路由（中文说明：场馆管理员管理本场馆优惠码，通用优惠码仅平台管理员）
+func RegisterPromoCodeRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	r.GET("/admin/promo_codes", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		list, err := db.ListPromoCodes(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	r.GET("/admin/promo_codes/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		pc, err := db.GetPromoCode(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusOK, pc)
+	})
+
+	// 新增优惠码：{code, discount_type: percent|fixed, discount_value, venue_id?, facility_types?, starts_at?, ends_at?, max_redemptions?, per_user_limit?}
+	r.POST("/admin/promo_codes", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		var body struct {
+			Code           string     `json:"code"`
+			Description    string     `json:"description"`
+			VenueID        *int64     `json:"venue_id"`
+			DiscountType   string     `json:"discount_type"`
+			DiscountValue  float64    `json:"discount_value"`
+			FacilityTypes  []string   `json:"facility_types"`
+			StartsAt       *time.Time `json:"starts_at"`
+			EndsAt         *time.Time `json:"ends_at"`
+			MaxRedemptions *int       `json:"max_redemptions"`
+			PerUserLimit   *int       `json:"per_user_limit"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		pc := repo.PromoCode{
+			Code:           repo.NormalizePromoCode(body.Code),
+			Description:    body.Description,
+			VenueID:        body.VenueID,
+			DiscountType:   body.DiscountType,
+			DiscountValue:  body.DiscountValue,
+			FacilityTypes:  body.FacilityTypes,
+			StartsAt:       body.StartsAt,
+			EndsAt:         body.EndsAt,
+			MaxRedemptions: body.MaxRedemptions,
+			PerUserLimit:   body.PerUserLimit,
+			IsActive:       true,
+		}
+		if err := pc.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for _, t := range pc.FacilityTypes {
+			if !checkFacilityType(c, db, t) {
+				return
+			}
+		}
+		if _, err := db.GetPromoCodeByCode(c.Request.Context(), pc.Code); err == nil || errors.Is(err, repo.ErrOutOfScope) {
+			c.JSON(http.StatusConflict, gin.H{"error": "promo code already exists"})
+			return
+		}
+		created, err := db.CreatePromoCode(actorContext(c), pc)
+		if err != nil {
+			c.JSON(scopeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, created)
+	})
+
+	// 修改优惠码（优惠额度、适用类型、有效期、次数上限、启用状态）
+	r.PATCH("/admin/promo_codes/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body repo.PromoCodeUpdate
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if body.FacilityTypes != nil {
+			for _, t := range *body.FacilityTypes {
+				if !checkFacilityType(c, db, t) {
+					return
+				}
+			}
+		}
+		if _, err := db.GetPromoCode(c.Request.Context(), id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		pc, err := db.UpdatePromoCode(actorContext(c), id, body)
+		if err != nil {
+			c.JSON(scopeStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, pc)
+	})
+
+	// 核销记录（含已撤销）
+	r.GET("/admin/promo_codes/:id/redemptions", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		if _, err := db.GetPromoCode(c.Request.Context(), id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		list, err := db.ListPromoRedemptions(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		active := 0
+		for _, r := range list {
+			if r.Status == repo.RedemptionActive {
+				active++
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"active": active, "redemptions": list})
+	})
+}
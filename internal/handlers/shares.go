@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// shareView 份额响应（附带支付链接）
+type shareView struct {
+	repo.BookingShare
+	PaymentLink string `json:"PaymentLink"`
+}
+
+func shareViews(shares []repo.BookingShare) []shareView {
+	res := make([]shareView, len(shares))
+	for i, s := range shares {
+		res[i] = shareView{BookingShare: s, PaymentLink: service.ShareLink(s.Token)}
+	}
+	return res
+}
+
+// RegisterShareRoutes 注册分摊支付路由（中文说明：支付链接凭 token 访问，无需登录；份额列表与补足仅发起人或管理员）
+func RegisterShareRoutes(r *gin.Engine, db *repo.DB, jwtSecret string, notifier notify.Notifier, provider payment.Provider, currency string, simulate bool) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	r.GET("/bookings/:id/shares", authMW, func(c *gin.Context) {
+		b, ok := loadManagedBooking(c, db)
+		if !ok {
+			return
+		}
+		shares, err := db.ListBookingShares(c.Request.Context(), b.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, shareViews(shares))
+	})
+
+	// 发起人补足所有未付份额：返回补足支付与 ClientSecret
+	r.POST("/bookings/:id/split/cover", authMW, func(c *gin.Context) {
+		b, ok := loadManagedBooking(c, db)
+		if !ok {
+			return
+		}
+		p, secret, err := service.CoverShares(actorContext(c), db, provider, currency, b, time.Now())
+		if errors.Is(err, service.ErrSplitUnavailable) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"payment": p, "client_secret": secret})
+	})
+
+	// 邀请给我的份额
+	r.GET("/me/shares", authMW, func(c *gin.Context) {
+		userID, _ := auth.GetUserID(c)
+		shares, err := db.ListSharesForUser(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, shareViews(shares))
+	})
+
+	// 支付链接：份额金额、状态与预约时段（不含预约人信息）
+	r.GET("/shares/:token", func(c *gin.Context) {
+		share, b, ok := loadShare(c, db)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"share": shareViews([]repo.BookingShare{*share})[0],
+			"booking": gin.H{
+				"ID":             b.ID,
+				"ResourceUnitID": b.ResourceUnitID,
+				"StartTime":      b.StartTime,
+				"EndTime":        b.EndTime,
+				"Status":         b.Status,
+			},
+		})
+	})
+
+	// 支付份额：创建支付意图并返回 ClientSecret
+	r.POST("/shares/:token/pay", func(c *gin.Context) {
+		share, _, ok := loadShare(c, db)
+		if !ok {
+			return
+		}
+		p, secret, err := service.PayShare(c.Request.Context(), db, provider, currency, share, time.Now())
+		if errors.Is(err, service.ErrShareClosed) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(bookingErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"payment": p, "client_secret": secret})
+	})
+
+	// 本地渠道：凭支付链接模拟份额支付结果 ?payment_id=，请求体 {outcome: succeeded|failed}（仅在显式开启 PAYMENT_SIMULATE 时注册）
+	if fake, ok := provider.(*payment.FakeProvider); ok && simulate {
+		r.POST("/shares/:token/simulate", func(c *gin.Context) {
+			share, _, ok := loadShare(c, db)
+			if !ok {
+				return
+			}
+			id, err := parseIDParam(c.Query("payment_id"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "payment_id required"})
+				return
+			}
+			p, err := db.GetPaymentByID(c.Request.Context(), id)
+			if err != nil || p.ShareID == nil || *p.ShareID != share.ID {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			simulatePayment(c, db, notifier, fake, p)
+		})
+	}
+}
+
+// loadManagedBooking 读取路径中的预约并校验本人或管理员；失败时已写入响应
+func loadManagedBooking(c *gin.Context, db *repo.DB) (*repo.Booking, bool) {
+	id, err := parseIDParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	b, err := db.GetBookingByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	if !canManageBooking(c, db, b) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return nil, false
+	}
+	return b, true
+}
+
+// loadShare 按支付链接凭证读取份额与预约；失败时已写入响应
+func loadShare(c *gin.Context, db *repo.DB) (*repo.BookingShare, *repo.Booking, bool) {
+	if db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+		return nil, nil, false
+	}
+	share, err := db.GetBookingShareByToken(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, nil, false
+	}
+	b, err := db.GetBookingByID(c.Request.Context(), share.BookingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, nil, false
+	}
+	return share, b, true
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// adminScope 解析当前用户的管理范围（中文说明：JWT 角色为 admin 视为平台管理员；否则取其担任管理员的场馆）
+func adminScope(c *gin.Context, db *repo.DB) (repo.Scope, bool) {
+	if auth.IsAdmin(c) {
+		return repo.Scope{All: true}, true
+	}
+	userID, ok := auth.GetUserID(c)
+	if !ok || db == nil {
+		return repo.Scope{}, false
+	}
+	ids, err := db.ListAdminVenueIDs(c.Request.Context(), userID)
+	if err != nil || len(ids) == 0 {
+		return repo.Scope{}, false
+	}
+	return repo.Scope{VenueIDs: ids}, true
+}
+
+// requireAdmin 要求平台或场馆管理员，并将租户范围写入请求 context；失败时已写入响应
+func requireAdmin(c *gin.Context, db *repo.DB) bool {
+	scope, ok := adminScope(c, db)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin only"})
+		return false
+	}
+	c.Request = c.Request.WithContext(repo.WithScope(c.Request.Context(), scope))
+	return true
+}
+
+// requirePlatformAdmin 要求平台管理员（跨场馆操作）；失败时已写入响应
+func requirePlatformAdmin(c *gin.Context) bool {
+	if !auth.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "platform admin only"})
+		return false
+	}
+	return true
+}
+
+// canManageBooking 预约本人或管辖该预约所在场馆的管理员可操作
+func canManageBooking(c *gin.Context, db *repo.DB, b *repo.Booking) bool {
+	if userID, _ := auth.GetUserID(c); b.UserID == userID {
+		return true
+	}
+	return managesBooking(c, db, b)
+}
+
+// canViewBooking 预约本人、参与人或管辖该预约所在场馆的管理员可查看
+func canViewBooking(c *gin.Context, db *repo.DB, b *repo.Booking) bool {
+	if canManageBooking(c, db, b) {
+		return true
+	}
+	userID, _ := auth.GetUserID(c)
+	ok, err := db.IsBookingParticipant(c.Request.Context(), b.ID, userID)
+	return err == nil && ok
+}
+
+// managesBooking 当前用户是否为该预约所在场馆的管理员（含平台管理员）
+func managesBooking(c *gin.Context, db *repo.DB, b *repo.Booking) bool {
+	scope, ok := adminScope(c, db)
+	if !ok {
+		return false
+	}
+	if scope.All {
+		return true
+	}
+	_, err := db.GetBookingByID(repo.WithScope(c.Request.Context(), scope), b.ID)
+	return err == nil
+}
+
+// scopeStatus 租户范围外的实体按 404 处理
+func scopeStatus(err error, fallback int) int {
+	if errors.Is(err, repo.ErrOutOfScope) {
+		return http.StatusNotFound
+	}
+	return fallback
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/Juny09/sport_backend/internal/ticket"
+	"github.com/gin-gonic/gin"
+)
+
+// ticketQRSize 票据二维码边长（像素）
+const ticketQRSize = 256
+
+// ticketLookupTimeout 验票时查询预约的最长等待时间，超时按离线结果返回
+const ticketLookupTimeout = 2 * time.Second
+
+// ticketClaims 由预约生成票据内容
+func ticketClaims(b *repo.Booking) ticket.Claims {
+	return ticket.Claims{BookingID: b.ID, UnitID: b.ResourceUnitID, Start: b.StartTime, End: b.EndTime}
+}
+
+// ticketVerdict 验票结果（中文说明：Offline 为 true 表示数据库未及时响应，仅依据签名与时间窗口判定）
+type ticketVerdict struct {
+	Valid   bool           `json:"valid"`
+	Reason  string         `json:"reason,omitempty"`
+	Offline bool           `json:"offline"`
+	Ticket  *ticket.Claims `json:"ticket,omitempty"`
+	Booking *repo.Booking  `json:"booking,omitempty"`
+}
+
+// RegisterTicketRoutes 注册预约票据路由（中文说明：本人或参与人领取签名票据与二维码，管理员在入口验票并可同时签到）
+func RegisterTicketRoutes(r *gin.Engine, db *repo.DB, jwtSecret string, signer *ticket.Signer, policy service.NoShowPolicy) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 领取票据：默认返回 JSON（token 与 data URI 形式的二维码）；?format=png 直接返回二维码图片
+	r.GET("/bookings/:id/ticket", authMW, func(c *gin.Context) {
+		id, err := parseIDParam(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		b, err := db.GetBookingByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if !canViewBooking(c, db, b) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		if b.Status != "confirmed" {
+			c.JSON(http.StatusConflict, gin.H{"error": "tickets are only issued for confirmed bookings"})
+			return
+		}
+		claims := ticketClaims(b)
+		token := signer.Sign(claims)
+		png, err := ticket.QRCode(token, ticketQRSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if c.Query("format") == "png" {
+			c.Data(http.StatusOK, "image/png", png)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"token":       token,
+			"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+			"valid_from":  claims.Start.Add(-policy.CheckInOpens),
+			"valid_until": claims.End,
+		})
+	})
+
+	// 验票：{token, check_in?, headcount?}；签名与时间窗口不依赖数据库，预约状态查询超时则返回离线结果
+	r.POST("/tickets/verify", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		var body struct {
+			Token     string `json:"token"`
+			CheckIn   bool   `json:"check_in"`
+			Headcount int    `json:"headcount"`
+		}
+		if err := c.BindJSON(&body); err != nil || body.Token == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "token required"})
+			return
+		}
+		now := time.Now()
+		claims, err := signer.Verify(body.Token)
+		if err != nil {
+			c.JSON(http.StatusOK, ticketVerdict{Reason: err.Error()})
+			return
+		}
+		v := ticketVerdict{Valid: true, Ticket: &claims}
+		if err := claims.CheckWindow(now, policy.CheckInOpens); err != nil {
+			v.Valid, v.Reason = false, err.Error()
+		}
+
+		ctx := c.Request.Context()
+		type lookup struct {
+			b   *repo.Booking
+			err error
+		}
+		ch := make(chan lookup, 1)
+		go func() {
+			b, err := db.GetBookingByID(ctx, claims.BookingID)
+			ch <- lookup{b, err}
+		}()
+		var b *repo.Booking
+		select {
+		case res := <-ch:
+			if res.err != nil {
+				// 不存在或不在当前管理员的场馆范围内
+				c.JSON(http.StatusOK, ticketVerdict{Reason: "booking not found", Ticket: &claims})
+				return
+			}
+			b = res.b
+		case <-time.After(ticketLookupTimeout):
+			v.Offline = true
+			c.JSON(http.StatusOK, v)
+			return
+		}
+		v.Booking = b
+		switch {
+		case b.ResourceUnitID != claims.UnitID || !b.StartTime.Equal(claims.Start) || !b.EndTime.Equal(claims.End):
+			v.Valid, v.Reason = false, "ticket superseded: booking was moved"
+		case b.Status != "confirmed":
+			v.Valid, v.Reason = false, "booking is "+b.Status
+		case v.Valid && b.CheckedInAt != nil:
+			v.Reason = "already checked in"
+		}
+		if v.Valid && body.CheckIn && b.CheckedInAt == nil {
+			out, err := service.CheckIn(actorContext(c), db, policy, b, "", body.Headcount, true, now)
+			switch {
+			case errors.Is(err, repo.ErrNotCheckInable), errors.Is(err, service.ErrCheckInWindow):
+				v.Reason = err.Error()
+			case err != nil:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			default:
+				v.Booking = out
+			}
+		}
+		c.JSON(http.StatusOK, v)
+	})
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterVenueRoutes 注册场馆路由（中文说明：查询公开；创建与分配管理员仅平台管理员；修改需该场馆管理员）
+func RegisterVenueRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	r.GET("/venues", func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		list, err := db.ListVenues(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	r.GET("/venues/:id", func(c *gin.Context) {
+		if db == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "db not configured"})
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		v, err := db.GetVenueByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusOK, v)
+	})
+
+	// 创建场馆
+	r.POST("/venues", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		var body struct {
+			Name     string `json:"name"`
+			Slug     string `json:"slug"`
+			Timezone string `json:"timezone"`
+			OpensAt  string `json:"opens_at"`
+			ClosesAt string `json:"closes_at"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if body.Name == "" || body.Slug == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name/slug required"})
+			return
+		}
+		if body.Timezone != "" {
+			if _, err := time.LoadLocation(body.Timezone); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid timezone"})
+				return
+			}
+		}
+		v, err := db.CreateVenue(actorContext(c), repo.NewVenue{
+			Name:     body.Name,
+			Slug:     body.Slug,
+			Timezone: body.Timezone,
+			OpensAt:  body.OpensAt,
+			ClosesAt: body.ClosesAt,
+		})
+		if errors.Is(err, repo.ErrInvalidOpeningHours) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, v)
+	})
+
+	// 修改场馆
+	r.PATCH("/venues/:id", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body repo.VenueUpdate
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if body.Timezone != nil {
+			if _, err := time.LoadLocation(*body.Timezone); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid timezone"})
+				return
+			}
+		}
+		v, err := db.UpdateVenue(actorContext(c), id, body)
+		if err != nil {
+			c.JSON(scopeStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, v)
+	})
+
+	// 场馆管理员列表
+	r.GET("/venues/:id/admins", authMW, func(c *gin.Context) {
+		if !requireAdmin(c, db) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		list, err := db.ListVenueAdmins(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(scopeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"venue_id": id, "user_ids": list})
+	})
+
+	// 分配场馆管理员
+	r.POST("/venues/:id/admins", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body struct {
+			UserID string `json:"user_id"`
+		}
+		if err := c.BindJSON(&body); err != nil || body.UserID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+			return
+		}
+		if _, err := db.GetVenueByID(c.Request.Context(), id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err := db.AddVenueAdmin(actorContext(c), id, body.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// 移除场馆管理员
+	r.DELETE("/venues/:id/admins/:user_id", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		if err := db.RemoveVenueAdmin(actorContext(c), id, c.Param("user_id")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWalletRoutes 注册钱包路由（中文说明：用户查看自己的余额与流水；平台管理员查看与调整任意用户，
+// 钱包余额可在全部场馆使用，场馆管理员无权操作）
+func RegisterWalletRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 我的钱包：?limit=50
+	r.GET("/wallet", authMW, func(c *gin.Context) {
+		userID, _ := auth.GetUserID(c)
+		writeWallet(c, db, userID)
+	})
+
+	r.GET("/admin/wallets/:user_id", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		writeWallet(c, db, c.Param("user_id"))
+	})
+
+	// 充值或调整：{type: topup|adjustment, amount, note}；充值金额须为正，调整可为负但余额不能低于 0
+	r.POST("/admin/wallets/:user_id/transactions", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		var body struct {
+			Type   string  `json:"type"`
+			Amount float64 `json:"amount"`
+			Note   string  `json:"note"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		switch body.Type {
+		case repo.WalletTopUp:
+			if body.Amount <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "top-up amount must be > 0"})
+				return
+			}
+		case repo.WalletAdjustment:
+			if body.Amount == 0 || body.Note == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "adjustment requires non-zero amount and note"})
+				return
+			}
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be topup or adjustment"})
+			return
+		}
+		tx, err := db.PostWalletTransaction(actorContext(c), repo.WalletTransaction{
+			UserID: c.Param("user_id"),
+			Type:   body.Type,
+			Amount: body.Amount,
+			Note:   body.Note,
+		})
+		if errors.Is(err, repo.ErrInsufficientBalance) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, repo.ErrServiceRoleRequired) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, tx)
+	})
+}
+
+// writeWallet 返回用户余额与最近流水
+func writeWallet(c *gin.Context, db *repo.DB, userID string) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	balance, err := db.GetWalletBalance(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := db.ListWalletTransactions(c.Request.Context(), userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "balance": balance, "transactions": list})
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/events"
+	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// validWebhookURL 回调地址须为 http(s) 绝对地址
+func validWebhookURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// normalizeEventTypes 校验并去重订阅的事件类型
+func normalizeEventTypes(types []string) ([]string, bool) {
+	if len(types) == 0 {
+		return nil, false
+	}
+	seen := map[string]bool{}
+	out := make([]string, 0, len(types))
+	for _, t := range types {
+		if !events.ValidType(t) {
+			return nil, false
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out, true
+}
+
+// RegisterWebhookRoutes 注册对外 Webhook 管理路由（平台管理员）
+func RegisterWebhookRoutes(r *gin.Engine, db *repo.DB, jwtSecret string) {
+	authMW := auth.NewJWTMiddleware(jwtSecret)
+
+	// 登记回调地址 {url, event_types: [...], description?}；签名密钥仅在此返回一次
+	r.POST("/admin/webhooks", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		var body struct {
+			URL         string   `json:"url"`
+			EventTypes  []string `json:"event_types"`
+			Description string   `json:"description"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if !validWebhookURL(body.URL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http(s) URL"})
+			return
+		}
+		types, ok := normalizeEventTypes(body.EventTypes)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "event_types must list one or more of the supported events", "supported": events.Types})
+			return
+		}
+		secret, err := service.NewWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ep, err := db.CreateWebhookEndpoint(actorContext(c), repo.WebhookEndpoint{
+			URL:         body.URL,
+			Secret:      secret,
+			EventTypes:  types,
+			Description: body.Description,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, ep)
+	})
+
+	r.GET("/admin/webhooks", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		list, err := db.ListWebhookEndpoints(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	// 修改 {url?, event_types?, description?, is_active?}
+	r.PATCH("/admin/webhooks/:id", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body repo.WebhookEndpointUpdate
+		if err := c.BindJSON(&body); err != nil || body.IsEmpty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+			return
+		}
+		if body.URL != nil && !validWebhookURL(*body.URL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http(s) URL"})
+			return
+		}
+		if body.EventTypes != nil {
+			types, ok := normalizeEventTypes(*body.EventTypes)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "event_types must list one or more of the supported events", "supported": events.Types})
+				return
+			}
+			body.EventTypes = &types
+		}
+		ep, err := db.UpdateWebhookEndpoint(actorContext(c), id, body)
+		if errors.Is(err, repo.ErrWebhookEndpointNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, ep)
+	})
+
+	r.DELETE("/admin/webhooks/:id", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		err = db.DeleteWebhookEndpoint(actorContext(c), id)
+		if errors.Is(err, repo.ErrWebhookEndpointNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// 最近的投递记录：?endpoint_id=&event_type=&status=pending|succeeded|failed&limit=100
+	r.GET("/admin/webhook_deliveries", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		f := repo.WebhookDeliveryFilter{EventType: c.Query("event_type"), Status: c.Query("status")}
+		switch f.Status {
+		case "", repo.WebhookPending, repo.WebhookSucceeded, repo.WebhookFailed:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, succeeded or failed"})
+			return
+		}
+		if s := c.Query("endpoint_id"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid endpoint_id"})
+				return
+			}
+			f.EndpointID = id
+		}
+		if s := c.Query("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+				return
+			}
+			f.Limit = n
+		}
+		list, err := db.ListWebhookDeliveries(c.Request.Context(), f)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	// 单条投递记录及每次尝试的状态码、错误与耗时
+	r.GET("/admin/webhook_deliveries/:id", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		d, err := db.GetWebhookDelivery(c.Request.Context(), id)
+		if errors.Is(err, repo.ErrWebhookDeliveryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, d)
+	})
+
+	// 重放：以相同事件 ID 与请求体重新投递（新建投递记录，立即进入队列）
+	r.POST("/admin/webhook_deliveries/:id/replay", authMW, func(c *gin.Context) {
+		if !requirePlatformAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		d, err := db.ReplayWebhookDelivery(actorContext(c), id)
+		if errors.Is(err, repo.ErrWebhookDeliveryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, d)
+	})
+}
@@ -0,0 +1,123 @@
+package httpserver
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKeyFunc 从请求中提取限流主体（IP、用户ID 等）；返回空字符串表示不限流
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// KeyByIP 按客户端 IP 限流
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser 按用户ID 限流（中文说明：限流中间件先于路由鉴权执行，因此自行解析 Bearer Token；无有效 Token 时回退为 IP）
+func KeyByUser(jwtSecret string) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		if jwtSecret != "" {
+			if h := c.GetHeader("Authorization"); strings.HasPrefix(h, "Bearer ") {
+				if sub, _, err := auth.ParseToken(strings.TrimPrefix(h, "Bearer "), jwtSecret); err == nil {
+					return "user:" + sub
+				}
+			}
+		}
+		return KeyByIP(c)
+	}
+}
+
+// RateLimitRule 限流规则（中文说明：按路由模板与方法匹配，同一请求可命中多条规则；设置 Path 时精确匹配，否则按 PathPrefix 前缀匹配）
+type RateLimitRule struct {
+	Name       string   // 规则名，用于区分不同规则的桶
+	Methods    []string // 为空表示所有方法
+	Path       string   // 精确匹配 gin 路由模板（FullPath），如 "/bookings"
+	PathPrefix string   // 匹配 gin 路由模板（FullPath）的前缀，如 "/auth/"
+	Limit      ratelimit.Limit
+	Key        RateLimitKeyFunc
+}
+
+func (r RateLimitRule) matches(method, fullPath string) bool {
+	if fullPath == "" {
+		return false
+	}
+	if r.Path != "" {
+		if fullPath != r.Path {
+			return false
+		}
+	} else if !strings.HasPrefix(fullPath, r.PathPrefix) {
+		return false
+	}
+	if len(r.Methods) == 0 {
+		return true
+	}
+	for _, m := range r.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRateLimitMiddleware 返回令牌桶限流中间件
+// 中文说明：超限返回 429 并设置 Retry-After（秒）
+func NewRateLimitMiddleware(store ratelimit.Store, rules ...RateLimitRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		for _, rule := range rules {
+			if !rule.matches(c.Request.Method, c.FullPath()) {
+				continue
+			}
+			key := rule.Key(c)
+			if key == "" {
+				continue
+			}
+			ok, wait := store.Take(rule.Name+"|"+key, rule.Limit, now)
+			if !ok {
+				secs := int(math.Ceil(wait.Seconds()))
+				if secs < 1 {
+					secs = 1
+				}
+				c.Header("Retry-After", strconv.Itoa(secs))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// DefaultRateLimitRules 默认限流规则（中文说明：登录注册按 IP 防撞库，创建预约按用户防抢场脚本；
+// 预约规则只匹配 POST /bookings，报价、签到、参与人与分摊等子路由不占用创建预约的额度）
+func DefaultRateLimitRules(jwtSecret string) []RateLimitRule {
+	return []RateLimitRule{
+		{
+			Name:       "auth",
+			Methods:    []string{http.MethodPost},
+			PathPrefix: "/auth/",
+			Limit:      ratelimit.PerMinute(10, 5),
+			Key:        KeyByIP,
+		},
+		{
+			Name:    "bookings-ip",
+			Methods: []string{http.MethodPost},
+			Path:    "/bookings",
+			Limit:   ratelimit.PerMinute(60, 20),
+			Key:     KeyByIP,
+		},
+		{
+			Name:    "bookings-user",
+			Methods: []string{http.MethodPost},
+			Path:    "/bookings",
+			Limit:   ratelimit.PerMinute(10, 5),
+			Key:     KeyByUser(jwtSecret),
+		},
+	}
+}
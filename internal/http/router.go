@@ -1,18 +1,128 @@
 package httpserver
 
 import (
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/Juny09/sport_backend/internal/auth"
+	"github.com/Juny09/sport_backend/internal/events"
 	"github.com/Juny09/sport_backend/internal/handlers"
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/ratelimit"
 	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/Juny09/sport_backend/internal/storage"
+	"github.com/Juny09/sport_backend/internal/ticket"
 	"github.com/gin-gonic/gin"
 )
 
+// Option 路由可选依赖（中文说明：未提供时使用本地默认实现）
+type Option func(*options)
+
+type options struct {
+	storage  storage.Store
+	payments payment.Provider
+	currency string
+	simulate bool
+	proxies  []string
+	limiter  ratelimit.Store
+	limits   []RateLimitRule
+	noShow   *service.NoShowPolicy
+	tickets  *ticket.Signer
+	notifier notify.Notifier
+	bus      *events.Bus
+}
+
+// WithStorage 指定文件存储（设施照片等）
+func WithStorage(s storage.Store) Option {
+	return func(o *options) { o.storage = s }
+}
+
+// WithPayments 指定支付渠道与币种（中文说明：未指定时预约直接确认，不走支付）
+func WithPayments(p payment.Provider, currency string) Option {
+	return func(o *options) {
+		o.payments = p
+		o.currency = currency
+	}
+}
+
+// WithPaymentSimulation 开放模拟支付接口（中文说明：仅开发环境使用，且支付渠道须为 fake）
+func WithPaymentSimulation() Option {
+	return func(o *options) { o.simulate = true }
+}
+
+// WithTrustedProxies 指定可信反向代理的 IP 或 CIDR（中文说明：仅来自这些地址的 X-Forwarded-For 用于识别客户端 IP；未指定时不信任任何代理，按连接地址限流）
+func WithTrustedProxies(proxies []string) Option {
+	return func(o *options) { o.proxies = proxies }
+}
+
+// WithRateLimit 指定限流存储与规则（中文说明：多实例部署时可传入共享存储；rules 为空时使用 DefaultRateLimitRules；未指定时使用内存存储与默认规则）
+func WithRateLimit(store ratelimit.Store, rules ...RateLimitRule) Option {
+	return func(o *options) {
+		o.limiter = store
+		o.limits = rules
+	}
+}
+
+// WithNoShowPolicy 指定签到窗口与爽约处罚规则（中文说明：未指定时使用 service.DefaultNoShowPolicy）
+func WithNoShowPolicy(p service.NoShowPolicy) Option {
+	return func(o *options) { o.noShow = &p }
+}
+
+// WithTicketSigner 指定预约票据签发器（中文说明：未指定时由 JWT 密钥派生）
+func WithTicketSigner(s *ticket.Signer) Option {
+	return func(o *options) { o.tickets = s }
+}
+
+// WithNotifier 指定用户通知渠道（中文说明：未指定时仅记录日志）
+func WithNotifier(n notify.Notifier) Option {
+	return func(o *options) { o.notifier = n }
+}
+
+// WithEventBus 指定事件总线（中文说明：需同时设为 repo 的事件发布器，实时推送才能收到写操作；未指定时使用空的本地总线）
+func WithEventBus(b *events.Bus) Option {
+	return func(o *options) { o.bus = b }
+}
+
 // NewRouter 构建 HTTP 路由（中文说明：集中管理所有 API 路由）
-func NewRouter(db *repo.DB, jwtSecret string, authClient *auth.Client) *gin.Engine {
+func NewRouter(db *repo.DB, jwtSecret string, authClient *auth.Client, opts ...Option) *gin.Engine {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.storage == nil {
+		o.storage = storage.NewLocalStore("uploads", "/media")
+	}
+	if o.currency == "" {
+		o.currency = "MYR"
+	}
+	if o.noShow == nil {
+		o.noShow = &service.DefaultNoShowPolicy
+	}
+	if o.tickets == nil {
+		o.tickets = ticket.NewSigner(jwtSecret)
+	}
+	if o.notifier == nil {
+		o.notifier = notify.NewLogNotifier(slog.Default())
+	}
+	if o.bus == nil {
+		o.bus = events.NewBus(nil)
+	}
+	if o.limiter == nil {
+		o.limiter = ratelimit.NewMemoryStore(0)
+	}
+	if len(o.limits) == 0 {
+		o.limits = DefaultRateLimitRules(jwtSecret)
+	}
+
 	r := gin.Default()
+	// gin 默认信任所有代理，客户端可伪造 X-Forwarded-For 绕过按 IP 限流
+	if err := r.SetTrustedProxies(o.proxies); err != nil {
+		slog.Error("invalid trusted proxies, trusting none", "error", err)
+		_ = r.SetTrustedProxies(nil)
+	}
 
 	// 添加 CORS 中间件
 	r.Use(func(c *gin.Context) {
@@ -36,6 +146,9 @@ func NewRouter(db *repo.DB, jwtSecret string, authClient *auth.Client) *gin.Engi
 		c.Next()
 	})
 
+	// 限流：默认为登录注册与创建预约（中文说明：存储与规则可经 WithRateLimit 替换）
+	r.Use(NewRateLimitMiddleware(o.limiter, o.limits...))
+
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok", "time": time.Now().UTC()})
@@ -61,14 +174,33 @@ func NewRouter(db *repo.DB, jwtSecret string, authClient *auth.Client) *gin.Engi
 		c.JSON(http.StatusOK, gin.H{"user_id": userID})
 	})
 
-	// 设施路由
-	handlers.RegisterFacilityRoutes(r, db, jwtSecret)
+	// 场馆与设施路由
+	handlers.RegisterVenueRoutes(r, db, jwtSecret)
+	handlers.RegisterFacilityTypeRoutes(r, db, jwtSecret)
+	handlers.RegisterFacilityRoutes(r, db, jwtSecret, o.notifier, o.payments)
+	handlers.RegisterFacilityPhotoRoutes(r, db, jwtSecret, o.storage)
 
 	// 预留路由组（后续逐步实现）
 	// /availability, /bookings, /admin
-	handlers.RegisterAvailabilityRoutes(r, db)
-	handlers.RegisterBookingRoutes(r, db, jwtSecret)
+	handlers.RegisterAvailabilityRoutes(r, db, o.bus)
+	handlers.RegisterBookingRoutes(r, db, jwtSecret, o.notifier, o.payments, o.currency)
+	handlers.RegisterPaymentRoutes(r, db, jwtSecret, o.notifier, o.payments, o.simulate)
+	handlers.RegisterShareRoutes(r, db, jwtSecret, o.notifier, o.payments, o.currency, o.simulate)
+	handlers.RegisterParticipantRoutes(r, db, jwtSecret)
+	handlers.RegisterCheckInRoutes(r, db, jwtSecret, *o.noShow)
+	handlers.RegisterTicketRoutes(r, db, jwtSecret, o.tickets, *o.noShow)
+	handlers.RegisterCalendarRoutes(r, db, jwtSecret)
+	handlers.RegisterNotificationRoutes(r, db, jwtSecret)
+	handlers.RegisterWalletRoutes(r, db, jwtSecret)
+	handlers.RegisterMembershipRoutes(r, db, jwtSecret)
+	handlers.RegisterPromoCodeRoutes(r, db, jwtSecret)
 	handlers.RegisterAdminRoutes(r, db, jwtSecret)
+	handlers.RegisterPricingRoutes(r, db, jwtSecret)
+	handlers.RegisterBlackoutRoutes(r, db, jwtSecret, o.notifier, o.payments)
+	handlers.RegisterAuditRoutes(r, db, jwtSecret)
+	handlers.RegisterWebhookRoutes(r, db, jwtSecret)
+	handlers.RegisterDashboardRoutes(r, db, jwtSecret, o.bus)
+	handlers.RegisterBookingEventRoutes(r, db, jwtSecret)
 
 	return r
 }
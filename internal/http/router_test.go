@@ -4,6 +4,9 @@ import (
     "net/http"
     "net/http/httptest"
     "testing"
+
+    "github.com/Juny09/sport_backend/internal/ratelimit"
+    "github.com/gin-gonic/gin"
 )
 
 // 测试基础路由（中文说明：确保健康检查与鉴权行为正常）
@@ -27,3 +30,121 @@ func TestHealthAndAuth(t *testing.T) {
     }
 }
 
+
+// 测试限流中间件（中文说明：超出突发后返回 429 与 Retry-After）
+func TestRateLimitMiddleware(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+    r.Use(NewRateLimitMiddleware(ratelimit.NewMemoryStore(0), RateLimitRule{
+        Name:       "auth",
+        Methods:    []string{http.MethodPost},
+        PathPrefix: "/auth/",
+        Limit:      ratelimit.PerMinute(1, 2),
+        Key:        KeyByIP,
+    }))
+    r.POST("/auth/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+    r.GET("/auth/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+    for i := 0; i < 2; i++ {
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/login", nil))
+        if w.Code != http.StatusOK {
+            t.Fatalf("request %d: expected 200, got %d", i, w.Code)
+        }
+    }
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/login", nil))
+    if w.Code != http.StatusTooManyRequests {
+        t.Fatalf("expected 429, got %d", w.Code)
+    }
+    if w.Header().Get("Retry-After") == "" {
+        t.Fatalf("expected Retry-After header")
+    }
+
+    // 未匹配方法不受限
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET should not be limited, got %d", w.Code)
+    }
+}
+
+// 测试预约限流只匹配 POST /bookings（中文说明：报价等子路由不占用创建预约的额度）
+func TestRateLimitExactPath(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+    r.Use(NewRateLimitMiddleware(ratelimit.NewMemoryStore(0), RateLimitRule{
+        Name:    "bookings",
+        Methods: []string{http.MethodPost},
+        Path:    "/bookings",
+        Limit:   ratelimit.PerMinute(1, 1),
+        Key:     KeyByIP,
+    }))
+    r.POST("/bookings", func(c *gin.Context) { c.Status(http.StatusOK) })
+    r.POST("/bookings/quote", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+    for i := 0; i < 3; i++ {
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bookings/quote", nil))
+        if w.Code != http.StatusOK {
+            t.Fatalf("quote %d: expected 200, got %d", i, w.Code)
+        }
+    }
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bookings", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", w.Code)
+    }
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bookings", nil))
+    if w.Code != http.StatusTooManyRequests {
+        t.Fatalf("expected 429, got %d", w.Code)
+    }
+}
+
+// 测试默认不信任代理（中文说明：伪造 X-Forwarded-For 不能绕过按 IP 限流）
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    r := NewRouter(nil, "", nil)
+    var last int
+    for i := 0; i < 6; i++ {
+        req := httptest.NewRequest(http.MethodPost, "/bookings", nil)
+        req.Header.Set("X-Forwarded-For", "203.0.113."+string(rune('1'+i)))
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        last = w.Code
+    }
+    if last != http.StatusTooManyRequests {
+        t.Fatalf("expected 429 despite X-Forwarded-For, got %d", last)
+    }
+}
+
+// 测试自定义限流存储与规则替换默认配置
+func TestWithRateLimit(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    r := NewRouter(nil, "", nil, WithRateLimit(ratelimit.NewMemoryStore(0), RateLimitRule{
+        Name:    "health",
+        Methods: []string{http.MethodGet},
+        Path:    "/health",
+        Limit:   ratelimit.PerMinute(1, 1),
+        Key:     KeyByIP,
+    }))
+    codes := make([]int, 2)
+    for i := range codes {
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+        codes[i] = w.Code
+    }
+    if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+        t.Fatalf("expected 200 then 429, got %v", codes)
+    }
+
+    // 自定义规则替换默认规则：POST /bookings 不再受限
+    for i := 0; i < 6; i++ {
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bookings", nil))
+        if w.Code == http.StatusTooManyRequests {
+            t.Fatalf("default booking rule should be replaced")
+        }
+    }
+}
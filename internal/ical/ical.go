@@ -0,0 +1,223 @@
+package ical
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 事件状态（RFC 5545 STATUS）
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+// maxLineOctets 内容行折行长度（RFC 5545 3.1，不含 CRLF）
+const maxLineOctets = 75
+
+const (
+	localLayout = "20060102T150405"
+	utcLayout   = "20060102T150405Z"
+)
+
+// Event 日历事件（中文说明：Location 为空时按 UTC 输出时间，否则按该时区输出并附带 VTIMEZONE）
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Place       string // LOCATION
+	Status      string
+	Location    *time.Location
+}
+
+// Calendar 日历（中文说明：Name 写入 X-WR-CALNAME，订阅客户端据此显示日历名称）
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Write 以 iCalendar 格式输出（中文说明：CRLF 换行、75 字节折行；按事件用到的时区生成 VTIMEZONE）
+func (c Calendar) Write(w io.Writer, now time.Time) error {
+	lw := &lineWriter{w: w}
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:-//sport_backend//bookings//EN")
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	lw.line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	lw.line("X-PUBLISHED-TTL:PT1H")
+	for _, tz := range timezones(c.Events) {
+		writeTimezone(lw, tz.loc, tz.from, tz.to)
+	}
+	stamp := now.UTC().Format(utcLayout)
+	for _, e := range c.Events {
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + e.UID)
+		lw.line("DTSTAMP:" + stamp)
+		lw.line(dateProp("DTSTART", e.Start, e.Location))
+		lw.line(dateProp("DTEND", e.End, e.Location))
+		lw.line("SUMMARY:" + escape(e.Summary))
+		if e.Place != "" {
+			lw.line("LOCATION:" + escape(e.Place))
+		}
+		if e.Description != "" {
+			lw.line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.Status != "" {
+			lw.line("STATUS:" + e.Status)
+		}
+		if e.Status == StatusCancelled {
+			lw.line("TRANSP:TRANSPARENT")
+		} else {
+			lw.line("TRANSP:OPAQUE")
+		}
+		lw.line("END:VEVENT")
+	}
+	lw.line("END:VCALENDAR")
+	return lw.err
+}
+
+// dateProp 输出带时区的时间属性；无时区时使用 UTC
+func dateProp(name string, t time.Time, loc *time.Location) string {
+	if loc == nil || loc == time.UTC {
+		return name + ":" + t.UTC().Format(utcLayout)
+	}
+	return name + ";TZID=" + loc.String() + ":" + t.In(loc).Format(localLayout)
+}
+
+// tzRange 某时区覆盖的事件时间范围
+type tzRange struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+// timezones 汇总事件用到的时区及其时间范围（按名称排序，输出稳定）
+func timezones(events []Event) []tzRange {
+	byName := map[string]*tzRange{}
+	for _, e := range events {
+		if e.Location == nil || e.Location == time.UTC {
+			continue
+		}
+		r, ok := byName[e.Location.String()]
+		if !ok {
+			byName[e.Location.String()] = &tzRange{loc: e.Location, from: e.Start, to: e.End}
+			continue
+		}
+		if e.Start.Before(r.from) {
+			r.from = e.Start
+		}
+		if e.End.After(r.to) {
+			r.to = e.End
+		}
+	}
+	res := make([]tzRange, 0, len(byName))
+	for _, r := range byName {
+		res = append(res, *r)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].loc.String() < res[j].loc.String() })
+	return res
+}
+
+// Transition 时区偏移变化（中文说明：At 为变化时刻，Before/After 为变化前后的 UTC 偏移秒数）
+type Transition struct {
+	At     time.Time
+	Before int
+	After  int
+	Name   string
+	IsDST  bool
+}
+
+// Transitions 计算 [from, to] 内的时区偏移变化，第一项为 from 时刻所在时段的起点
+// 中文说明：依据 Go 内置时区数据逐段查找，无夏令时的时区只返回一项
+func Transitions(loc *time.Location, from, to time.Time) []Transition {
+	t := from.In(loc)
+	name, offset := t.Zone()
+	start, end := t.ZoneBounds()
+	if start.IsZero() {
+		start = time.Unix(0, 0)
+	}
+	res := []Transition{{At: start, Before: offset, After: offset, Name: name, IsDST: t.IsDST()}}
+	for !end.IsZero() && !end.After(to) {
+		next := end.In(loc)
+		nextName, nextOffset := next.Zone()
+		res = append(res, Transition{At: end, Before: offset, After: nextOffset, Name: nextName, IsDST: next.IsDST()})
+		offset = nextOffset
+		_, end = next.ZoneBounds()
+	}
+	return res
+}
+
+// writeTimezone 输出 VTIMEZONE（中文说明：为事件范围内的每次偏移变化输出一个 STANDARD/DAYLIGHT 子组件，DTSTART 为变化前的本地时间）
+func writeTimezone(lw *lineWriter, loc *time.Location, from, to time.Time) {
+	lw.line("BEGIN:VTIMEZONE")
+	lw.line("TZID:" + loc.String())
+	for _, tr := range Transitions(loc, from, to) {
+		kind := "STANDARD"
+		if tr.IsDST {
+			kind = "DAYLIGHT"
+		}
+		lw.line("BEGIN:" + kind)
+		lw.line("DTSTART:" + tr.At.In(time.FixedZone("", tr.Before)).Format(localLayout))
+		lw.line("TZOFFSETFROM:" + formatOffset(tr.Before))
+		lw.line("TZOFFSETTO:" + formatOffset(tr.After))
+		if tr.Name != "" {
+			lw.line("TZNAME:" + escape(tr.Name))
+		}
+		lw.line("END:" + kind)
+	}
+	lw.line("END:VTIMEZONE")
+}
+
+// formatOffset 将 UTC 偏移秒数格式化为 ±hhmm（有秒数时为 ±hhmmss）
+func formatOffset(sec int) string {
+	sign := "+"
+	if sec < 0 {
+		sign = "-"
+		sec = -sec
+	}
+	if sec%60 != 0 {
+		return fmt.Sprintf("%s%02d%02d%02d", sign, sec/3600, sec/60%60, sec%60)
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, sec/3600, sec/60%60)
+}
+
+// escape 转义 TEXT 值中的反斜杠、分号、逗号与换行
+func escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// lineWriter 按 RFC 5545 折行输出内容行（中文说明：按字节计长，不拆分多字节字符）
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (l *lineWriter) line(s string) {
+	if l.err != nil {
+		return
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 0
+			limit = maxLineOctets - 1 // 续行以空格开头
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	b.WriteString("\r\n")
+	_, l.err = io.WriteString(l.w, b.String())
+}
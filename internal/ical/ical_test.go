@@ -0,0 +1,64 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试日历输出：时区事件带 TZID 与 VTIMEZONE、取消状态、转义与 75 字节折行
+func TestCalendarWrite(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	start := time.Date(2025, 3, 29, 10, 0, 0, 0, london)
+	cal := Calendar{Name: "My bookings", Events: []Event{
+		{UID: "booking-1@test", Start: start, End: start.Add(time.Hour), Summary: "Badminton, Court 1; Hall A", Status: StatusConfirmed, Location: london},
+		{UID: "booking-2@test", Start: start.AddDate(0, 0, 2), End: start.AddDate(0, 0, 2).Add(time.Hour), Summary: "Tennis", Status: StatusCancelled, Location: london},
+		{UID: "booking-3@test", Start: start, End: start.Add(time.Hour), Summary: strings.Repeat("羽毛球", 20), Description: "line1\nline2"},
+	}}
+	var buf bytes.Buffer
+	if err := cal.Write(&buf, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/London\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20250330T010000\r\nTZOFFSETFROM:+0000\r\nTZOFFSETTO:+0100\r\n",
+		"DTSTART;TZID=Europe/London:20250329T100000\r\n",
+		"DTSTART;TZID=Europe/London:20250331T100000\r\n",
+		"STATUS:CANCELLED\r\n",
+		"SUMMARY:Badminton\\, Court 1\\; Hall A\r\n",
+		"DESCRIPTION:line1\\nline2\r\n",
+		"DTSTART:20250329T100000Z\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "BEGIN:VTIMEZONE") != 1 {
+		t.Fatalf("expected one VTIMEZONE")
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Fatalf("line longer than %d octets: %q", maxLineOctets, line)
+		}
+	}
+}
+
+func TestTransitionsWithoutDST(t *testing.T) {
+	kl, err := time.LoadLocation("Asia/Kuala_Lumpur")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	trs := Transitions(kl, from, from.AddDate(1, 0, 0))
+	if len(trs) != 1 || trs[0].After != 8*3600 || trs[0].IsDST {
+		t.Fatalf("unexpected transitions %+v", trs)
+	}
+	if formatOffset(8*3600) != "+0800" || formatOffset(-(5*3600+30*60)) != "-0530" {
+		t.Fatal("unexpected offset format")
+	}
+}
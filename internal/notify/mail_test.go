@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试邮件构造：中文主题按 RFC 2047 编码，正文 base64 按 76 字符折行
+func TestBuildMessage(t *testing.T) {
+	body := strings.Repeat("预约已确认。", 30)
+	raw := string(buildMessage("no-reply@example.com", Message{To: "a@example.com", Subject: "预约已确认", Body: body}, time.Unix(0, 0)))
+	head, enc, ok := strings.Cut(raw, "\r\n\r\n")
+	if !ok {
+		t.Fatal("missing header separator")
+	}
+	if !strings.Contains(head, "Subject: =?UTF-8?b?") || !strings.Contains(head, "To: a@example.com") {
+		t.Fatalf("unexpected headers:\n%s", head)
+	}
+	for _, line := range strings.Split(strings.TrimRight(enc, "\r\n"), "\r\n") {
+		if len(line) > 76 {
+			t.Fatalf("body line longer than 76: %q", line)
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(enc, "\r\n", ""))
+	if err != nil || string(decoded) != body {
+		t.Fatalf("body does not round-trip: %v", err)
+	}
+}
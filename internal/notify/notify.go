@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+)
+
+// 通知类型
+const (
+	KindBlackoutCancelled  = "blackout_cancelled"
+	KindBlackoutRelocated  = "blackout_relocated"
+	KindClosureCancelled   = "closure_cancelled" // 设施/单元停用导致取消
+	KindClosureRelocated   = "closure_relocated" // 设施/单元停用导致迁移
+	KindBookingConfirmed   = "booking_confirmed"
+	KindBookingRescheduled = "booking_rescheduled"
+	KindBookingCancelled   = "booking_cancelled"
+	KindWaitlistOffer      = "waitlist_offer" // 候补名额释放
+	KindReminder24h        = "booking_reminder_24h"
+	KindReminder2h         = "booking_reminder_2h"
+	KindRatingPrompt       = "rating_prompt" // 结束后邀请评分
+)
+
+// 通知语言
+const (
+	LocaleZH = "zh"
+	LocaleEN = "en"
+)
+
+// ValidLocale 是否为支持的通知语言
+func ValidLocale(locale string) bool {
+	return locale == LocaleZH || locale == LocaleEN
+}
+
+// Notification 发送给用户的通知（中文说明：Data 为模板变量，如预约时间、新场地等）
+type Notification struct {
+	UserID    string
+	Kind      string
+	BookingID int64
+	Data      map[string]interface{}
+}
+
+// Notifier 通知发送接口
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier 仅记录日志的通知实现（中文说明：本地开发或未配置发送渠道时使用）
+type LogNotifier struct {
+	Logger *slog.Logger
+}
+
+// NewLogNotifier 创建日志通知器
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &LogNotifier{Logger: logger}
+}
+
+// Notify 实现 Notifier 接口
+func (l *LogNotifier) Notify(ctx context.Context, n Notification) error {
+	l.Logger.Info("notification", "user_id", n.UserID, "kind", n.Kind, "booking_id", n.BookingID, "data", n.Data)
+	return nil
+}
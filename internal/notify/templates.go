@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Message 邮件内容
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// emailTemplate 单个语言的邮件模板（中文说明：变量见 Render）
+type emailTemplate struct {
+	subject, body string
+}
+
+// bookingLines 模板公共片段：场地、时间与预约编号
+const (
+	bookingLinesZH = "场地：{{.facility}} - {{.unit}}\n时间：{{.start}} 至 {{.end}}\n预约编号：#{{.booking_id}}\n"
+	bookingLinesEN = "Court: {{.facility}} - {{.unit}}\nTime: {{.start}} to {{.end}}\nBooking: #{{.booking_id}}\n"
+	reasonZH       = "{{with .reason}}原因：{{.}}\n{{end}}"
+	reasonEN       = "{{with .reason}}Reason: {{.}}\n{{end}}"
+	refundZH       = "{{with .refund_amount}}退款金额：{{.}}，将原路退回。\n{{end}}"
+	refundEN       = "{{with .refund_amount}}Refund: {{.}}, returned to your original payment method.\n{{end}}"
+)
+
+var templateSources = map[string]map[string]emailTemplate{
+	KindBookingConfirmed: {
+		LocaleZH: {"预约已确认：{{.facility}} {{.start}}", "您好，\n\n您的预约已确认。\n\n" + bookingLinesZH + "\n如需取消或改签，请在应用内操作。\n"},
+		LocaleEN: {"Booking confirmed: {{.facility}} {{.start}}", "Hello,\n\nYour booking is confirmed.\n\n" + bookingLinesEN + "\nYou can cancel or reschedule in the app.\n"},
+	},
+	KindBookingRescheduled: {
+		LocaleZH: {"预约已改签：{{.facility}} {{.start}}", "您好，\n\n您的预约时间已更改{{with .previous_start}}（原时间 {{.}}）{{end}}。\n\n" + bookingLinesZH},
+		LocaleEN: {"Booking rescheduled: {{.facility}} {{.start}}", "Hello,\n\nYour booking has been moved{{with .previous_start}} (previously {{.}}){{end}}.\n\n" + bookingLinesEN},
+	},
+	KindBookingCancelled: {
+		LocaleZH: {"预约已取消：{{.facility}} {{.start}}", "您好，\n\n您的预约已取消。\n\n" + bookingLinesZH + reasonZH + refundZH},
+		LocaleEN: {"Booking cancelled: {{.facility}} {{.start}}", "Hello,\n\nYour booking has been cancelled.\n\n" + bookingLinesEN + reasonEN + refundEN},
+	},
+	KindBlackoutCancelled: {
+		LocaleZH: {"场地封场，预约已取消：{{.facility}} {{.start}}", "您好，\n\n由于场地封场，您的预约已取消，已支付金额将按规则退回。\n\n" + bookingLinesZH + reasonZH},
+		LocaleEN: {"Court closed, booking cancelled: {{.facility}} {{.start}}", "Hello,\n\nThe court is closed during your booking, so it has been cancelled. Any payment will be refunded.\n\n" + bookingLinesEN + reasonEN},
+	},
+	KindBlackoutRelocated: {
+		LocaleZH: {"场地封场，预约已调整至 {{.new_unit_name}}", "您好，\n\n由于场地封场，您的预约已调整至 {{.new_unit_name}}，时间不变。\n\n" + bookingLinesZH + reasonZH},
+		LocaleEN: {"Court closed, booking moved to {{.new_unit_name}}", "Hello,\n\nThe court is closed during your booking, so it has been moved to {{.new_unit_name}} at the same time.\n\n" + bookingLinesEN + reasonEN},
+	},
+	KindClosureCancelled: {
+		LocaleZH: {"场地停用，预约已取消：{{.facility}} {{.start}}", "您好，\n\n由于场地停用，您的预约已取消，已支付金额将按规则退回。\n\n" + bookingLinesZH + reasonZH},
+		LocaleEN: {"Court unavailable, booking cancelled: {{.facility}} {{.start}}", "Hello,\n\nThe court is no longer available, so your booking has been cancelled. Any payment will be refunded.\n\n" + bookingLinesEN + reasonEN},
+	},
+	KindClosureRelocated: {
+		LocaleZH: {"场地停用，预约已调整至 {{.new_unit_name}}", "您好，\n\n由于场地停用，您的预约已调整至 {{.new_unit_name}}，时间不变。\n\n" + bookingLinesZH + reasonZH},
+		LocaleEN: {"Court unavailable, booking moved to {{.new_unit_name}}", "Hello,\n\nThe court is no longer available, so your booking has been moved to {{.new_unit_name}} at the same time.\n\n" + bookingLinesEN + reasonEN},
+	},
+	KindReminder24h: {
+		LocaleZH: {"明天的预约提醒：{{.facility}} {{.start}}", "您好，\n\n提醒您明天有一场预约。\n\n" + bookingLinesZH + "\n如无法到场，请提前取消，以免计为爽约。\n"},
+		LocaleEN: {"Reminder: {{.facility}} tomorrow at {{.start}}", "Hello,\n\nA reminder that you have a booking tomorrow.\n\n" + bookingLinesEN + "\nIf you can no longer make it, please cancel in advance so it is not counted as a no-show.\n"},
+	},
+	KindReminder2h: {
+		LocaleZH: {"预约即将开始：{{.facility}} {{.start}}", "您好，\n\n您的预约将在 2 小时后开始，请准时到场签到。\n\n" + bookingLinesZH},
+		LocaleEN: {"Starting soon: {{.facility}} at {{.start}}", "Hello,\n\nYour booking starts in 2 hours. Please arrive on time to check in.\n\n" + bookingLinesEN},
+	},
+	KindRatingPrompt: {
+		LocaleZH: {"为本次运动评分：{{.facility}}", "您好，\n\n感谢您在 {{.facility}} 运动！欢迎在应用中为本次场地体验评分，您的反馈将帮助我们改进。\n\n" + bookingLinesZH},
+		LocaleEN: {"How was your session at {{.facility}}?", "Hello,\n\nThanks for playing at {{.facility}}! Please rate your session in the app; your feedback helps us improve.\n\n" + bookingLinesEN},
+	},
+	KindWaitlistOffer: {
+		LocaleZH: {"候补名额已释放：{{.facility}} {{.start}}", "您好，\n\n您候补的时段有空位了。\n\n场地：{{.facility}} - {{.unit}}\n时间：{{.start}} 至 {{.end}}\n{{with .expires_at}}请在 {{.}} 前完成预约，逾期名额将释放给下一位。\n{{end}}"},
+		LocaleEN: {"Waitlist spot available: {{.facility}} {{.start}}", "Hello,\n\nA spot you were waiting for is now available.\n\nCourt: {{.facility}} - {{.unit}}\nTime: {{.start}} to {{.end}}\n{{with .expires_at}}Please book before {{.}}, after which the spot goes to the next person.\n{{end}}"},
+	},
+}
+
+// templates 解析后的模板，键为 kind + "." + locale
+var templates = parseTemplates()
+
+func parseTemplates() map[string]*template.Template {
+	out := map[string]*template.Template{}
+	for kind, byLocale := range templateSources {
+		for locale, src := range byLocale {
+			name := kind + "." + locale
+			t := template.Must(template.New(name).Parse(src.subject))
+			template.Must(t.New("body").Parse(src.body))
+			out[name] = t
+		}
+	}
+	return out
+}
+
+// Render 渲染通知邮件（中文说明：不支持的语言按中文渲染；变量 facility、unit、start、end、booking_id 由调用方提供，
+// reason、refund_amount、new_unit_name、previous_start、expires_at 可选）
+func Render(kind, locale string, data map[string]interface{}) (Message, error) {
+	if !ValidLocale(locale) {
+		locale = LocaleZH
+	}
+	t, ok := templates[kind+"."+locale]
+	if !ok {
+		return Message{}, fmt.Errorf("no email template for %q", kind)
+	}
+	var subject, body strings.Builder
+	if err := t.Execute(&subject, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, err
+	}
+	return Message{Subject: strings.TrimSpace(subject.String()), Body: body.String()}, nil
+}
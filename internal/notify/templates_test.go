@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+// 测试模板：每种通知都有中英文模板，且必填变量齐全时不出现缺失占位（迁移类通知另需 new_unit_name）
+func TestRenderAllKinds(t *testing.T) {
+	data := map[string]interface{}{
+		"booking_id":    int64(42),
+		"facility":      "Sports Hall",
+		"unit":          "Court 1",
+		"start":         "2025-03-01 10:00",
+		"end":           "11:00",
+		"new_unit_name": "Court 2",
+	}
+	for kind := range templateSources {
+		for _, locale := range []string{LocaleZH, LocaleEN} {
+			m, err := Render(kind, locale, data)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", kind, locale, err)
+			}
+			if m.Subject == "" || strings.Contains(m.Subject+m.Body, "<no value>") {
+				t.Fatalf("%s/%s rendered badly: %q / %q", kind, locale, m.Subject, m.Body)
+			}
+		}
+	}
+}
+
+func TestRenderOptionalFields(t *testing.T) {
+	data := map[string]interface{}{
+		"booking_id": int64(7), "facility": "Hall", "unit": "A", "start": "s", "end": "e",
+		"reason": "rain", "refund_amount": 12.5,
+	}
+	m, err := Render(KindBookingCancelled, LocaleEN, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(m.Body, "Reason: rain") || !strings.Contains(m.Body, "Refund: 12.5") {
+		t.Fatalf("optional fields missing: %q", m.Body)
+	}
+	// 不支持的语言按中文渲染
+	m, err = Render(KindBookingCancelled, "fr", data)
+	if err != nil || !strings.Contains(m.Subject, "预约已取消") {
+		t.Fatalf("expected zh fallback, got %q (%v)", m.Subject, err)
+	}
+	if _, err := Render("unknown", LocaleEN, data); err == nil {
+		t.Fatal("expected error for unknown kind")
+	}
+}
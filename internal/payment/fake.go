@@ -0,0 +1,83 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FakeProvider 确定性的本地支付渠道（中文说明：不发起网络请求；引用按序号生成，退款立即成功）
+type FakeProvider struct {
+	secret string
+	now    func() time.Time
+
+	mu  sync.Mutex
+	seq int64
+}
+
+// NewFakeProvider 创建本地支付渠道（中文说明：secret 用于 Webhook 签名）
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{secret: secret, now: time.Now}
+}
+
+// Name 实现 Provider 接口
+func (f *FakeProvider) Name() string { return "fake" }
+
+func (f *FakeProvider) next() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	return f.seq
+}
+
+// CreateIntent 实现 Provider 接口
+func (f *FakeProvider) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be > 0")
+	}
+	ref := fmt.Sprintf("fake_pi_%s_%d", req.Reference, f.next())
+	return &Intent{Ref: ref, ClientSecret: ref + "_secret", Status: IntentRequiresPayment}, nil
+}
+
+// Refund 实现 Provider 接口
+func (f *FakeProvider) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be > 0")
+	}
+	return &Refund{Ref: fmt.Sprintf("fake_re_%d", f.next()), Status: IntentSucceeded}, nil
+}
+
+// ParseWebhook 实现 Provider 接口
+func (f *FakeProvider) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	if err := VerifySignature(f.secret, payload, header.Get(SignatureHeader), f.now()); err != nil {
+		return nil, err
+	}
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownEvent, err)
+	}
+	switch ev.Type {
+	case EventPaymentSucceeded, EventPaymentFailed, EventRefundSucceeded:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEvent, ev.Type)
+	}
+	return &ev, nil
+}
+
+// SignedEvent 生成带签名的 Webhook 请求（中文说明：供本地模拟支付结果与测试使用）
+func (f *FakeProvider) SignedEvent(ev Event) ([]byte, http.Header, error) {
+	if ev.ID == "" {
+		ev.ID = fmt.Sprintf("fake_evt_%d", f.next())
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return nil, nil, err
+	}
+	h := http.Header{}
+	h.Set(SignatureHeader, Sign(f.secret, body, f.now()))
+	return body, h, nil
+}
@@ -0,0 +1,131 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader Webhook 签名请求头（格式：t=<unix 秒>,v1=<hex(HMAC-SHA256(secret, "t.body"))>）
+const SignatureHeader = "X-Payment-Signature"
+
+// signatureTolerance 签名时间戳允许的偏差，防止重放
+const signatureTolerance = 5 * time.Minute
+
+// 错误定义
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrUnknownEvent     = errors.New("unknown webhook event")
+)
+
+// 支付意图状态
+const (
+	IntentRequiresPayment = "requires_payment"
+	IntentSucceeded       = "succeeded"
+)
+
+// Webhook 事件类型
+const (
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentFailed    = "payment.failed"
+	EventRefundSucceeded  = "refund.succeeded"
+)
+
+// IntentRequest 创建支付意图（中文说明：Reference 为业务引用，如预约 ID，金额以元为单位保留两位小数）
+type IntentRequest struct {
+	Reference   string
+	Amount      float64
+	Currency    string
+	Description string
+}
+
+// Intent 支付意图（中文说明：ClientSecret 交给客户端完成支付，不落库）
+type Intent struct {
+	Ref          string
+	ClientSecret string
+	Status       string
+}
+
+// RefundRequest 退款请求
+type RefundRequest struct {
+	PaymentRef string
+	Amount     float64
+	Reason     string
+}
+
+// Refund 退款结果
+type Refund struct {
+	Ref    string
+	Status string // succeeded / pending
+}
+
+// Event Webhook 事件（已通过签名校验）
+type Event struct {
+	ID            string  `json:"id"`
+	Type          string  `json:"type"`
+	PaymentRef    string  `json:"payment_ref"`
+	RefundRef     string  `json:"refund_ref,omitempty"`
+	Amount        float64 `json:"amount"`
+	FailureReason string  `json:"failure_reason,omitempty"`
+}
+
+// Provider 支付渠道接口（中文说明：本地与测试使用 FakeProvider，生产可接入 Stripe 等）
+type Provider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+	// ParseWebhook 校验签名并解析事件；签名无效返回 ErrInvalidSignature
+	ParseWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// Sign 计算签名头的值
+func Sign(secret string, payload []byte, ts time.Time) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + mac(secret, t, payload)
+}
+
+// VerifySignature 校验签名头与时间戳（中文说明：超出容忍窗口的时间戳视为重放；未配置密钥时一律拒绝）
+func VerifySignature(secret string, payload []byte, header string, now time.Time) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			v1 = v
+		}
+	}
+	if secret == "" || t == "" || v1 == "" {
+		return ErrInvalidSignature
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > signatureTolerance || d < -signatureTolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(v1), []byte(mac(secret, t, payload))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mac(secret, t string, payload []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,61 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// 测试本地支付渠道：引用确定、签名校验、篡改与过期时间戳被拒绝
+func TestFakeProviderWebhook(t *testing.T) {
+	f := NewFakeProvider("whsec")
+	ctx := context.Background()
+
+	in, err := f.CreateIntent(ctx, IntentRequest{Reference: "42", Amount: 30})
+	if err != nil {
+		t.Fatalf("create intent: %v", err)
+	}
+	if in.Ref != "fake_pi_42_1" || in.Status != IntentRequiresPayment {
+		t.Fatalf("unexpected intent %+v", in)
+	}
+
+	body, h, err := f.SignedEvent(Event{Type: EventPaymentSucceeded, PaymentRef: in.Ref, Amount: 30})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	ev, err := f.ParseWebhook(body, h)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if ev.PaymentRef != in.Ref || ev.Type != EventPaymentSucceeded {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-2] = '9'
+	if _, err := f.ParseWebhook(tampered, h); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature for tampered body, got %v", err)
+	}
+
+	old := http.Header{}
+	old.Set(SignatureHeader, Sign("whsec", body, time.Now().Add(-time.Hour)))
+	if _, err := f.ParseWebhook(body, old); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected stale timestamp rejected, got %v", err)
+	}
+
+	other := http.Header{}
+	other.Set(SignatureHeader, Sign("other", body, time.Now()))
+	if _, err := f.ParseWebhook(body, other); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected wrong secret rejected, got %v", err)
+	}
+
+	// 未配置密钥时拒绝以空密钥签名的回调
+	unset := NewFakeProvider("")
+	blank := http.Header{}
+	blank.Set(SignatureHeader, Sign("", body, time.Now()))
+	if _, err := unset.ParseWebhook(body, blank); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected empty secret rejected, got %v", err)
+	}
+}
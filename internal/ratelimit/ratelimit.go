@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit 令牌桶参数（中文说明：Rate 为每秒补充的令牌数，Burst 为桶容量）
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute 以“每分钟 n 次、突发 burst 次”构造限额
+func PerMinute(n, burst int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: burst}
+}
+
+// Store 限流状态存储接口（中文说明：内存实现用于单实例，共享存储如 Redis 可实现同一接口）
+// Take 尝试从 key 对应的桶中取出一个令牌；失败时返回需要等待的时长
+type Store interface {
+	Take(key string, limit Limit, now time.Time) (allowed bool, retryAfter time.Duration)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryStore 进程内令牌桶存储
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	idleTTL time.Duration
+	lastGC  time.Time
+}
+
+// NewMemoryStore 创建内存存储（中文说明：超过 idleTTL 未访问的桶会被惰性清理）
+func NewMemoryStore(idleTTL time.Duration) *MemoryStore {
+	if idleTTL <= 0 {
+		idleTTL = 10 * time.Minute
+	}
+	return &MemoryStore{buckets: make(map[string]*bucket), idleTTL: idleTTL}
+}
+
+// Take 实现 Store 接口
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (bool, time.Duration) {
+	if limit.Burst <= 0 || limit.Rate <= 0 {
+		return true, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gc(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	// 按流逝时间补充令牌，不超过桶容量
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait
+}
+
+// gc 清理长时间未使用的桶（调用方需持有锁）
+func (s *MemoryStore) gc(now time.Time) {
+	if now.Sub(s.lastGC) < s.idleTTL {
+		return
+	}
+	for k, b := range s.buckets {
+		if now.Sub(b.last) > s.idleTTL {
+			delete(s.buckets, k)
+		}
+	}
+	s.lastGC = now
+}
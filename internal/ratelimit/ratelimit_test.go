@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// 测试令牌桶（中文说明：突发用尽后拒绝，并按速率恢复）
+func TestMemoryStoreTake(t *testing.T) {
+	s := NewMemoryStore(time.Minute)
+	limit := PerMinute(60, 2) // 每秒补充 1 个
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := s.Take("ip:1", limit, now); !ok {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	ok, wait := s.Take("ip:1", limit, now)
+	if ok {
+		t.Fatalf("third request should be limited")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Fatalf("unexpected retry after %v", wait)
+	}
+
+	// 其它 key 互不影响
+	if ok, _ := s.Take("ip:2", limit, now); !ok {
+		t.Fatalf("different key should be allowed")
+	}
+
+	// 1 秒后恢复一个令牌
+	if ok, _ := s.Take("ip:1", limit, now.Add(time.Second)); !ok {
+		t.Fatalf("token should refill after 1s")
+	}
+}
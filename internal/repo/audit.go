@@ -0,0 +1,198 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// 审计动作与实体类型（中文说明：写入 audit_logs.action / entity_type）
+const (
+	AuditBookingCreate        = "booking.create"
+	AuditBookingConfirm       = "booking.confirm"
+	AuditBookingCancel        = "booking.cancel"
+	AuditBookingReschedule    = "booking.reschedule"
+	AuditBookingRelocate      = "booking.relocate"
+	AuditBookingCheckIn       = "booking.check_in"
+	AuditBookingNoShow        = "booking.no_show"
+	AuditBookingBanCreate     = "booking_ban.create"
+	AuditBookingBanLift       = "booking_ban.lift"
+	AuditFacilityCreate       = "facility.create"
+	AuditFacilityUpdate       = "facility.update"
+	AuditFacilityPhotoCreate  = "facility.photo_create"
+	AuditFacilityPhotoDelete  = "facility.photo_delete"
+	AuditUnitCreate           = "unit.create"
+	AuditUnitUpdate           = "unit.update"
+	AuditUnitCheckInCode      = "unit.check_in_code"
+	AuditPricingRuleCreate    = "pricing_rule.create"
+	AuditPricingRuleUpdate    = "pricing_rule.update"
+	AuditPricingRuleDelete    = "pricing_rule.delete"
+	AuditBlackoutCreate       = "blackout.create"
+	AuditBlackoutUpdate       = "blackout.update"
+	AuditBlackoutDelete       = "blackout.delete"
+	AuditVenueCreate          = "venue.create"
+	AuditVenueUpdate          = "venue.update"
+	AuditVenueAdminAdd        = "venue.admin_add"
+	AuditVenueAdminRemove     = "venue.admin_remove"
+	AuditFacilityTypeCreate   = "facility_type.create"
+	AuditFacilityTypeUpdate   = "facility_type.update"
+	AuditPaymentCreate        = "payment.create"
+	AuditPaymentUpdate        = "payment.update"
+	AuditRefundCreate         = "refund.create"
+	AuditWalletTransaction    = "wallet.transaction"
+	AuditMembershipPlanCreate = "membership_plan.create"
+	AuditMembershipPlanUpdate = "membership_plan.update"
+	AuditMembershipGrant      = "membership.grant"
+	AuditMembershipRevoke     = "membership.revoke"
+	AuditPromoCodeCreate      = "promo_code.create"
+	AuditPromoCodeUpdate      = "promo_code.update"
+	AuditPromoRedeem          = "promo_code.redeem"
+	AuditPromoReverse         = "promo_code.reverse"
+	AuditParticipantAdd       = "participant.add"
+	AuditParticipantRemove    = "participant.remove"
+	AuditNotificationRequeue  = "notification.requeue"
+	AuditWebhookCreate        = "webhook.create"
+	AuditWebhookUpdate        = "webhook.update"
+	AuditWebhookDelete        = "webhook.delete"
+	AuditWebhookReplay        = "webhook.replay"
+
+	EntityBooking           = "booking"
+	EntityFacility          = "facility"
+	EntityUnit              = "resource_unit"
+	EntityPricingRule       = "pricing_rule"
+	EntityBlackout          = "blackout"
+	EntityVenue             = "venue"
+	EntityFacilityType      = "facility_type"
+	EntityPayment           = "payment"
+	EntityRefund            = "refund"
+	EntityWalletTransaction = "wallet_transaction"
+	EntityMembershipPlan    = "membership_plan"
+	EntityMembership        = "membership"
+	EntityPromoCode         = "promo_code"
+	EntityPromoRedemption   = "promo_redemption"
+	EntityParticipant       = "booking_participant"
+	EntityBookingBan        = "booking_ban"
+	EntityNotification      = "notification"
+	EntityWebhookEndpoint   = "webhook_endpoint"
+	EntityWebhookDelivery   = "webhook_delivery"
+)
+
+type actorKey struct{}
+
+// WithActor 在 context 中记录操作人（中文说明：repo 写操作据此填充 audit_logs.actor_user_id）
+func WithActor(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// ActorFromContext 读取操作人
+func ActorFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(actorKey{}).(string)
+	return v
+}
+
+// AuditLog 审计日志实体
+type AuditLog struct {
+	ID          int64           `json:"ID"`
+	ActorUserID *string         `json:"ActorUserID,omitempty"`
+	Action      string          `json:"Action"`
+	EntityType  string          `json:"EntityType"`
+	EntityID    *int64          `json:"EntityID,omitempty"`
+	Payload     json.RawMessage `json:"Payload,omitempty"`
+	CreatedAt   time.Time       `json:"CreatedAt"`
+}
+
+type auditLogDB struct {
+	ID          int64           `json:"id"`
+	ActorUserID *string         `json:"actor_user_id"`
+	Action      string          `json:"action"`
+	EntityType  string          `json:"entity_type"`
+	EntityID    *int64          `json:"entity_id"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func (a *auditLogDB) toAPI() AuditLog {
+	return AuditLog{
+		ID:          a.ID,
+		ActorUserID: a.ActorUserID,
+		Action:      a.Action,
+		EntityType:  a.EntityType,
+		EntityID:    a.EntityID,
+		Payload:     a.Payload,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+// AuditFilter 审计查询条件（中文说明：零值字段表示不过滤）
+type AuditFilter struct {
+	EntityType  string
+	EntityID    int64
+	ActorUserID string
+	From        time.Time
+	To          time.Time
+	Limit       int
+}
+
+// audit 写入一条审计日志（中文说明：变更已生效，审计失败仅记录告警，不影响业务结果）
+func (d *DB) audit(ctx context.Context, action, entityType string, entityID int64, before, after interface{}) {
+	payload := map[string]interface{}{
+		"action":      action,
+		"entity_type": entityType,
+		"payload": map[string]interface{}{
+			"before": before,
+			"after":  after,
+		},
+	}
+	if entityID > 0 {
+		payload["entity_id"] = entityID
+	}
+	if actor := ActorFromContext(ctx); actor != "" {
+		payload["actor_user_id"] = actor
+	}
+	var out []interface{}
+	if err := d.Client.DB.From("audit_logs").Insert(payload).Execute(&out); err != nil {
+		slog.Warn("audit log write failed", "action", action, "entity_id", entityID, "err", err)
+	}
+}
+
+// ListAuditLogs 按实体、操作人与时间范围查询审计日志（按时间倒序；时间范围转为 UTC 格式化，带时区偏移的参数不会因 "+" 被误解析）
+func (d *DB) ListAuditLogs(ctx context.Context, f AuditFilter) ([]AuditLog, error) {
+	// 审计日志跨场馆，仅平台管理员可查询
+	if !ScopeFromContext(ctx).All {
+		return nil, ErrOutOfScope
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	q := d.Client.DB.From("audit_logs").
+		Select("*").
+		OrderBy("created_at", "desc").
+		Limit(limit)
+	if f.EntityType != "" {
+		q.Eq("entity_type", f.EntityType)
+	}
+	if f.EntityID > 0 {
+		q.Eq("entity_id", strconv.FormatInt(f.EntityID, 10))
+	}
+	if f.ActorUserID != "" {
+		q.Eq("actor_user_id", f.ActorUserID)
+	}
+	if !f.From.IsZero() {
+		q.Gte("created_at", f.From.UTC().Format(time.RFC3339))
+	}
+	if !f.To.IsZero() {
+		q.Lt("created_at", f.To.UTC().Format(time.RFC3339))
+	}
+	var out []auditLogDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	res := make([]AuditLog, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
@@ -0,0 +1,380 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+)
+
+// RecurrenceWeekly 每周重复的封场（如每周一上午维护）
+const RecurrenceWeekly = "weekly"
+
+// recurringHorizon 未设置截止时间的重复封场，计算受影响预约时向后展开的范围
+const recurringHorizon = 180 * 24 * time.Hour
+
+// BlackoutRequest 封场请求
+type BlackoutRequest struct {
+	FacilityID      *int64 `json:"facility_id,omitempty"`
+	ResourceUnitID  *int64 `json:"resource_unit_id,omitempty"`
+	StartTime       string `json:"start_time"` // ISO8601 for insert
+	EndTime         string `json:"end_time"`   // ISO8601 for insert
+	Reason          string `json:"reason"`
+	Recurrence      string `json:"recurrence,omitempty"`       // "" 或 "weekly"
+	RecurrenceUntil string `json:"recurrence_until,omitempty"` // ISO8601，可选
+}
+
+// Validate 校验并解析封场请求（中文说明：在拼接 tstzrange 字面量之前校验时间格式与范围）
+func (r BlackoutRequest) Validate() (start, end time.Time, until *time.Time, err error) {
+	if (r.FacilityID == nil) == (r.ResourceUnitID == nil) {
+		return start, end, nil, errors.New("exactly one of facility_id or resource_unit_id required")
+	}
+	start, err = time.Parse(time.RFC3339, r.StartTime)
+	if err != nil {
+		return start, end, nil, errors.New("invalid start_time")
+	}
+	end, err = time.Parse(time.RFC3339, r.EndTime)
+	if err != nil {
+		return start, end, nil, errors.New("invalid end_time")
+	}
+	if !start.Before(end) {
+		return start, end, nil, errors.New("start_time must be before end_time")
+	}
+	switch r.Recurrence {
+	case "":
+		if r.RecurrenceUntil != "" {
+			return start, end, nil, errors.New("recurrence_until requires recurrence")
+		}
+	case RecurrenceWeekly:
+		if end.Sub(start) > 7*24*time.Hour {
+			return start, end, nil, errors.New("weekly blackout cannot be longer than a week")
+		}
+		if r.RecurrenceUntil != "" {
+			u, err := time.Parse(time.RFC3339, r.RecurrenceUntil)
+			if err != nil {
+				return start, end, nil, errors.New("invalid recurrence_until")
+			}
+			if !u.After(start) {
+				return start, end, nil, errors.New("recurrence_until must be after start_time")
+			}
+			until = &u
+		}
+	default:
+		return start, end, nil, errors.New("unsupported recurrence")
+	}
+	return start.UTC(), end.UTC(), until, nil
+}
+
+// Blackout 封场实体（中文说明：重复封场的 StartTime/EndTime 为首次发生的时间段）
+type Blackout struct {
+	ID              int64      `json:"id,omitempty"`
+	FacilityID      *int64     `json:"facility_id,omitempty"`
+	ResourceUnitID  *int64     `json:"resource_unit_id,omitempty"`
+	StartTime       time.Time  `json:"start_time"`
+	EndTime         time.Time  `json:"end_time"`
+	Reason          string     `json:"reason,omitempty"`
+	Recurrence      string     `json:"recurrence,omitempty"`
+	RecurrenceUntil *time.Time `json:"recurrence_until,omitempty"`
+}
+
+type blackoutDB struct {
+	ID              int64      `json:"id"`
+	FacilityID      *int64     `json:"facility_id"`
+	ResourceUnitID  *int64     `json:"resource_unit_id"`
+	StartTime       time.Time  `json:"start_time"`
+	EndTime         time.Time  `json:"end_time"`
+	Reason          *string    `json:"reason"`
+	Recurrence      *string    `json:"recurrence"`
+	RecurrenceUntil *time.Time `json:"recurrence_until"`
+	// 嵌入查询：用于租户过滤
+	Facility *venueRef `json:"facilities,omitempty"`
+	Unit     *struct {
+		Facility *venueRef `json:"facilities"`
+	} `json:"resource_units,omitempty"`
+}
+
+// venueID 封场所属场馆（中文说明：设施级取设施，单元级取单元所在设施）
+func (b *blackoutDB) venueID() int64 {
+	if b.Facility != nil {
+		return b.Facility.VenueID
+	}
+	if b.Unit != nil && b.Unit.Facility != nil {
+		return b.Unit.Facility.VenueID
+	}
+	return 0
+}
+
+// blackoutSelect 封场查询列（含所属场馆）
+const blackoutSelect = "*,facilities(venue_id),resource_units(facilities(venue_id))"
+
+func (b *blackoutDB) toAPI() Blackout {
+	res := Blackout{
+		ID:              b.ID,
+		FacilityID:      b.FacilityID,
+		ResourceUnitID:  b.ResourceUnitID,
+		StartTime:       b.StartTime,
+		EndTime:         b.EndTime,
+		RecurrenceUntil: b.RecurrenceUntil,
+	}
+	if b.Reason != nil {
+		res.Reason = *b.Reason
+	}
+	if b.Recurrence != nil {
+		res.Recurrence = *b.Recurrence
+	}
+	return res
+}
+
+// Occurrences 展开封场在 [from, to) 内的各次发生（中文说明：一次性封场最多返回自身）
+func (b Blackout) Occurrences(from, to time.Time) []Blackout {
+	var res []Blackout
+	if b.Recurrence != RecurrenceWeekly {
+		if b.StartTime.Before(to) && b.EndTime.After(from) {
+			res = append(res, b)
+		}
+		return res
+	}
+	week := 7 * 24 * time.Hour
+	dur := b.EndTime.Sub(b.StartTime)
+	// 跳过 from 之前已结束的周次
+	k := 0
+	if from.After(b.EndTime) {
+		k = int(from.Sub(b.EndTime) / week)
+	}
+	for ; ; k++ {
+		s := b.StartTime.Add(time.Duration(k) * week)
+		if !s.Before(to) {
+			break
+		}
+		if b.RecurrenceUntil != nil && !s.Before(*b.RecurrenceUntil) {
+			break
+		}
+		occ := b
+		occ.StartTime = s
+		occ.EndTime = s.Add(dur)
+		if occ.EndTime.After(from) {
+			res = append(res, occ)
+		}
+	}
+	return res
+}
+
+// horizon 封场影响的最晚时间（中文说明：无截止时间的重复封场按 recurringHorizon 截断）
+func (b Blackout) horizon() time.Time {
+	if b.Recurrence != RecurrenceWeekly {
+		return b.EndTime
+	}
+	if b.RecurrenceUntil != nil {
+		return b.RecurrenceUntil.Add(b.EndTime.Sub(b.StartTime))
+	}
+	return b.StartTime.Add(recurringHorizon)
+}
+
+func blackoutPayload(req BlackoutRequest, start, end time.Time, until *time.Time) map[string]interface{} {
+	payload := map[string]interface{}{
+		"reason":           req.Reason,
+		"facility_id":      req.FacilityID,
+		"resource_unit_id": req.ResourceUnitID,
+		// Construct PostgREST range literal
+		"time_range":       fmt.Sprintf("[%s,%s)", start.Format(time.RFC3339), end.Format(time.RFC3339)),
+		"recurrence":       nil,
+		"recurrence_until": nil,
+	}
+	if req.Recurrence != "" {
+		payload["recurrence"] = req.Recurrence
+	}
+	if until != nil {
+		payload["recurrence_until"] = until.Format(time.RFC3339)
+	}
+	return payload
+}
+
+// CreateBlackout 创建封场
+func (d *DB) CreateBlackout(ctx context.Context, req BlackoutRequest) (*Blackout, error) {
+	start, end, until, err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkBlackoutTarget(ctx, req); err != nil {
+		return nil, err
+	}
+	var out []blackoutDB
+	if err := d.Client.DB.From("blackouts").Insert(blackoutPayload(req, start, end, until)).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create blackout")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditBlackoutCreate, EntityBlackout, res.ID, nil, res)
+	d.publish(ctx, events.BlackoutCreated, res, nil, blackoutTarget(res))
+	return &res, nil
+}
+
+// checkBlackoutTarget 校验封场目标（设施或单元）存在且属于当前租户范围
+func (d *DB) checkBlackoutTarget(ctx context.Context, req BlackoutRequest) error {
+	if req.FacilityID != nil {
+		_, err := d.GetFacilityByID(ctx, *req.FacilityID)
+		return err
+	}
+	if req.ResourceUnitID != nil {
+		_, err := d.GetResourceUnitByID(ctx, *req.ResourceUnitID)
+		return err
+	}
+	return nil
+}
+
+// GetBlackoutByID 查询单个封场
+func (d *DB) GetBlackoutByID(ctx context.Context, id int64) (*Blackout, error) {
+	var out []blackoutDB
+	err := d.Client.DB.From("blackouts").
+		Select(blackoutSelect).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("blackout not found")
+	}
+	if !ScopeFromContext(ctx).Allows(out[0].venueID()) {
+		return nil, ErrOutOfScope
+	}
+	res := out[0].toAPI()
+	return &res, nil
+}
+
+// UpdateBlackout 更新封场（整体替换）
+func (d *DB) UpdateBlackout(ctx context.Context, id int64, req BlackoutRequest) (*Blackout, error) {
+	start, end, until, err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+	before, err := d.GetBlackoutByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkBlackoutTarget(ctx, req); err != nil {
+		return nil, err
+	}
+	var out []blackoutDB
+	err = d.Client.DB.From("blackouts").
+		Update(blackoutPayload(req, start, end, until)).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("blackout not found")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditBlackoutUpdate, EntityBlackout, id, before, res)
+	d.publish(ctx, events.BlackoutUpdated, res, *before, blackoutTarget(*before), blackoutTarget(res))
+	return &res, nil
+}
+
+// DeleteBlackout 删除封场
+func (d *DB) DeleteBlackout(ctx context.Context, id int64) error {
+	before, err := d.GetBlackoutByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	var out []interface{}
+	if err := d.Client.DB.From("blackouts").Delete().Eq("id", fmt.Sprintf("%d", id)).Execute(&out); err != nil {
+		return err
+	}
+	d.audit(ctx, AuditBlackoutDelete, EntityBlackout, id, before, nil)
+	d.publish(ctx, events.BlackoutDeleted, nil, *before, blackoutTarget(*before))
+	return nil
+}
+
+// BlackoutFilter 封场列表查询条件（中文说明：零值字段表示不过滤；时间范围内按发生次展开判断）
+type BlackoutFilter struct {
+	FacilityID     int64
+	ResourceUnitID int64
+	From           time.Time
+	To             time.Time
+}
+
+// ListBlackouts 查询封场列表
+func (d *DB) ListBlackouts(ctx context.Context, f BlackoutFilter) ([]Blackout, error) {
+	q := d.Client.DB.From("blackouts").
+		Select(blackoutSelect).
+		OrderBy("start_time", "asc")
+	if f.FacilityID > 0 {
+		q.Eq("facility_id", fmt.Sprintf("%d", f.FacilityID))
+	}
+	if f.ResourceUnitID > 0 {
+		q.Eq("resource_unit_id", fmt.Sprintf("%d", f.ResourceUnitID))
+	}
+	if !f.To.IsZero() {
+		q.Lt("start_time", f.To.UTC().Format(time.RFC3339))
+	}
+	var out []blackoutDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	scope := ScopeFromContext(ctx)
+	res := make([]Blackout, 0, len(out))
+	for _, v := range out {
+		if !scope.Allows(v.venueID()) {
+			continue
+		}
+		b := v.toAPI()
+		if !f.From.IsZero() && !b.horizon().After(f.From) {
+			continue
+		}
+		res = append(res, b)
+	}
+	return res, nil
+}
+
+// ListBlackoutsForUnitOrFacilityBetween 查询封场（单元或设施级别）
+// 中文说明：重复封场按周展开，仅返回与 [start, end) 相交的发生次
+func (d *DB) ListBlackoutsForUnitOrFacilityBetween(ctx context.Context, facilityID int64, unitID int64, start, end time.Time) ([]Blackout, error) {
+	var all []Blackout
+	for _, f := range []BlackoutFilter{
+		{ResourceUnitID: unitID, From: start, To: end},
+		{FacilityID: facilityID, From: start, To: end},
+	} {
+		list, err := d.ListBlackouts(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range list {
+			all = append(all, b.Occurrences(start, end)...)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].StartTime.Before(all[j].StartTime) })
+	return all, nil
+}
+
+// ListBlackoutAffectedBookings 查询与封场（含全部重复发生次）重叠的已确认预约
+func (d *DB) ListBlackoutAffectedBookings(ctx context.Context, b Blackout) ([]Booking, error) {
+	from, to := b.StartTime, b.horizon()
+	var facilityID, unitID int64
+	if b.ResourceUnitID != nil {
+		unitID = *b.ResourceUnitID
+	} else if b.FacilityID != nil {
+		facilityID = *b.FacilityID
+	}
+	list, err := d.ListConfirmedBookings(ctx, facilityID, unitID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	occurrences := b.Occurrences(from, to)
+	res := make([]Booking, 0, len(list))
+	for _, bk := range list {
+		for _, o := range occurrences {
+			if bk.StartTime.Before(o.EndTime) && bk.EndTime.After(o.StartTime) {
+				res = append(res, bk)
+				break
+			}
+		}
+	}
+	return res, nil
+}
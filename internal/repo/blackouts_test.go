@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试重复封场展开（中文说明：每周一次，截止时间之后不再发生）
+func TestBlackoutOccurrences(t *testing.T) {
+	first := time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC) // 周一
+	until := first.Add(14 * 24 * time.Hour)
+	b := Blackout{
+		StartTime:       first,
+		EndTime:         first.Add(2 * time.Hour),
+		Recurrence:      RecurrenceWeekly,
+		RecurrenceUntil: &until,
+	}
+
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	occ := b.Occurrences(day, day.Add(24*time.Hour))
+	if len(occ) != 1 || !occ[0].StartTime.Equal(first.Add(7*24*time.Hour)) {
+		t.Fatalf("expected one occurrence on 2025-03-10, got %+v", occ)
+	}
+
+	// 截止时间当天及之后不再发生
+	day = time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)
+	if occ := b.Occurrences(day, day.Add(24*time.Hour)); len(occ) != 0 {
+		t.Fatalf("expected no occurrence after until, got %+v", occ)
+	}
+
+	// 非周一无发生
+	day = time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)
+	if occ := b.Occurrences(day, day.Add(24*time.Hour)); len(occ) != 0 {
+		t.Fatalf("expected no occurrence on tuesday, got %+v", occ)
+	}
+
+	if occ := b.Occurrences(first, b.horizon()); len(occ) != 2 {
+		t.Fatalf("expected 2 occurrences in total, got %d", len(occ))
+	}
+}
+
+// 测试封场查询的时间参数按 UTC 格式化（中文说明：时区偏移中的 "+" 在查询串中会被当作空格）
+func TestListBlackoutsFormatsToInUTC(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query().Get("start_time")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+	d, err := NewDB(srv.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	to := time.Date(2026, 5, 4, 22, 0, 0, 0, time.FixedZone("UTC+8", 8*3600))
+	if _, err := d.ListBlackouts(context.Background(), BlackoutFilter{To: to}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2026-05-04T14:00:00Z"; !strings.HasPrefix(got, "lt.") || !strings.Contains(got, want) {
+		t.Fatalf("expected start_time lt %s, got %q", want, got)
+	}
+}
@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 预约变更类型（中文说明：由数据库触发器写入，见 024_booking_events.sql）
+const (
+	BookingEventCreated  = "booking.created"
+	BookingEventDeleted  = "booking.deleted"
+	BookingEventSnapshot = "booking.snapshot" // 启用变更流前已有的预约，changes 为整行
+)
+
+// BookingEvent 预约变更记录（中文说明：Changes 为新增或快照时的整行、修改时变化的列，删除时为空）
+type BookingEvent struct {
+	Seq       int64           `json:"Seq"`
+	BookingID int64           `json:"BookingID"`
+	VenueID   *int64          `json:"VenueID,omitempty"`
+	EventType string          `json:"EventType"`
+	Changes   json.RawMessage `json:"Changes,omitempty"`
+	CreatedAt time.Time       `json:"CreatedAt"`
+}
+
+type bookingEventDB struct {
+	Seq       int64           `json:"seq"`
+	BookingID int64           `json:"booking_id"`
+	VenueID   *int64          `json:"venue_id"`
+	EventType string          `json:"event_type"`
+	Changes   json.RawMessage `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (e *bookingEventDB) toAPI() BookingEvent {
+	res := BookingEvent{
+		Seq:       e.Seq,
+		BookingID: e.BookingID,
+		VenueID:   e.VenueID,
+		EventType: e.EventType,
+		CreatedAt: e.CreatedAt,
+	}
+	if len(e.Changes) > 0 && string(e.Changes) != "null" {
+		res.Changes = e.Changes
+	}
+	return res
+}
+
+// BookingEventFilter 变更流查询条件（中文说明：按 seq 升序返回 After 之后的记录）
+type BookingEventFilter struct {
+	After     int64
+	BookingID int64
+	Limit     int
+}
+
+// ListBookingEvents 按序号读取预约变更（中文说明：场馆管理员只能读取所管辖场馆的记录）
+func (d *DB) ListBookingEvents(ctx context.Context, f BookingEventFilter) ([]BookingEvent, error) {
+	limit := f.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	q := d.Client.DB.From("booking_events").
+		Select("*").
+		OrderBy("seq", "asc").
+		Limit(limit)
+	applyScope(ctx, &q.FilterRequestBuilder, "venue_id")
+	q.Gt("seq", fmt.Sprintf("%d", f.After))
+	if f.BookingID > 0 {
+		q.Eq("booking_id", fmt.Sprintf("%d", f.BookingID))
+	}
+	var out []bookingEventDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	res := make([]BookingEvent, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// ReplayBookingEvents 按序号依次合并变更，重建预约状态（中文说明：删除的预约不在结果中；
+// 修改记录之前没有新增或快照记录时返回错误，说明变更流不完整）
+func ReplayBookingEvents(list []BookingEvent) (map[int64]Booking, error) {
+	rows := map[int64]map[string]json.RawMessage{}
+	var last int64
+	for _, e := range list {
+		if e.Seq <= last {
+			return nil, fmt.Errorf("booking event %d out of order", e.Seq)
+		}
+		last = e.Seq
+		var changes map[string]json.RawMessage
+		if len(e.Changes) > 0 {
+			if err := json.Unmarshal(e.Changes, &changes); err != nil {
+				return nil, fmt.Errorf("booking event %d: %w", e.Seq, err)
+			}
+		}
+		switch e.EventType {
+		case BookingEventDeleted:
+			delete(rows, e.BookingID)
+		case BookingEventCreated, BookingEventSnapshot:
+			rows[e.BookingID] = changes
+		default:
+			row, ok := rows[e.BookingID]
+			if !ok {
+				return nil, fmt.Errorf("booking event %d: booking %d has no created event", e.Seq, e.BookingID)
+			}
+			for k, v := range changes {
+				row[k] = v
+			}
+		}
+	}
+
+	res := make(map[int64]Booking, len(rows))
+	for id, row := range rows {
+		raw, err := json.Marshal(row)
+		if err != nil {
+			return nil, err
+		}
+		var b bookingDB
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return nil, fmt.Errorf("booking %d: %w", id, err)
+		}
+		res[id] = b.toAPI()
+	}
+	return res, nil
+}
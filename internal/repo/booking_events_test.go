@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// 测试按变更流重建预约：新增后依次合并变化的列，删除后移除，缺少新增记录或乱序时报错
+func TestReplayBookingEvents(t *testing.T) {
+	ev := func(seq, id int64, typ, changes string) BookingEvent {
+		e := BookingEvent{Seq: seq, BookingID: id, EventType: typ}
+		if changes != "" {
+			e.Changes = json.RawMessage(changes)
+		}
+		return e
+	}
+	list := []BookingEvent{
+		ev(1, 7, BookingEventSnapshot, `{"id":7,"resource_unit_id":1,"user_id":"u1","status":"confirmed","price":20,"start_time":"2026-05-04T08:00:00+00:00","end_time":"2026-05-04T09:00:00+00:00"}`),
+		ev(2, 8, BookingEventCreated, `{"id":8,"resource_unit_id":2,"user_id":"u2","status":"pending","price":30,"start_time":"2026-05-04T10:00:00+00:00","end_time":"2026-05-04T11:00:00+00:00","cancel_reason":null}`),
+		ev(3, 8, "booking.confirmed", `{"status":"confirmed"}`),
+		ev(4, 8, "booking.rescheduled", `{"resource_unit_id":3,"start_time":"2026-05-04T12:00:00+00:00","end_time":"2026-05-04T13:00:00+00:00"}`),
+		ev(5, 8, "booking.checked_in", `{"checked_in_at":"2026-05-04T11:55:00+00:00","checked_in_count":4}`),
+		ev(6, 9, BookingEventCreated, `{"id":9,"resource_unit_id":1,"user_id":"u3","status":"confirmed"}`),
+		ev(7, 9, "booking.cancelled", `{"status":"cancelled","cancel_reason":"rain"}`),
+		ev(8, 7, BookingEventDeleted, ""),
+	}
+	got, err := ReplayBookingEvents(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 bookings, got %+v", got)
+	}
+	if _, ok := got[7]; ok {
+		t.Fatal("expected deleted booking removed")
+	}
+	b := got[8]
+	start := time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+	if b.Status != "confirmed" || b.ResourceUnitID != 3 || !b.StartTime.Equal(start) || b.Price != 30 || b.UserID != "u2" {
+		t.Fatalf("unexpected rebuilt booking %+v", b)
+	}
+	if b.CheckedInAt == nil || b.CheckedInCount == nil || *b.CheckedInCount != 4 {
+		t.Fatalf("expected check-in merged, got %+v", b)
+	}
+	if got[9].Status != "cancelled" || got[9].CancelReason != "rain" {
+		t.Fatalf("unexpected cancelled booking %+v", got[9])
+	}
+
+	if _, err := ReplayBookingEvents([]BookingEvent{ev(1, 8, "booking.confirmed", `{"status":"confirmed"}`)}); err == nil {
+		t.Fatal("expected error without created event")
+	}
+	if _, err := ReplayBookingEvents([]BookingEvent{list[1], list[0]}); err == nil {
+		t.Fatal("expected error for out of order events")
+	}
+}
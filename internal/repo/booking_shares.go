@@ -0,0 +1,201 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 分摊份额状态
+const (
+	SharePending  = "pending"
+	SharePaid     = "paid"
+	ShareCovered  = "covered"  // 发起人已补足
+	ShareReleased = "released" // 截止未付，随预约释放
+)
+
+// BookingShare 分摊支付份额（中文说明：受邀人以 UserID 或 Email 标识；Token 为支付链接凭证，仅返回给发起人、受邀人本人与管理员）
+type BookingShare struct {
+	ID          int64      `json:"ID"`
+	BookingID   int64      `json:"BookingID"`
+	UserID      string     `json:"UserID,omitempty"`
+	Email       string     `json:"Email,omitempty"`
+	IsOrganizer bool       `json:"IsOrganizer"`
+	Amount      float64    `json:"Amount"`
+	Status      string     `json:"Status"`
+	Token       string     `json:"Token,omitempty"`
+	Deadline    time.Time  `json:"Deadline"`
+	PaidAt      *time.Time `json:"PaidAt,omitempty"`
+	CreatedAt   time.Time  `json:"CreatedAt"`
+}
+
+type bookingShareDB struct {
+	ID          int64      `json:"id"`
+	BookingID   int64      `json:"booking_id"`
+	UserID      *string    `json:"user_id"`
+	Email       *string    `json:"email"`
+	IsOrganizer bool       `json:"is_organizer"`
+	Amount      float64    `json:"amount"`
+	Status      string     `json:"status"`
+	Token       string     `json:"token"`
+	Deadline    time.Time  `json:"deadline"`
+	PaidAt      *time.Time `json:"paid_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (s *bookingShareDB) toAPI() BookingShare {
+	return BookingShare{
+		ID:          s.ID,
+		BookingID:   s.BookingID,
+		UserID:      deref(s.UserID),
+		Email:       deref(s.Email),
+		IsOrganizer: s.IsOrganizer,
+		Amount:      s.Amount,
+		Status:      s.Status,
+		Token:       s.Token,
+		Deadline:    s.Deadline,
+		PaidAt:      s.PaidAt,
+		CreatedAt:   s.CreatedAt,
+	}
+}
+
+func sharesToAPI(out []bookingShareDB) []BookingShare {
+	res := make([]BookingShare, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res
+}
+
+// CreateBookingShares 批量写入预约的分摊份额
+func (d *DB) CreateBookingShares(ctx context.Context, shares []BookingShare) ([]BookingShare, error) {
+	rows := make([]map[string]interface{}, len(shares))
+	for i, s := range shares {
+		row := map[string]interface{}{
+			"booking_id":   s.BookingID,
+			"is_organizer": s.IsOrganizer,
+			"amount":       roundCents(s.Amount),
+			"status":       SharePending,
+			"token":        s.Token,
+			"deadline":     s.Deadline.UTC().Format(time.RFC3339),
+			"user_id":      nil,
+			"email":        nil,
+		}
+		if s.UserID != "" {
+			row["user_id"] = s.UserID
+		}
+		if s.Email != "" {
+			row["email"] = s.Email
+		}
+		rows[i] = row
+	}
+	var out []bookingShareDB
+	if err := d.Client.DB.From("booking_shares").Insert(rows).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) != len(shares) {
+		return nil, errors.New("failed to create booking shares")
+	}
+	return sharesToAPI(out), nil
+}
+
+// ListBookingShares 查询预约的分摊份额（发起人在前）
+func (d *DB) ListBookingShares(ctx context.Context, bookingID int64) ([]BookingShare, error) {
+	var out []bookingShareDB
+	err := d.Client.DB.From("booking_shares").
+		Select("*").
+		OrderBy("id", "asc").
+		Eq("booking_id", fmt.Sprintf("%d", bookingID)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	return sharesToAPI(out), nil
+}
+
+func (d *DB) getBookingShare(column, value string) (*BookingShare, error) {
+	var out []bookingShareDB
+	err := d.Client.DB.From("booking_shares").
+		Select("*").
+		Eq(column, value).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("share not found")
+	}
+	s := out[0].toAPI()
+	return &s, nil
+}
+
+// GetBookingShare 按 ID 查询份额
+func (d *DB) GetBookingShare(ctx context.Context, id int64) (*BookingShare, error) {
+	return d.getBookingShare("id", fmt.Sprintf("%d", id))
+}
+
+// GetBookingShareByToken 按支付链接凭证查询份额
+func (d *DB) GetBookingShareByToken(ctx context.Context, token string) (*BookingShare, error) {
+	if token == "" {
+		return nil, errors.New("share not found")
+	}
+	return d.getBookingShare("token", token)
+}
+
+// TransitionBookingShares 条件更新预约份额状态（中文说明：shareID>0 时只更新该份额；仅当前状态为 from 的份额被更新，返回实际更新的份额）
+func (d *DB) TransitionBookingShares(ctx context.Context, bookingID, shareID int64, from, to string) ([]BookingShare, error) {
+	payload := map[string]interface{}{"status": to}
+	if to == SharePaid || to == ShareCovered {
+		payload["paid_at"] = time.Now().UTC().Format(time.RFC3339)
+	}
+	q := d.Client.DB.From("booking_shares").
+		Update(payload).
+		Eq("booking_id", fmt.Sprintf("%d", bookingID)).
+		Eq("status", from)
+	if shareID > 0 {
+		q.Eq("id", fmt.Sprintf("%d", shareID))
+	}
+	var out []bookingShareDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	return sharesToAPI(out), nil
+}
+
+// ListOverdueShareBookings 查询存在逾期未付份额的预约 ID
+func (d *DB) ListOverdueShareBookings(ctx context.Context, now time.Time) ([]int64, error) {
+	var out []bookingShareDB
+	err := d.Client.DB.From("booking_shares").
+		Select("booking_id").
+		Eq("status", SharePending).
+		Lt("deadline", now.UTC().Format(time.RFC3339)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[int64]bool{}
+	var ids []int64
+	for _, s := range out {
+		if !seen[s.BookingID] {
+			seen[s.BookingID] = true
+			ids = append(ids, s.BookingID)
+		}
+	}
+	return ids, nil
+}
+
+// ListSharesForUser 查询邀请给已注册用户的份额（按时间倒序，不含发起人自己的份额）
+func (d *DB) ListSharesForUser(ctx context.Context, userID string) ([]BookingShare, error) {
+	var out []bookingShareDB
+	err := d.Client.DB.From("booking_shares").
+		Select("*").
+		OrderBy("id", "desc").
+		Eq("user_id", userID).
+		Eq("is_organizer", "false").
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	return sharesToAPI(out), nil
+}
@@ -0,0 +1,58 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// BusEvent 跨实例转发的事件（中文说明：Event 为 events.Event 的 JSON）
+type BusEvent struct {
+	Seq    int64           `json:"seq"`
+	Origin string          `json:"origin"`
+	Event  json.RawMessage `json:"event"`
+}
+
+// AppendBusEvent 写入一条跨实例事件
+func (d *DB) AppendBusEvent(ctx context.Context, origin string, event json.RawMessage) error {
+	var out []BusEvent
+	return d.Client.DB.From("bus_events").
+		Insert(map[string]interface{}{"origin": origin, "event": event}).
+		Execute(&out)
+}
+
+// ListBusEventsAfter 按序号读取 seq 之后的事件
+func (d *DB) ListBusEventsAfter(ctx context.Context, seq int64, limit int) ([]BusEvent, error) {
+	var out []BusEvent
+	err := d.Client.DB.From("bus_events").
+		Select("seq,origin,event").
+		OrderBy("seq", "asc").
+		Limit(limit).
+		Gt("seq", fmt.Sprintf("%d", seq)).
+		Execute(&out)
+	return out, err
+}
+
+// LatestBusEventSeq 当前最大序号（没有事件时为 0），实例启动时从此处开始轮询
+func (d *DB) LatestBusEventSeq(ctx context.Context) (int64, error) {
+	var out []BusEvent
+	err := d.Client.DB.From("bus_events").
+		Select("seq").
+		OrderBy("seq", "desc").
+		Limit(1).
+		Execute(&out)
+	if err != nil || len(out) == 0 {
+		return 0, err
+	}
+	return out[0].Seq, nil
+}
+
+// PruneBusEvents 删除 before 之前写入的事件
+func (d *DB) PruneBusEvents(ctx context.Context, before time.Time) error {
+	var out []BusEvent
+	return d.Client.DB.From("bus_events").
+		Delete().
+		Lt("created_at", before.UTC().Format(time.RFC3339)).
+		Execute(&out)
+}
@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CalendarFeed 日历订阅（中文说明：FacilityID 为空表示个人预约订阅，否则为设施占用时段订阅）
+type CalendarFeed struct {
+	ID         int64     `json:"ID"`
+	Token      string    `json:"Token"`
+	UserID     string    `json:"UserID"`
+	FacilityID *int64    `json:"FacilityID,omitempty"`
+	CreatedAt  time.Time `json:"CreatedAt"`
+}
+
+type calendarFeedDB struct {
+	ID         int64     `json:"id"`
+	Token      string    `json:"token"`
+	UserID     string    `json:"user_id"`
+	FacilityID *int64    `json:"facility_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (f *calendarFeedDB) toAPI() CalendarFeed {
+	return CalendarFeed{ID: f.ID, Token: f.Token, UserID: f.UserID, FacilityID: f.FacilityID, CreatedAt: f.CreatedAt}
+}
+
+// GetCalendarFeed 查询用户有效的订阅（中文说明：facilityID 为 nil 时查询个人订阅；不存在返回 nil）
+func (d *DB) GetCalendarFeed(ctx context.Context, userID string, facilityID *int64) (*CalendarFeed, error) {
+	q := d.Client.DB.From("calendar_feeds").
+		Select("*").
+		Eq("user_id", userID).
+		IsNull("revoked_at")
+	if facilityID == nil {
+		q.IsNull("facility_id")
+	} else {
+		q.Eq("facility_id", fmt.Sprintf("%d", *facilityID))
+	}
+	var out []calendarFeedDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	f := out[0].toAPI()
+	return &f, nil
+}
+
+// GetCalendarFeedByToken 按订阅凭证查询有效订阅
+func (d *DB) GetCalendarFeedByToken(ctx context.Context, token string) (*CalendarFeed, error) {
+	if token == "" {
+		return nil, errors.New("feed not found")
+	}
+	var out []calendarFeedDB
+	err := d.Client.DB.From("calendar_feeds").
+		Select("*").
+		Eq("token", token).
+		IsNull("revoked_at").
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("feed not found")
+	}
+	f := out[0].toAPI()
+	return &f, nil
+}
+
+// CreateCalendarFeed 新建订阅（中文说明：调用方先撤销同一用户同一范围的旧订阅）
+func (d *DB) CreateCalendarFeed(ctx context.Context, userID string, facilityID *int64, token string) (*CalendarFeed, error) {
+	payload := map[string]interface{}{
+		"token":       token,
+		"user_id":     userID,
+		"facility_id": facilityID,
+	}
+	var out []calendarFeedDB
+	if err := d.Client.DB.From("calendar_feeds").Insert(payload).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create calendar feed")
+	}
+	f := out[0].toAPI()
+	return &f, nil
+}
+
+// RevokeCalendarFeeds 撤销用户在该范围内的有效订阅（旧订阅地址随即失效）
+func (d *DB) RevokeCalendarFeeds(ctx context.Context, userID string, facilityID *int64) error {
+	q := d.Client.DB.From("calendar_feeds").
+		Update(map[string]interface{}{"revoked_at": time.Now().UTC().Format(time.RFC3339)}).
+		Eq("user_id", userID).
+		IsNull("revoked_at")
+	if facilityID == nil {
+		q.IsNull("facility_id")
+	} else {
+		q.Eq("facility_id", fmt.Sprintf("%d", *facilityID))
+	}
+	var out []calendarFeedDB
+	return q.Execute(&out)
+}
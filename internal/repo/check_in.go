@@ -0,0 +1,279 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+)
+
+// ErrNotCheckInable 预约不是待签到状态（未确认、已签到或已标记爽约）
+var ErrNotCheckInable = errors.New("booking cannot be checked in")
+
+// CheckInBooking 记录签到（中文说明：仅已确认且未签到的预约可签到，操作人取自 context；并发签到只有一次生效）
+func (d *DB) CheckInBooking(ctx context.Context, id int64, headcount int, at time.Time) (*Booking, error) {
+	before, err := d.GetBookingByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]interface{}{
+		"checked_in_at":    at.UTC().Format(time.RFC3339),
+		"checked_in_count": headcount,
+	}
+	if actor := ActorFromContext(ctx); actor != "" {
+		payload["checked_in_by"] = actor
+	}
+	var out []bookingDB
+	err = d.Client.DB.From("bookings").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Eq("status", "confirmed").
+		IsNull("checked_in_at").
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrNotCheckInable
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditBookingCheckIn, EntityBooking, id, before, res)
+	d.publish(ctx, events.BookingCheckedIn, res, *before, bookingTarget(res))
+	return &res, nil
+}
+
+// ListCheckInDue 查询开场时间在 [after, before) 内仍未签到的已确认预约
+func (d *DB) ListCheckInDue(ctx context.Context, after, before time.Time) ([]Booking, error) {
+	var out []bookingDB
+	err := d.Client.DB.From("bookings").
+		Select("*").
+		OrderBy("start_time", "asc").
+		Eq("status", "confirmed").
+		IsNull("checked_in_at").
+		Gte("start_time", after.UTC().Format(time.RFC3339)).
+		Lt("start_time", before.UTC().Format(time.RFC3339)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Booking, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// MarkNoShow 将未签到的已确认预约标记为爽约（中文说明：标记后不再占用时段；期间已签到则不标记，返回 false）
+func (d *DB) MarkNoShow(ctx context.Context, id int64, at time.Time) (bool, error) {
+	before, err := d.GetBookingByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	var out []bookingDB
+	err = d.Client.DB.From("bookings").
+		Update(map[string]interface{}{
+			"status":     "no_show",
+			"no_show_at": at.UTC().Format(time.RFC3339),
+		}).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Eq("status", "confirmed").
+		IsNull("checked_in_at").
+		Execute(&out)
+	if err != nil {
+		return false, err
+	}
+	if len(out) == 0 {
+		return false, nil
+	}
+	after := out[0].toAPI()
+	d.audit(ctx, AuditBookingNoShow, EntityBooking, id, before, after)
+	d.publish(ctx, events.BookingNoShow, after, *before, bookingTarget(after))
+	return true, nil
+}
+
+// CountNoShows 统计用户自 since 起的爽约次数
+func (d *DB) CountNoShows(ctx context.Context, userID string, since time.Time) (int, error) {
+	var out []bookingDB
+	err := d.Client.DB.From("bookings").
+		Select("id").
+		Eq("user_id", userID).
+		Eq("status", "no_show").
+		Gte("no_show_at", since.UTC().Format(time.RFC3339)).
+		Execute(&out)
+	if err != nil {
+		return 0, err
+	}
+	return len(out), nil
+}
+
+// GetUnitCheckInCode 查询单元签到码（受租户范围约束）
+func (d *DB) GetUnitCheckInCode(ctx context.Context, unitID int64) (string, error) {
+	q := d.Client.DB.From("resource_units").
+		Select("check_in_code,facilities!inner(venue_id)")
+	applyScope(ctx, &q.FilterRequestBuilder, "facilities.venue_id")
+	var out []struct {
+		CheckInCode string `json:"check_in_code"`
+	}
+	if err := q.Eq("id", fmt.Sprintf("%d", unitID)).Execute(&out); err != nil {
+		return "", err
+	}
+	if len(out) == 0 {
+		return "", errors.New("unit not found")
+	}
+	return out[0].CheckInCode, nil
+}
+
+// SetUnitCheckInCode 更换单元签到码（旧二维码随即失效）
+func (d *DB) SetUnitCheckInCode(ctx context.Context, unitID int64, code string) error {
+	if _, err := d.GetResourceUnitByID(ctx, unitID); err != nil {
+		return err
+	}
+	var out []resourceUnitDB
+	err := d.Client.DB.From("resource_units").
+		Update(map[string]interface{}{"check_in_code": code}).
+		Eq("id", fmt.Sprintf("%d", unitID)).
+		Execute(&out)
+	if err != nil {
+		return err
+	}
+	if len(out) == 0 {
+		return errors.New("unit not found")
+	}
+	d.audit(ctx, AuditUnitCheckInCode, EntityUnit, unitID, nil, nil)
+	return nil
+}
+
+// BookingBan 预约禁令（中文说明：有效期内用户不能创建新预约；LiftedAt 非空表示已被管理员解除）
+type BookingBan struct {
+	ID          int64      `json:"ID"`
+	UserID      string     `json:"UserID"`
+	Reason      string     `json:"Reason"`
+	NoShowCount int        `json:"NoShowCount"`
+	StartsAt    time.Time  `json:"StartsAt"`
+	EndsAt      time.Time  `json:"EndsAt"`
+	LiftedAt    *time.Time `json:"LiftedAt,omitempty"`
+	LiftedBy    string     `json:"LiftedBy,omitempty"`
+	CreatedAt   time.Time  `json:"CreatedAt"`
+}
+
+type bookingBanDB struct {
+	ID          int64      `json:"id"`
+	UserID      string     `json:"user_id"`
+	Reason      string     `json:"reason"`
+	NoShowCount int        `json:"no_show_count"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	LiftedAt    *time.Time `json:"lifted_at"`
+	LiftedBy    *string    `json:"lifted_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (b *bookingBanDB) toAPI() BookingBan {
+	return BookingBan{
+		ID:          b.ID,
+		UserID:      b.UserID,
+		Reason:      b.Reason,
+		NoShowCount: b.NoShowCount,
+		StartsAt:    b.StartsAt,
+		EndsAt:      b.EndsAt,
+		LiftedAt:    b.LiftedAt,
+		LiftedBy:    deref(b.LiftedBy),
+		CreatedAt:   b.CreatedAt,
+	}
+}
+
+// ActiveAt 禁令在 at 时刻是否生效
+func (b BookingBan) ActiveAt(at time.Time) bool {
+	return b.LiftedAt == nil && !at.Before(b.StartsAt) && at.Before(b.EndsAt)
+}
+
+func bansToAPI(out []bookingBanDB) []BookingBan {
+	res := make([]BookingBan, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res
+}
+
+// ListBookingBans 查询禁令（中文说明：userID 为空时返回全部，按创建时间倒序）
+func (d *DB) ListBookingBans(ctx context.Context, userID string) ([]BookingBan, error) {
+	q := d.Client.DB.From("booking_bans").
+		Select("*").
+		OrderBy("id", "desc")
+	if userID != "" {
+		q.Eq("user_id", userID)
+	}
+	var out []bookingBanDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	return bansToAPI(out), nil
+}
+
+// GetActiveBan 查询用户在 at 时刻生效的禁令（中文说明：多条时取结束最晚的一条；无生效禁令返回 nil）
+func (d *DB) GetActiveBan(ctx context.Context, userID string, at time.Time) (*BookingBan, error) {
+	ts := at.UTC().Format(time.RFC3339)
+	var out []bookingBanDB
+	err := d.Client.DB.From("booking_bans").
+		Select("*").
+		OrderBy("ends_at", "desc").
+		Eq("user_id", userID).
+		IsNull("lifted_at").
+		Lte("starts_at", ts).
+		Gt("ends_at", ts).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	b := out[0].toAPI()
+	return &b, nil
+}
+
+// CreateBookingBan 新增禁令
+func (d *DB) CreateBookingBan(ctx context.Context, b BookingBan) (*BookingBan, error) {
+	payload := map[string]interface{}{
+		"user_id":       b.UserID,
+		"reason":        b.Reason,
+		"no_show_count": b.NoShowCount,
+		"starts_at":     b.StartsAt.UTC().Format(time.RFC3339),
+		"ends_at":       b.EndsAt.UTC().Format(time.RFC3339),
+	}
+	var out []bookingBanDB
+	if err := d.Client.DB.From("booking_bans").Insert(payload).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create booking ban")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditBookingBanCreate, EntityBookingBan, res.ID, nil, res)
+	return &res, nil
+}
+
+// LiftBookingBan 解除禁令（中文说明：已解除的禁令再次解除返回错误）
+func (d *DB) LiftBookingBan(ctx context.Context, id int64, at time.Time) (*BookingBan, error) {
+	payload := map[string]interface{}{"lifted_at": at.UTC().Format(time.RFC3339)}
+	if actor := ActorFromContext(ctx); actor != "" {
+		payload["lifted_by"] = actor
+	}
+	var out []bookingBanDB
+	err := d.Client.DB.From("booking_bans").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		IsNull("lifted_at").
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("ban not found or already lifted")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditBookingBanLift, EntityBookingBan, id, nil, res)
+	return &res, nil
+}
@@ -6,60 +6,108 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Juny09/sport_backend/internal/events"
 	"github.com/nedpals/supabase-go"
 )
 
 // DB 封装 Supabase 客户端（中文说明：使用 HTTP API 替代直连数据库）
 type DB struct {
-	Client *supabase.Client
+	Client    *supabase.Client
+	service   *supabase.Client // service_role 客户端，仅用于读取 auth.users 等受限函数
+	publisher events.Publisher
 }
 
 // Internal structs for mapping snake_case DB fields
 type facilityDB struct {
-	ID       int64  `json:"id"`
-	Name     string `json:"name"`
-	Type     string `json:"type"`
-	IsActive bool   `json:"is_active"`
+	ID           int64    `json:"id"`
+	VenueID      int64    `json:"venue_id"`
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	IsActive     bool     `json:"is_active"`
+	Description  *string  `json:"description"`
+	Address      *string  `json:"address"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+	ContactPhone *string  `json:"contact_phone"`
+	ContactEmail *string  `json:"contact_email"`
+	Amenities    []string `json:"amenities"`
 }
 
 func (f *facilityDB) toAPI() Facility {
 	return Facility{
-		ID:       f.ID,
-		Name:     f.Name,
-		Type:     f.Type,
-		IsActive: f.IsActive,
+		ID:           f.ID,
+		VenueID:      f.VenueID,
+		Name:         f.Name,
+		Type:         f.Type,
+		IsActive:     f.IsActive,
+		Description:  deref(f.Description),
+		Address:      deref(f.Address),
+		Latitude:     f.Latitude,
+		Longitude:    f.Longitude,
+		ContactPhone: deref(f.ContactPhone),
+		ContactEmail: deref(f.ContactEmail),
+		Amenities:    f.Amenities,
 	}
 }
 
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 type resourceUnitDB struct {
-	ID         int64  `json:"id"`
-	FacilityID int64  `json:"facility_id"`
-	Label      string `json:"label"`
-	IsActive   bool   `json:"is_active"`
+	ID              int64     `json:"id"`
+	FacilityID      int64     `json:"facility_id"`
+	Label           string    `json:"label"`
+	IsActive        bool      `json:"is_active"`
+	SortOrder       int       `json:"sort_order"`
+	SurfaceType     *string   `json:"surface_type"`
+	IsIndoor        *bool     `json:"is_indoor"`
+	HasLighting     *bool     `json:"has_lighting"`
+	MaxParticipants *int      `json:"max_participants"`
+	Facility        *venueRef `json:"facilities,omitempty"` // 嵌入查询时返回所属场馆
 }
 
 func (r *resourceUnitDB) toAPI() ResourceUnit {
-	return ResourceUnit{
-		ID:         r.ID,
-		FacilityID: r.FacilityID,
-		Label:      r.Label,
-		IsActive:   r.IsActive,
+	res := ResourceUnit{
+		ID:              r.ID,
+		FacilityID:      r.FacilityID,
+		Label:           r.Label,
+		IsActive:        r.IsActive,
+		SortOrder:       r.SortOrder,
+		IsIndoor:        r.IsIndoor,
+		HasLighting:     r.HasLighting,
+		MaxParticipants: r.MaxParticipants,
+	}
+	if r.SurfaceType != nil {
+		res.SurfaceType = *r.SurfaceType
 	}
+	if r.Facility != nil {
+		res.VenueID = r.Facility.VenueID
+	}
+	return res
 }
 
 type bookingDB struct {
-	StartTime      time.Time `json:"start_time"`
-	EndTime        time.Time `json:"end_time"`
-	ID             int64     `json:"id"`
-	ResourceUnitID int64     `json:"resource_unit_id"`
-	UserID         string    `json:"user_id"`
-	Status         string    `json:"status"`
-	Price          float64   `json:"price"`
-	Notes          string    `json:"notes,omitempty"`
+	StartTime      time.Time  `json:"start_time"`
+	EndTime        time.Time  `json:"end_time"`
+	ID             int64      `json:"id"`
+	ResourceUnitID int64      `json:"resource_unit_id"`
+	UserID         string     `json:"user_id"`
+	Status         string     `json:"status"`
+	Price          float64    `json:"price"`
+	Notes          string     `json:"notes,omitempty"`
+	CancelReason   *string    `json:"cancel_reason,omitempty"`
+	CheckedInAt    *time.Time `json:"checked_in_at,omitempty"`
+	CheckedInBy    *string    `json:"checked_in_by,omitempty"`
+	CheckedInCount *int       `json:"checked_in_count,omitempty"`
+	NoShowAt       *time.Time `json:"no_show_at,omitempty"`
 }
 
 func (b *bookingDB) toAPI() Booking {
-	return Booking{
+	res := Booking{
 		StartTime:      b.StartTime,
 		EndTime:        b.EndTime,
 		ID:             b.ID,
@@ -68,7 +116,15 @@ func (b *bookingDB) toAPI() Booking {
 		Status:         b.Status,
 		Price:          b.Price,
 		Notes:          b.Notes,
+		CheckedInAt:    b.CheckedInAt,
+		CheckedInBy:    deref(b.CheckedInBy),
+		CheckedInCount: b.CheckedInCount,
+		NoShowAt:       b.NoShowAt,
+	}
+	if b.CancelReason != nil {
+		res.CancelReason = *b.CancelReason
 	}
+	return res
 }
 
 // NewDB 创建 Supabase 客户端连接
@@ -81,6 +137,16 @@ func NewDB(url, key string) (*DB, error) {
 	return &DB{Client: client}, nil
 }
 
+// ErrServiceRoleRequired 未配置 service_role key，无法调用受限数据库函数
+var ErrServiceRoleRequired = errors.New("supabase service role key not configured")
+
+// SetServiceRoleKey 配置 service_role 客户端（中文说明：受限函数已撤销 anon 与 authenticated 的执行权限）
+func (d *DB) SetServiceRoleKey(url, key string) {
+	if url != "" && key != "" {
+		d.service = supabase.CreateClient(url, key)
+	}
+}
+
 // Close 关闭连接（Supabase HTTP 客户端无需显式关闭，保留接口兼容性）
 func (d *DB) Close() {
 	// No-op
@@ -88,34 +154,56 @@ func (d *DB) Close() {
 
 // Facility 设施实体
 type Facility struct {
-	ID       int64  `json:"ID"`
-	Name     string `json:"Name"`
-	Type     string `json:"Type"`
-	IsActive bool   `json:"IsActive"`
+	ID           int64    `json:"ID"`
+	VenueID      int64    `json:"VenueID"`
+	Name         string   `json:"Name"`
+	Type         string   `json:"Type"`
+	IsActive     bool     `json:"IsActive"`
+	Description  string   `json:"Description,omitempty"`
+	Address      string   `json:"Address,omitempty"`
+	Latitude     *float64 `json:"Latitude,omitempty"`
+	Longitude    *float64 `json:"Longitude,omitempty"`
+	ContactPhone string   `json:"ContactPhone,omitempty"`
+	ContactEmail string   `json:"ContactEmail,omitempty"`
+	Amenities    []string `json:"Amenities,omitempty"`  // 如 parking、shower、locker、wifi
+	DistanceKm   *float64 `json:"DistanceKm,omitempty"` // 按位置查询时填充
 }
 
 // ResourceUnit 单元实体
 type ResourceUnit struct {
-	ID         int64  `json:"ID"`
-	FacilityID int64  `json:"FacilityID"`
-	Label      string `json:"Label"`
-	IsActive   bool   `json:"IsActive"`
+	ID              int64  `json:"ID"`
+	FacilityID      int64  `json:"FacilityID"`
+	Label           string `json:"Label"`
+	IsActive        bool   `json:"IsActive"`
+	SortOrder       int    `json:"SortOrder"`
+	SurfaceType     string `json:"SurfaceType,omitempty"` // 如 wood、pvc、hard、clay
+	IsIndoor        *bool  `json:"IsIndoor,omitempty"`
+	HasLighting     *bool  `json:"HasLighting,omitempty"`
+	MaxParticipants *int   `json:"MaxParticipants,omitempty"` // 人数上限（含预订人），为空表示不限
+	VenueID         int64  `json:"VenueID,omitempty"`         // 查询时嵌入所属设施的场馆，未嵌入时为 0
 }
 
 // Booking 预约实体
 type Booking struct {
-	StartTime      time.Time `json:"StartTime"`
-	EndTime        time.Time `json:"EndTime"`
-	ID             int64     `json:"ID"`
-	ResourceUnitID int64     `json:"ResourceUnitID"`
-	UserID         string    `json:"UserID"`
-	Status         string    `json:"Status"`
-	Price          float64   `json:"Price"`
-	Notes          string    `json:"Notes,omitempty"`
+	StartTime      time.Time  `json:"StartTime"`
+	EndTime        time.Time  `json:"EndTime"`
+	ID             int64      `json:"ID"`
+	ResourceUnitID int64      `json:"ResourceUnitID"`
+	UserID         string     `json:"UserID"`
+	Status         string     `json:"Status"`
+	Price          float64    `json:"Price"`
+	Notes          string     `json:"Notes,omitempty"`
+	CancelReason   string     `json:"CancelReason,omitempty"`
+	CheckedInAt    *time.Time `json:"CheckedInAt,omitempty"`
+	CheckedInBy    string     `json:"CheckedInBy,omitempty"`
+	CheckedInCount *int       `json:"CheckedInCount,omitempty"` // 签到时的到场人数
+	NoShowAt       *time.Time `json:"NoShowAt,omitempty"`
 }
 
 // PricingRule 价格规则
 type PricingRule struct {
+	ID           int64   `json:"ID,omitempty"`
+	VenueID      int64   `json:"VenueID"`
 	FacilityType string  `json:"FacilityType"`
 	DayOfWeek    int     `json:"DayOfWeek"`
 	StartHour    int     `json:"StartHour"`
@@ -123,28 +211,35 @@ type PricingRule struct {
 	PricePerHour float64 `json:"PricePerHour"`
 }
 
-// BlackoutRequest 封场请求
-type BlackoutRequest struct {
-	FacilityID     *int64 `json:"facility_id,omitempty"`
-	ResourceUnitID *int64 `json:"resource_unit_id,omitempty"`
-	StartTime      string `json:"start_time"` // ISO8601 for insert
-	EndTime        string `json:"end_time"`   // ISO8601 for insert
-	Reason         string `json:"reason"`
-}
-
-// Blackout 简化结构用于可用性计算
-type Blackout struct {
-	StartTime time.Time `json:"start_time"`
-	EndTime   time.Time `json:"end_time"`
+// FacilityFilter 设施列表筛选（中文说明：零值字段表示不过滤）
+type FacilityFilter struct {
+	VenueID int64
+	Type    string
+	Amenity string
 }
 
 // ListFacilities 查询设施列表
 func (d *DB) ListFacilities(ctx context.Context) ([]Facility, error) {
-	var out []facilityDB
-	err := d.Client.DB.From("facilities").
+	return d.ListFacilitiesFiltered(ctx, FacilityFilter{})
+}
+
+// ListFacilitiesFiltered 按类型与设施标签查询设施列表
+func (d *DB) ListFacilitiesFiltered(ctx context.Context, f FacilityFilter) ([]Facility, error) {
+	q := d.Client.DB.From("facilities").
 		Select("*").
-		Execute(&out)
-	if err != nil {
+		OrderBy("id", "asc")
+	applyScope(ctx, &q.FilterRequestBuilder, "venue_id")
+	if f.VenueID > 0 {
+		q.Eq("venue_id", fmt.Sprintf("%d", f.VenueID))
+	}
+	if f.Type != "" {
+		q.Eq("type", f.Type)
+	}
+	if f.Amenity != "" {
+		q.Cs("amenities", []string{f.Amenity})
+	}
+	var out []facilityDB
+	if err := q.Execute(&out); err != nil {
 		return nil, err
 	}
 
@@ -169,17 +264,20 @@ func (d *DB) GetFacilityByID(ctx context.Context, id int64) (*Facility, error) {
 		return nil, errors.New("facility not found")
 	}
 	f := out[0].toAPI()
+	if !ScopeFromContext(ctx).Allows(f.VenueID) {
+		return nil, ErrOutOfScope
+	}
 	return &f, nil
 }
 
 // ListUnitsByFacility 查询设施下的单元列表
 func (d *DB) ListUnitsByFacility(ctx context.Context, facilityID int64) ([]ResourceUnit, error) {
+	q := d.Client.DB.From("resource_units").
+		Select("*,facilities!inner(venue_id)").
+		OrderBy("sort_order", "asc")
+	applyScope(ctx, &q.FilterRequestBuilder, "facilities.venue_id")
 	var out []resourceUnitDB
-	err := d.Client.DB.From("resource_units").
-		Select("*").
-		Eq("facility_id", fmt.Sprintf("%d", facilityID)).
-		Execute(&out)
-	if err != nil {
+	if err := q.Eq("facility_id", fmt.Sprintf("%d", facilityID)).Execute(&out); err != nil {
 		return nil, err
 	}
 
@@ -190,12 +288,30 @@ func (d *DB) ListUnitsByFacility(ctx context.Context, facilityID int64) ([]Resou
 	return res, nil
 }
 
+// GetResourceUnitByID 查询单个单元
+func (d *DB) GetResourceUnitByID(ctx context.Context, id int64) (*ResourceUnit, error) {
+	q := d.Client.DB.From("resource_units").
+		Select("*,facilities!inner(venue_id)")
+	applyScope(ctx, &q.FilterRequestBuilder, "facilities.venue_id")
+	var out []resourceUnitDB
+	if err := q.Eq("id", fmt.Sprintf("%d", id)).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("unit not found")
+	}
+	u := out[0].toAPI()
+	return &u, nil
+}
+
 // ListUnitsByFacilityType 根据设施类型查询激活单元
 func (d *DB) ListUnitsByFacilityType(ctx context.Context, facilityType string) ([]ResourceUnit, error) {
+	q := d.Client.DB.From("resource_units").
+		Select("*,facilities!inner(type,venue_id)").
+		OrderBy("sort_order", "asc")
+	applyScope(ctx, &q.FilterRequestBuilder, "facilities.venue_id")
 	var out []resourceUnitDB
-	err := d.Client.DB.From("resource_units").
-		Select("*,facilities!inner(type)").
-		Eq("facilities.type", facilityType).
+	err := q.Eq("facilities.type", facilityType).
 		Eq("is_active", "true").
 		Eq("facilities.is_active", "true").
 		Execute(&out)
@@ -210,18 +326,21 @@ func (d *DB) ListUnitsByFacilityType(ctx context.Context, facilityType string) (
 	return res, nil
 }
 
-// ListBookingsForUnitOnDay 查询某单元在指定日期的所有预约
+// ListBookingsForUnitOnDay 查询某单元在指定日期（UTC）占用时段的预约（中文说明：不含已取消与爽约释放的预约）
 func (d *DB) ListBookingsForUnitOnDay(ctx context.Context, unitID int64, day time.Time) ([]Booking, error) {
 	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
-	end := start.Add(24 * time.Hour)
+	return d.ListBookingsForUnitBetween(ctx, unitID, start, start.Add(24*time.Hour))
+}
 
+// ListBookingsForUnitBetween 查询某单元在 [start, end) 内占用时段的预约（中文说明：不含已取消与爽约释放的预约）
+func (d *DB) ListBookingsForUnitBetween(ctx context.Context, unitID int64, start, end time.Time) ([]Booking, error) {
 	var out []bookingDB
 	err := d.Client.DB.From("bookings").
 		Select("start_time,end_time,id,resource_unit_id,user_id,status,price").
 		Eq("resource_unit_id", fmt.Sprintf("%d", unitID)).
-		Neq("status", "cancelled").
-		Lt("start_time", end.Format(time.RFC3339)).
-		Gt("end_time", start.Format(time.RFC3339)).
+		In("status", []string{"pending", "confirmed"}).
+		Lt("start_time", end.UTC().Format(time.RFC3339)).
+		Gt("end_time", start.UTC().Format(time.RFC3339)).
 		Execute(&out)
 	if err != nil {
 		return nil, err
@@ -234,14 +353,52 @@ func (d *DB) ListBookingsForUnitOnDay(ctx context.Context, unitID int64, day tim
 	return res, nil
 }
 
-// ListBlackoutsForUnitOrFacilityOnDay 查询封场（单元或设施级别）
-func (d *DB) ListBlackoutsForUnitOrFacilityOnDay(ctx context.Context, facilityID int64, unitID int64, day time.Time) ([]Blackout, error) {
-	// TODO: 实现 Blackout 查询
-	return []Blackout{}, nil
+// ListBookingsForUnitsBetween 查询一组单元在 [from, to) 内的预约（中文说明：不含已取消，含爽约；按开始时间排序，运营看板使用）
+func (d *DB) ListBookingsForUnitsBetween(ctx context.Context, unitIDs []int64, from, to time.Time) ([]Booking, error) {
+	if len(unitIDs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(unitIDs))
+	for i, id := range unitIDs {
+		ids[i] = fmt.Sprintf("%d", id)
+	}
+	var out []bookingDB
+	err := d.Client.DB.From("bookings").
+		Select("*").
+		OrderBy("start_time", "asc").
+		In("resource_unit_id", ids).
+		In("status", []string{"pending", "confirmed", "no_show"}).
+		Lt("start_time", to.UTC().Format(time.RFC3339)).
+		Gt("end_time", from.UTC().Format(time.RFC3339)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Booking, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// NewBooking 创建预约参数（中文说明：Status 为空时为 confirmed；需要支付时为 pending 占位）
+type NewBooking struct {
+	ResourceUnitID int64
+	UserID         string
+	Start          time.Time
+	End            time.Time
+	Notes          string
+	Status         string
+	Price          float64
 }
 
 // CreateBooking 创建预约
-func (d *DB) CreateBooking(ctx context.Context, unitID int64, userID string, start, end time.Time, notes string) (*Booking, error) {
+func (d *DB) CreateBooking(ctx context.Context, nb NewBooking) (*Booking, error) {
+	unitID, userID, start, end := nb.ResourceUnitID, nb.UserID, nb.Start, nb.End
+	status := nb.Status
+	if status == "" {
+		status = "confirmed"
+	}
 	if !start.Before(end) {
 		return nil, errors.New("invalid time range: start must be before end")
 	}
@@ -262,8 +419,9 @@ func (d *DB) CreateBooking(ctx context.Context, unitID int64, userID string, sta
 		"user_id":          userID,
 		"start_time":       start.Format(time.RFC3339),
 		"end_time":         end.Format(time.RFC3339),
-		"notes":            notes,
-		"status":           "confirmed", // Default status
+		"notes":            nb.Notes,
+		"status":           status,
+		"price":            nb.Price,
 	}
 
 	var out []bookingDB
@@ -277,17 +435,18 @@ func (d *DB) CreateBooking(ctx context.Context, unitID int64, userID string, sta
 		return nil, errors.New("failed to create booking")
 	}
 	res := out[0].toAPI()
+	d.audit(ctx, AuditBookingCreate, EntityBooking, res.ID, nil, res)
+	d.publish(ctx, events.BookingCreated, res, nil, bookingTarget(res))
 	return &res, nil
 }
 
 // GetBookingByID 获取单个预约
 func (d *DB) GetBookingByID(ctx context.Context, id int64) (*Booking, error) {
+	q := d.Client.DB.From("bookings").
+		Select("*,resource_units!inner(facilities!inner(venue_id))")
+	applyScope(ctx, &q.FilterRequestBuilder, "resource_units.facilities.venue_id")
 	var out []bookingDB
-	err := d.Client.DB.From("bookings").
-		Select("*").
-		Eq("id", fmt.Sprintf("%d", id)).
-		Execute(&out)
-	if err != nil {
+	if err := q.Eq("id", fmt.Sprintf("%d", id)).Execute(&out); err != nil {
 		return nil, err
 	}
 	if len(out) == 0 {
@@ -297,7 +456,7 @@ func (d *DB) GetBookingByID(ctx context.Context, id int64) (*Booking, error) {
 	return &res, nil
 }
 
-// ListBookingsByUser 获取用户预约
+// ListBookingsByUser 获取用户预约（中文说明：包含用户作为参与人加入的他人预约）
 func (d *DB) ListBookingsByUser(ctx context.Context, userID string) ([]Booking, error) {
 	var out []bookingDB
 	err := d.Client.DB.From("bookings").
@@ -307,6 +466,21 @@ func (d *DB) ListBookingsByUser(ctx context.Context, userID string) ([]Booking,
 	if err != nil {
 		return nil, err
 	}
+	ids, err := d.listParticipantBookingIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		var joined []bookingDB
+		err := d.Client.DB.From("bookings").
+			Select("*").
+			In("id", ids).
+			Execute(&joined)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, joined...)
+	}
 
 	res := make([]Booking, len(out))
 	for i, v := range out {
@@ -315,76 +489,120 @@ func (d *DB) ListBookingsByUser(ctx context.Context, userID string) ([]Booking,
 	return res, nil
 }
 
-// CancelBooking 取消预约
-func (d *DB) CancelBooking(ctx context.Context, id int64) error {
+// ErrBookingNotActive 预约已不是 pending 或 confirmed（已取消或已判为爽约）
+var ErrBookingNotActive = errors.New("booking is no longer active")
+
+// CancelBooking 取消预约（中文说明：reason 可为空，非空时记录到 cancel_reason；同时撤销优惠码核销；
+// 仅 pending 或 confirmed 的预约会被更新，并发取消时只有一个请求成功，其余返回 ErrBookingNotActive）
+func (d *DB) CancelBooking(ctx context.Context, id int64, reason string) error {
+	before, err := d.GetBookingByID(ctx, id)
+	if err != nil {
+		return err
+	}
 	var out []bookingDB
 	payload := map[string]interface{}{"status": "cancelled"}
-	err := d.Client.DB.From("bookings").
+	if reason != "" {
+		payload["cancel_reason"] = reason
+	}
+	err = d.Client.DB.From("bookings").
 		Update(payload).
 		Eq("id", fmt.Sprintf("%d", id)).
+		In("status", []string{"pending", "confirmed"}).
+		Execute(&out)
+	if err != nil {
+		return err
+	}
+	if len(out) == 0 {
+		return ErrBookingNotActive
+	}
+	after := out[0].toAPI()
+	d.audit(ctx, AuditBookingCancel, EntityBooking, id, before, after)
+	d.publish(ctx, events.BookingCancelled, after, *before, bookingTarget(after))
+	return d.ReversePromoRedemptions(ctx, id)
+}
+
+// ConfirmBooking 支付成功后确认占位预约（中文说明：仅 pending 状态可确认；返回 false 表示状态已变化）
+func (d *DB) ConfirmBooking(ctx context.Context, id int64) (bool, error) {
+	before, err := d.GetBookingByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	var out []bookingDB
+	err = d.Client.DB.From("bookings").
+		Update(map[string]interface{}{"status": "confirmed"}).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Eq("status", "pending").
 		Execute(&out)
-	return err
+	if err != nil {
+		return false, err
+	}
+	if len(out) == 0 {
+		return false, nil
+	}
+	after := out[0].toAPI()
+	d.audit(ctx, AuditBookingConfirm, EntityBooking, id, before, after)
+	d.publish(ctx, events.BookingConfirmed, after, *before, bookingTarget(after))
+	return true, nil
 }
 
 // RescheduleBooking 改签预约
 func (d *DB) RescheduleBooking(ctx context.Context, id int64, start, end time.Time) error {
+	before, err := d.GetBookingByID(ctx, id)
+	if err != nil {
+		return err
+	}
 	payload := map[string]interface{}{
 		"start_time": start.Format(time.RFC3339),
 		"end_time":   end.Format(time.RFC3339),
 	}
 	var out []bookingDB
-	err := d.Client.DB.From("bookings").
+	err = d.Client.DB.From("bookings").
 		Update(payload).
 		Eq("id", fmt.Sprintf("%d", id)).
 		Execute(&out)
-	return err
-}
-
-// CreatePricingRule 创建价格规则
-func (d *DB) CreatePricingRule(ctx context.Context, rule PricingRule) error {
-	// Need to map PricingRule (PascalCase) to snake_case payload manually or use a struct with snake_case tags
-	// Or just use a map
-	payload := map[string]interface{}{
-		"facility_type":  rule.FacilityType,
-		"day_of_week":    rule.DayOfWeek,
-		"start_hour":     rule.StartHour,
-		"end_hour":       rule.EndHour,
-		"price_per_hour": rule.PricePerHour,
+	if err != nil {
+		return err
 	}
-	var out []interface{}
-	err := d.Client.DB.From("pricing_rules").Insert(payload).Execute(&out)
-	return err
+	if len(out) > 0 {
+		after := out[0].toAPI()
+		d.audit(ctx, AuditBookingReschedule, EntityBooking, id, before, after)
+		d.publish(ctx, events.BookingRescheduled, after, *before, bookingTarget(*before), bookingTarget(after))
+	}
+	return nil
 }
 
-// CreateBlackout 创建封场
-func (d *DB) CreateBlackout(ctx context.Context, req BlackoutRequest) error {
-	// ... existing logic uses map, so it's fine ...
-	payload := map[string]interface{}{
-		"reason": req.Reason,
+// MoveBooking 将预约迁移到同设施的另一个单元（时间不变）
+func (d *DB) MoveBooking(ctx context.Context, id int64, unitID int64) (*Booking, error) {
+	before, err := d.GetBookingByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	if req.FacilityID != nil {
-		payload["facility_id"] = *req.FacilityID
+	payload := map[string]interface{}{"resource_unit_id": unitID}
+	var out []bookingDB
+	err = d.Client.DB.From("bookings").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
 	}
-	if req.ResourceUnitID != nil {
-		payload["resource_unit_id"] = *req.ResourceUnitID
+	if len(out) == 0 {
+		return nil, errors.New("booking not found")
 	}
-
-	// Construct PostgREST range literal
-	rangeStr := fmt.Sprintf("[%s,%s)", req.StartTime, req.EndTime)
-	payload["time_range"] = rangeStr
-
-	var out []interface{}
-	err := d.Client.DB.From("blackouts").Insert(payload).Execute(&out)
-	return err
+	res := out[0].toAPI()
+	d.audit(ctx, AuditBookingRelocate, EntityBooking, id, before, res)
+	d.publish(ctx, events.BookingRescheduled, res, *before, bookingTarget(*before), bookingTarget(res))
+	return &res, nil
 }
 
 // ListAdminBookings 管理员查询预约
 func (d *DB) ListAdminBookings(ctx context.Context, facilityType string, start, end time.Time) ([]Booking, error) {
 	// Complex join + filter
+	q := d.Client.DB.From("bookings").
+		Select("*,resource_units!inner(facilities!inner(type,venue_id))")
+	applyScope(ctx, &q.FilterRequestBuilder, "resource_units.facilities.venue_id")
 	var out []bookingDB
-	err := d.Client.DB.From("bookings").
-		Select("*,resource_units!inner(facilities!inner(type))").
-		Eq("resource_units.facilities.type", facilityType).
+	err := q.Eq("resource_units.facilities.type", facilityType).
 		// Overlap check: start < queryEnd AND end > queryStart
 		Lt("start_time", end.Format(time.RFC3339)).
 		Gt("end_time", start.Format(time.RFC3339)).
@@ -401,32 +619,42 @@ func (d *DB) ListAdminBookings(ctx context.Context, facilityType string, start,
 }
 
 // CreateFacility 创建设施
-func (d *DB) CreateFacility(ctx context.Context, name, type_ string) error {
+func (d *DB) CreateFacility(ctx context.Context, venueID int64, name, type_ string) error {
+	if !ScopeFromContext(ctx).Allows(venueID) {
+		return ErrOutOfScope
+	}
 	payload := map[string]interface{}{
+		"venue_id":  venueID,
 		"name":      name,
 		"type":      type_,
 		"is_active": true,
 	}
-	var out []interface{}
-	return d.Client.DB.From("facilities").Insert(payload).Execute(&out)
+	var out []facilityDB
+	if err := d.Client.DB.From("facilities").Insert(payload).Execute(&out); err != nil {
+		return err
+	}
+	if len(out) > 0 {
+		d.audit(ctx, AuditFacilityCreate, EntityFacility, out[0].ID, nil, out[0].toAPI())
+	}
+	return nil
 }
 
 // CreateResourceUnit 创建单元
 func (d *DB) CreateResourceUnit(ctx context.Context, facilityID int64, label string) error {
+	if _, err := d.GetFacilityByID(ctx, facilityID); err != nil {
+		return err
+	}
 	payload := map[string]interface{}{
 		"facility_id": facilityID,
 		"label":       label,
 		"is_active":   true,
 	}
-	var out []interface{}
-	return d.Client.DB.From("resource_units").Insert(payload).Execute(&out)
-}
-
-// UpdateResourceUnit 更新单元状态
-func (d *DB) UpdateResourceUnit(ctx context.Context, id int64, isActive bool) error {
-	payload := map[string]interface{}{
-		"is_active": isActive,
+	var out []resourceUnitDB
+	if err := d.Client.DB.From("resource_units").Insert(payload).Execute(&out); err != nil {
+		return err
+	}
+	if len(out) > 0 {
+		d.audit(ctx, AuditUnitCreate, EntityUnit, out[0].ID, nil, out[0].toAPI())
 	}
-	var out []interface{}
-	return d.Client.DB.From("resource_units").Update(payload).Eq("id", fmt.Sprintf("%d", id)).Execute(&out)
+	return nil
 }
@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+)
+
+// SetPublisher 设置领域事件发布器（中文说明：启动时调用一次；未设置时写操作不发布事件）
+func (d *DB) SetPublisher(p events.Publisher) {
+	d.publisher = p
+}
+
+// publish 写操作成功后发布领域事件（中文说明：与 audit 一样在写入之后调用，不影响写操作结果）
+func (d *DB) publish(ctx context.Context, eventType string, data, previous interface{}, targets ...events.Target) {
+	if d.publisher == nil {
+		return
+	}
+	d.publisher.Publish(ctx, events.Event{
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+		Previous:   previous,
+		Targets:    targets,
+	})
+}
+
+// bookingTarget 预约占用的单元与时段
+func bookingTarget(b Booking) events.Target {
+	return events.Target{UnitID: b.ResourceUnitID, Start: b.StartTime, End: b.EndTime}
+}
+
+// blackoutTarget 封场影响的单元或设施与时段
+func blackoutTarget(b Blackout) events.Target {
+	t := events.Target{Start: b.StartTime, End: b.EndTime, Recurring: b.Recurrence != ""}
+	if b.ResourceUnitID != nil {
+		t.UnitID = *b.ResourceUnitID
+	}
+	if b.FacilityID != nil {
+		t.FacilityID = *b.FacilityID
+	}
+	return t
+}
@@ -0,0 +1,190 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FacilityUpdate 设施部分更新（中文说明：nil 字段保持不变）
+type FacilityUpdate struct {
+	Name         *string   `json:"name,omitempty"`
+	Type         *string   `json:"type,omitempty"`
+	IsActive     *bool     `json:"is_active,omitempty"`
+	Description  *string   `json:"description,omitempty"`
+	Address      *string   `json:"address,omitempty"`
+	Latitude     *float64  `json:"latitude,omitempty"`
+	Longitude    *float64  `json:"longitude,omitempty"`
+	ContactPhone *string   `json:"contact_phone,omitempty"`
+	ContactEmail *string   `json:"contact_email,omitempty"`
+	Amenities    *[]string `json:"amenities,omitempty"`
+}
+
+// Empty 是否没有任何需要更新的字段
+func (u FacilityUpdate) Empty() bool {
+	return u.Name == nil && u.Type == nil && u.IsActive == nil &&
+		u.Description == nil && u.Address == nil && u.Latitude == nil && u.Longitude == nil &&
+		u.ContactPhone == nil && u.ContactEmail == nil && u.Amenities == nil
+}
+
+// Validate 校验坐标范围
+func (u FacilityUpdate) Validate() error {
+	if u.Latitude != nil && (*u.Latitude < -90 || *u.Latitude > 90) {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if u.Longitude != nil && (*u.Longitude < -180 || *u.Longitude > 180) {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// UnitUpdate 单元部分更新（中文说明：nil 字段保持不变）
+type UnitUpdate struct {
+	Label           *string `json:"label,omitempty"`
+	SortOrder       *int    `json:"sort_order,omitempty"`
+	SurfaceType     *string `json:"surface_type,omitempty"`
+	IsIndoor        *bool   `json:"is_indoor,omitempty"`
+	HasLighting     *bool   `json:"has_lighting,omitempty"`
+	IsActive        *bool   `json:"is_active,omitempty"`
+	MaxParticipants *int    `json:"max_participants,omitempty"`
+}
+
+// Empty 是否没有任何需要更新的字段
+func (u UnitUpdate) Empty() bool {
+	return u.Label == nil && u.SortOrder == nil && u.SurfaceType == nil &&
+		u.IsIndoor == nil && u.HasLighting == nil && u.IsActive == nil && u.MaxParticipants == nil
+}
+
+// UpdateFacility 更新设施（重命名、变更类型、启用/停用）
+func (d *DB) UpdateFacility(ctx context.Context, id int64, upd FacilityUpdate) (*Facility, error) {
+	if upd.Empty() {
+		return nil, errors.New("nothing to update")
+	}
+	before, err := d.GetFacilityByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]interface{}{}
+	if upd.Name != nil {
+		payload["name"] = *upd.Name
+	}
+	if upd.Type != nil {
+		payload["type"] = *upd.Type
+	}
+	if upd.IsActive != nil {
+		payload["is_active"] = *upd.IsActive
+	}
+	if upd.Description != nil {
+		payload["description"] = *upd.Description
+	}
+	if upd.Address != nil {
+		payload["address"] = *upd.Address
+	}
+	if upd.Latitude != nil {
+		payload["latitude"] = *upd.Latitude
+	}
+	if upd.Longitude != nil {
+		payload["longitude"] = *upd.Longitude
+	}
+	if upd.ContactPhone != nil {
+		payload["contact_phone"] = *upd.ContactPhone
+	}
+	if upd.ContactEmail != nil {
+		payload["contact_email"] = *upd.ContactEmail
+	}
+	if upd.Amenities != nil {
+		payload["amenities"] = *upd.Amenities
+	}
+	var out []facilityDB
+	err = d.Client.DB.From("facilities").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("facility not found")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditFacilityUpdate, EntityFacility, id, before, res)
+	return &res, nil
+}
+
+// UpdateResourceUnit 更新单元（名称、排序、场地属性、启用/停用）
+func (d *DB) UpdateResourceUnit(ctx context.Context, id int64, upd UnitUpdate) (*ResourceUnit, error) {
+	if upd.Empty() {
+		return nil, errors.New("nothing to update")
+	}
+	before, err := d.GetResourceUnitByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]interface{}{}
+	if upd.Label != nil {
+		payload["label"] = *upd.Label
+	}
+	if upd.SortOrder != nil {
+		payload["sort_order"] = *upd.SortOrder
+	}
+	if upd.SurfaceType != nil {
+		payload["surface_type"] = *upd.SurfaceType
+	}
+	if upd.IsIndoor != nil {
+		payload["is_indoor"] = *upd.IsIndoor
+	}
+	if upd.HasLighting != nil {
+		payload["has_lighting"] = *upd.HasLighting
+	}
+	if upd.IsActive != nil {
+		payload["is_active"] = *upd.IsActive
+	}
+	if upd.MaxParticipants != nil {
+		payload["max_participants"] = *upd.MaxParticipants
+	}
+	var out []resourceUnitDB
+	err = d.Client.DB.From("resource_units").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("unit not found")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditUnitUpdate, EntityUnit, id, before, res)
+	return &res, nil
+}
+
+// ListConfirmedBookings 查询设施或单元在 [from, to) 内的已确认预约
+// 中文说明：facilityID/unitID 为 0 表示不按其过滤；to 为零值表示不设上限；时间统一转为 UTC 格式化，
+// 避免本地时区偏移中的 "+" 在查询串中被当作空格
+func (d *DB) ListConfirmedBookings(ctx context.Context, facilityID, unitID int64, from, to time.Time) ([]Booking, error) {
+	q := d.Client.DB.From("bookings").
+		Select("*,resource_units!inner(facility_id,facilities!inner(venue_id))").
+		OrderBy("start_time", "asc")
+	applyScope(ctx, &q.FilterRequestBuilder, "resource_units.facilities.venue_id")
+	q.Eq("status", "confirmed").
+		Gt("end_time", from.UTC().Format(time.RFC3339))
+	if !to.IsZero() {
+		q.Lt("start_time", to.UTC().Format(time.RFC3339))
+	}
+	if unitID > 0 {
+		q.Eq("resource_unit_id", fmt.Sprintf("%d", unitID))
+	}
+	if facilityID > 0 {
+		q.Eq("resource_units.facility_id", fmt.Sprintf("%d", facilityID))
+	}
+	var out []bookingDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	res := make([]Booking, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FacilityPhoto 设施照片（中文说明：文件本身保存在 storage，数据库仅记录对象 key）
+type FacilityPhoto struct {
+	ID           int64     `json:"ID"`
+	FacilityID   int64     `json:"FacilityID"`
+	StorageKey   string    `json:"StorageKey"`
+	ThumbnailKey string    `json:"ThumbnailKey"`
+	ContentType  string    `json:"ContentType"`
+	SortOrder    int       `json:"SortOrder"`
+	CreatedAt    time.Time `json:"CreatedAt"`
+	URL          string    `json:"URL,omitempty"`          // 由存储层填充
+	ThumbnailURL string    `json:"ThumbnailURL,omitempty"` // 由存储层填充
+}
+
+type facilityPhotoDB struct {
+	ID           int64     `json:"id"`
+	FacilityID   int64     `json:"facility_id"`
+	StorageKey   string    `json:"storage_key"`
+	ThumbnailKey string    `json:"thumbnail_key"`
+	ContentType  string    `json:"content_type"`
+	SortOrder    int       `json:"sort_order"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (p *facilityPhotoDB) toAPI() FacilityPhoto {
+	return FacilityPhoto{
+		ID:           p.ID,
+		FacilityID:   p.FacilityID,
+		StorageKey:   p.StorageKey,
+		ThumbnailKey: p.ThumbnailKey,
+		ContentType:  p.ContentType,
+		SortOrder:    p.SortOrder,
+		CreatedAt:    p.CreatedAt,
+	}
+}
+
+// ListFacilityPhotos 查询设施照片
+func (d *DB) ListFacilityPhotos(ctx context.Context, facilityID int64) ([]FacilityPhoto, error) {
+	var out []facilityPhotoDB
+	err := d.Client.DB.From("facility_photos").
+		Select("*").
+		OrderBy("sort_order", "asc").
+		Eq("facility_id", fmt.Sprintf("%d", facilityID)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]FacilityPhoto, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// GetFacilityPhotoByID 查询单张照片
+func (d *DB) GetFacilityPhotoByID(ctx context.Context, id int64) (*FacilityPhoto, error) {
+	var out []facilityPhotoDB
+	err := d.Client.DB.From("facility_photos").
+		Select("*").
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("photo not found")
+	}
+	res := out[0].toAPI()
+	return &res, nil
+}
+
+// CreateFacilityPhoto 记录设施照片
+func (d *DB) CreateFacilityPhoto(ctx context.Context, p FacilityPhoto) (*FacilityPhoto, error) {
+	payload := map[string]interface{}{
+		"facility_id":   p.FacilityID,
+		"storage_key":   p.StorageKey,
+		"thumbnail_key": p.ThumbnailKey,
+		"content_type":  p.ContentType,
+		"sort_order":    p.SortOrder,
+	}
+	var out []facilityPhotoDB
+	if err := d.Client.DB.From("facility_photos").Insert(payload).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create photo")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditFacilityPhotoCreate, EntityFacility, res.FacilityID, nil, res)
+	return &res, nil
+}
+
+// DeleteFacilityPhoto 删除照片记录
+func (d *DB) DeleteFacilityPhoto(ctx context.Context, id int64) error {
+	before, err := d.GetFacilityPhotoByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	var out []interface{}
+	if err := d.Client.DB.From("facility_photos").Delete().Eq("id", fmt.Sprintf("%d", id)).Execute(&out); err != nil {
+		return err
+	}
+	d.audit(ctx, AuditFacilityPhotoDelete, EntityFacility, before.FacilityID, before, nil)
+	return nil
+}
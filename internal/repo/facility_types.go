@@ -0,0 +1,268 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+)
+
+// FacilityType 设施类型目录项（中文说明：替代数据库 CHECK 列表；新增类型只需插入一行）
+type FacilityType struct {
+	Code          string    `json:"Code"`
+	DisplayName   string    `json:"DisplayName"`
+	DisplayNameZh string    `json:"DisplayNameZh"`
+	Icon          string    `json:"Icon"`
+	SortOrder     int       `json:"SortOrder"`
+	IsActive      bool      `json:"IsActive"`
+	CreatedAt     time.Time `json:"CreatedAt"`
+	// 默认预约策略：场馆未单独配置 reservation_policies 时使用
+	MinDurationMinutes        int `json:"MinDurationMinutes"`
+	MaxDurationMinutes        int `json:"MaxDurationMinutes"`
+	SlotGranularityMinutes    int `json:"SlotGranularityMinutes"`
+	AdvanceBookingDays        int `json:"AdvanceBookingDays"`
+	CancellationCutoffMinutes int `json:"CancellationCutoffMinutes"`
+	// 退款规则：开场前 RefundFullHours 小时以上全额退款，RefundPartialHours 小时以上按 RefundPartialPercent 退款，之后不退
+	RefundFullHours      int `json:"RefundFullHours"`
+	RefundPartialHours   int `json:"RefundPartialHours"`
+	RefundPartialPercent int `json:"RefundPartialPercent"`
+}
+
+type facilityTypeDB struct {
+	Code                      string    `json:"code"`
+	DisplayName               string    `json:"display_name"`
+	DisplayNameZh             *string   `json:"display_name_zh"`
+	Icon                      *string   `json:"icon"`
+	SortOrder                 int       `json:"sort_order"`
+	IsActive                  bool      `json:"is_active"`
+	CreatedAt                 time.Time `json:"created_at"`
+	MinDurationMinutes        int       `json:"min_duration_minutes"`
+	MaxDurationMinutes        int       `json:"max_duration_minutes"`
+	SlotGranularityMinutes    int       `json:"slot_granularity_minutes"`
+	AdvanceBookingDays        int       `json:"advance_booking_days"`
+	CancellationCutoffMinutes int       `json:"cancellation_cutoff_minutes"`
+	RefundFullHours           int       `json:"refund_full_hours"`
+	RefundPartialHours        int       `json:"refund_partial_hours"`
+	RefundPartialPercent      int       `json:"refund_partial_percent"`
+}
+
+func (t *facilityTypeDB) toAPI() FacilityType {
+	return FacilityType{
+		Code:                      t.Code,
+		DisplayName:               t.DisplayName,
+		DisplayNameZh:             deref(t.DisplayNameZh),
+		Icon:                      deref(t.Icon),
+		SortOrder:                 t.SortOrder,
+		IsActive:                  t.IsActive,
+		CreatedAt:                 t.CreatedAt,
+		MinDurationMinutes:        t.MinDurationMinutes,
+		MaxDurationMinutes:        t.MaxDurationMinutes,
+		SlotGranularityMinutes:    t.SlotGranularityMinutes,
+		AdvanceBookingDays:        t.AdvanceBookingDays,
+		CancellationCutoffMinutes: t.CancellationCutoffMinutes,
+		RefundFullHours:           t.RefundFullHours,
+		RefundPartialHours:        t.RefundPartialHours,
+		RefundPartialPercent:      t.RefundPartialPercent,
+	}
+}
+
+// FacilityTypeUpdate 设施类型部分更新（中文说明：nil 字段保持不变；code 不可修改）
+type FacilityTypeUpdate struct {
+	DisplayName               *string `json:"display_name,omitempty"`
+	DisplayNameZh             *string `json:"display_name_zh,omitempty"`
+	Icon                      *string `json:"icon,omitempty"`
+	SortOrder                 *int    `json:"sort_order,omitempty"`
+	IsActive                  *bool   `json:"is_active,omitempty"`
+	MinDurationMinutes        *int    `json:"min_duration_minutes,omitempty"`
+	MaxDurationMinutes        *int    `json:"max_duration_minutes,omitempty"`
+	SlotGranularityMinutes    *int    `json:"slot_granularity_minutes,omitempty"`
+	AdvanceBookingDays        *int    `json:"advance_booking_days,omitempty"`
+	CancellationCutoffMinutes *int    `json:"cancellation_cutoff_minutes,omitempty"`
+	RefundFullHours           *int    `json:"refund_full_hours,omitempty"`
+	RefundPartialHours        *int    `json:"refund_partial_hours,omitempty"`
+	RefundPartialPercent      *int    `json:"refund_partial_percent,omitempty"`
+}
+
+var facilityTypeCode = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// ErrUnknownFacilityType 设施类型不在目录中或已停用
+var ErrUnknownFacilityType = errors.New("unknown facility type")
+
+// Validate 校验编码与默认策略（中文说明：时长为正，最长不小于最短，粒度至少 5 分钟）
+func (t FacilityType) Validate() error {
+	if !facilityTypeCode.MatchString(t.Code) {
+		return errors.New("code must be 2-32 chars of lowercase letters, digits or underscore")
+	}
+	if t.DisplayName == "" {
+		return errors.New("display_name required")
+	}
+	if t.MinDurationMinutes <= 0 || t.MaxDurationMinutes < t.MinDurationMinutes {
+		return errors.New("duration limits must satisfy 0 < min <= max")
+	}
+	if t.SlotGranularityMinutes < 5 {
+		return errors.New("slot_granularity_minutes must be >= 5")
+	}
+	if t.AdvanceBookingDays < 0 || t.CancellationCutoffMinutes < 0 {
+		return errors.New("advance_booking_days and cancellation_cutoff_minutes must be >= 0")
+	}
+	if t.RefundPartialHours < 0 || t.RefundFullHours < t.RefundPartialHours {
+		return errors.New("refund hours must satisfy 0 <= partial <= full")
+	}
+	if t.RefundPartialPercent < 0 || t.RefundPartialPercent > 100 {
+		return errors.New("refund_partial_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// apply 将部分更新合并到当前值（用于整体校验）
+func (u FacilityTypeUpdate) apply(t FacilityType) FacilityType {
+	if u.DisplayName != nil {
+		t.DisplayName = *u.DisplayName
+	}
+	if u.DisplayNameZh != nil {
+		t.DisplayNameZh = *u.DisplayNameZh
+	}
+	if u.Icon != nil {
+		t.Icon = *u.Icon
+	}
+	if u.SortOrder != nil {
+		t.SortOrder = *u.SortOrder
+	}
+	if u.IsActive != nil {
+		t.IsActive = *u.IsActive
+	}
+	if u.MinDurationMinutes != nil {
+		t.MinDurationMinutes = *u.MinDurationMinutes
+	}
+	if u.MaxDurationMinutes != nil {
+		t.MaxDurationMinutes = *u.MaxDurationMinutes
+	}
+	if u.SlotGranularityMinutes != nil {
+		t.SlotGranularityMinutes = *u.SlotGranularityMinutes
+	}
+	if u.AdvanceBookingDays != nil {
+		t.AdvanceBookingDays = *u.AdvanceBookingDays
+	}
+	if u.CancellationCutoffMinutes != nil {
+		t.CancellationCutoffMinutes = *u.CancellationCutoffMinutes
+	}
+	if u.RefundFullHours != nil {
+		t.RefundFullHours = *u.RefundFullHours
+	}
+	if u.RefundPartialHours != nil {
+		t.RefundPartialHours = *u.RefundPartialHours
+	}
+	if u.RefundPartialPercent != nil {
+		t.RefundPartialPercent = *u.RefundPartialPercent
+	}
+	return t
+}
+
+func (t FacilityType) toPayload() map[string]interface{} {
+	return map[string]interface{}{
+		"code":                        t.Code,
+		"display_name":                t.DisplayName,
+		"display_name_zh":             t.DisplayNameZh,
+		"icon":                        t.Icon,
+		"sort_order":                  t.SortOrder,
+		"is_active":                   t.IsActive,
+		"min_duration_minutes":        t.MinDurationMinutes,
+		"max_duration_minutes":        t.MaxDurationMinutes,
+		"slot_granularity_minutes":    t.SlotGranularityMinutes,
+		"advance_booking_days":        t.AdvanceBookingDays,
+		"cancellation_cutoff_minutes": t.CancellationCutoffMinutes,
+		"refund_full_hours":           t.RefundFullHours,
+		"refund_partial_hours":        t.RefundPartialHours,
+		"refund_partial_percent":      t.RefundPartialPercent,
+	}
+}
+
+// ListFacilityTypes 查询设施类型目录（中文说明：includeInactive=false 时仅返回启用的类型）
+func (d *DB) ListFacilityTypes(ctx context.Context, includeInactive bool) ([]FacilityType, error) {
+	q := d.Client.DB.From("facility_types").
+		Select("*").
+		OrderBy("sort_order", "asc")
+	if !includeInactive {
+		q.Eq("is_active", "true")
+	}
+	var out []facilityTypeDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	res := make([]FacilityType, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// GetFacilityType 按编码查询设施类型
+func (d *DB) GetFacilityType(ctx context.Context, code string) (*FacilityType, error) {
+	var out []facilityTypeDB
+	err := d.Client.DB.From("facility_types").
+		Select("*").
+		Eq("code", code).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("facility type not found")
+	}
+	t := out[0].toAPI()
+	return &t, nil
+}
+
+// CheckFacilityType 校验类型存在且启用（中文说明：创建设施、改类型与价格规则均据此校验）
+func (d *DB) CheckFacilityType(ctx context.Context, code string) error {
+	t, err := d.GetFacilityType(ctx, code)
+	if err != nil || !t.IsActive {
+		return ErrUnknownFacilityType
+	}
+	return nil
+}
+
+// CreateFacilityType 新增设施类型
+func (d *DB) CreateFacilityType(ctx context.Context, t FacilityType) (*FacilityType, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	var out []facilityTypeDB
+	if err := d.Client.DB.From("facility_types").Insert(t.toPayload()).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create facility type")
+	}
+	created := out[0].toAPI()
+	d.audit(ctx, AuditFacilityTypeCreate, EntityFacilityType, 0, nil, created)
+	return &created, nil
+}
+
+// UpdateFacilityType 更新设施类型（中文说明：停用后不可再用于新设施，已有设施不受影响）
+func (d *DB) UpdateFacilityType(ctx context.Context, code string, upd FacilityTypeUpdate) (*FacilityType, error) {
+	before, err := d.GetFacilityType(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	merged := upd.apply(*before)
+	if err := merged.Validate(); err != nil {
+		return nil, err
+	}
+	payload := merged.toPayload()
+	delete(payload, "code")
+	var out []facilityTypeDB
+	err = d.Client.DB.From("facility_types").
+		Update(payload).
+		Eq("code", code).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("facility type not found")
+	}
+	updated := out[0].toAPI()
+	d.audit(ctx, AuditFacilityTypeUpdate, EntityFacilityType, 0, before, updated)
+	return &updated, nil
+}
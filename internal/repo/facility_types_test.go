@@ -0,0 +1,37 @@
+package repo
+
+import "testing"
+
+// 测试设施类型校验与部分更新合并（中文说明：编码格式、时长上下限）
+func TestFacilityTypeValidate(t *testing.T) {
+	ft := FacilityType{
+		Code:                   "pickleball",
+		DisplayName:            "Pickleball",
+		MinDurationMinutes:     30,
+		MaxDurationMinutes:     120,
+		SlotGranularityMinutes: 30,
+	}
+	if err := ft.Validate(); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+
+	bad := ft
+	bad.Code = "Pickle Ball"
+	if bad.Validate() == nil {
+		t.Fatalf("expected invalid code")
+	}
+
+	min := 180
+	if (FacilityTypeUpdate{MinDurationMinutes: &min}).apply(ft).Validate() == nil {
+		t.Fatalf("min above max should be rejected")
+	}
+	full, partial := 12, 24
+	if (FacilityTypeUpdate{RefundFullHours: &full, RefundPartialHours: &partial}).apply(ft).Validate() == nil {
+		t.Fatalf("partial refund window longer than full window should be rejected")
+	}
+	name := "匹克球"
+	merged := FacilityTypeUpdate{DisplayNameZh: &name}.apply(ft)
+	if merged.DisplayNameZh != name || merged.Code != ft.Code {
+		t.Fatalf("unexpected merge result %+v", merged)
+	}
+}
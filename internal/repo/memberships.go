@@ -0,0 +1,388 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MembershipPlan 会员方案（中文说明：VenueID 为空表示全平台通用；覆盖项为空时沿用预约策略默认值）
+type MembershipPlan struct {
+	ID                 int64     `json:"ID"`
+	VenueID            *int64    `json:"VenueID,omitempty"`
+	Name               string    `json:"Name"`
+	Description        string    `json:"Description,omitempty"`
+	DiscountPercent    int       `json:"DiscountPercent"`
+	AdvanceBookingDays *int      `json:"AdvanceBookingDays,omitempty"`
+	MaxDurationMinutes *int      `json:"MaxDurationMinutes,omitempty"`
+	DurationDays       int       `json:"DurationDays"`
+	IsActive           bool      `json:"IsActive"`
+	CreatedAt          time.Time `json:"CreatedAt"`
+}
+
+type membershipPlanDB struct {
+	ID                 int64     `json:"id"`
+	VenueID            *int64    `json:"venue_id"`
+	Name               string    `json:"name"`
+	Description        *string   `json:"description"`
+	DiscountPercent    int       `json:"discount_percent"`
+	AdvanceBookingDays *int      `json:"advance_booking_days"`
+	MaxDurationMinutes *int      `json:"max_duration_minutes"`
+	DurationDays       int       `json:"duration_days"`
+	IsActive           bool      `json:"is_active"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+func (p *membershipPlanDB) toAPI() MembershipPlan {
+	return MembershipPlan{
+		ID:                 p.ID,
+		VenueID:            p.VenueID,
+		Name:               p.Name,
+		Description:        deref(p.Description),
+		DiscountPercent:    p.DiscountPercent,
+		AdvanceBookingDays: p.AdvanceBookingDays,
+		MaxDurationMinutes: p.MaxDurationMinutes,
+		DurationDays:       p.DurationDays,
+		IsActive:           p.IsActive,
+		CreatedAt:          p.CreatedAt,
+	}
+}
+
+// MembershipPlanUpdate 会员方案部分更新（中文说明：nil 字段保持不变；所属场馆不可修改）
+type MembershipPlanUpdate struct {
+	Name               *string `json:"name,omitempty"`
+	Description        *string `json:"description,omitempty"`
+	DiscountPercent    *int    `json:"discount_percent,omitempty"`
+	AdvanceBookingDays *int    `json:"advance_booking_days,omitempty"`
+	MaxDurationMinutes *int    `json:"max_duration_minutes,omitempty"`
+	DurationDays       *int    `json:"duration_days,omitempty"`
+	IsActive           *bool   `json:"is_active,omitempty"`
+}
+
+// Validate 校验会员方案（中文说明：折扣 0-100，覆盖项非负，有效天数为正）
+func (p MembershipPlan) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name required")
+	}
+	if p.DiscountPercent < 0 || p.DiscountPercent > 100 {
+		return errors.New("discount_percent must be between 0 and 100")
+	}
+	if p.AdvanceBookingDays != nil && *p.AdvanceBookingDays < 0 {
+		return errors.New("advance_booking_days must be >= 0")
+	}
+	if p.MaxDurationMinutes != nil && *p.MaxDurationMinutes <= 0 {
+		return errors.New("max_duration_minutes must be > 0")
+	}
+	if p.DurationDays <= 0 {
+		return errors.New("duration_days must be > 0")
+	}
+	return nil
+}
+
+// apply 将部分更新合并到当前值（用于整体校验）
+func (u MembershipPlanUpdate) apply(p MembershipPlan) MembershipPlan {
+	if u.Name != nil {
+		p.Name = *u.Name
+	}
+	if u.Description != nil {
+		p.Description = *u.Description
+	}
+	if u.DiscountPercent != nil {
+		p.DiscountPercent = *u.DiscountPercent
+	}
+	if u.AdvanceBookingDays != nil {
+		p.AdvanceBookingDays = u.AdvanceBookingDays
+	}
+	if u.MaxDurationMinutes != nil {
+		p.MaxDurationMinutes = u.MaxDurationMinutes
+	}
+	if u.DurationDays != nil {
+		p.DurationDays = *u.DurationDays
+	}
+	if u.IsActive != nil {
+		p.IsActive = *u.IsActive
+	}
+	return p
+}
+
+func (p MembershipPlan) toPayload() map[string]interface{} {
+	return map[string]interface{}{
+		"venue_id":             p.VenueID,
+		"name":                 p.Name,
+		"description":          p.Description,
+		"discount_percent":     p.DiscountPercent,
+		"advance_booking_days": p.AdvanceBookingDays,
+		"max_duration_minutes": p.MaxDurationMinutes,
+		"duration_days":        p.DurationDays,
+		"is_active":            p.IsActive,
+	}
+}
+
+// AppliesToVenue 方案是否适用于指定场馆
+func (p MembershipPlan) AppliesToVenue(venueID int64) bool {
+	return p.VenueID == nil || *p.VenueID == venueID
+}
+
+// ListMembershipPlans 查询会员方案（中文说明：venueID>0 时返回该场馆方案与通用方案；includeInactive=false 时仅返回启用的方案）
+func (d *DB) ListMembershipPlans(ctx context.Context, venueID int64, includeInactive bool) ([]MembershipPlan, error) {
+	q := d.Client.DB.From("membership_plans").
+		Select("*").
+		OrderBy("id", "asc")
+	if !includeInactive {
+		q.Eq("is_active", "true")
+	}
+	var out []membershipPlanDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	res := make([]MembershipPlan, 0, len(out))
+	for _, v := range out {
+		p := v.toAPI()
+		if venueID > 0 && !p.AppliesToVenue(venueID) {
+			continue
+		}
+		if !optionalVenueInScope(ctx, p.VenueID) {
+			continue
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// GetMembershipPlan 查询单个会员方案
+func (d *DB) GetMembershipPlan(ctx context.Context, id int64) (*MembershipPlan, error) {
+	var out []membershipPlanDB
+	err := d.Client.DB.From("membership_plans").
+		Select("*").
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("membership plan not found")
+	}
+	p := out[0].toAPI()
+	if !optionalVenueInScope(ctx, p.VenueID) {
+		return nil, ErrOutOfScope
+	}
+	return &p, nil
+}
+
+// CreateMembershipPlan 新增会员方案
+func (d *DB) CreateMembershipPlan(ctx context.Context, p MembershipPlan) (*MembershipPlan, error) {
+	if !optionalVenueInScope(ctx, p.VenueID) {
+		return nil, ErrOutOfScope
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	var out []membershipPlanDB
+	if err := d.Client.DB.From("membership_plans").Insert(p.toPayload()).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create membership plan")
+	}
+	created := out[0].toAPI()
+	d.audit(ctx, AuditMembershipPlanCreate, EntityMembershipPlan, created.ID, nil, created)
+	return &created, nil
+}
+
+// UpdateMembershipPlan 更新会员方案（中文说明：停用后不可再发放，已发放的会员在有效期内仍享受权益）
+func (d *DB) UpdateMembershipPlan(ctx context.Context, id int64, upd MembershipPlanUpdate) (*MembershipPlan, error) {
+	before, err := d.GetMembershipPlan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	merged := upd.apply(*before)
+	if err := merged.Validate(); err != nil {
+		return nil, err
+	}
+	payload := merged.toPayload()
+	delete(payload, "venue_id")
+	var out []membershipPlanDB
+	err = d.Client.DB.From("membership_plans").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("membership plan not found")
+	}
+	updated := out[0].toAPI()
+	d.audit(ctx, AuditMembershipPlanUpdate, EntityMembershipPlan, id, before, updated)
+	return &updated, nil
+}
+
+// Membership 用户持有的会员（中文说明：StartsAt <= t < EndsAt 时有效）
+type Membership struct {
+	ID        int64          `json:"ID"`
+	UserID    string         `json:"UserID"`
+	PlanID    int64          `json:"PlanID"`
+	Plan      MembershipPlan `json:"Plan"`
+	StartsAt  time.Time      `json:"StartsAt"`
+	EndsAt    time.Time      `json:"EndsAt"`
+	CreatedBy string         `json:"CreatedBy,omitempty"`
+	CreatedAt time.Time      `json:"CreatedAt"`
+}
+
+type membershipDB struct {
+	ID        int64            `json:"id"`
+	UserID    string           `json:"user_id"`
+	PlanID    int64            `json:"plan_id"`
+	Plan      membershipPlanDB `json:"membership_plans"`
+	StartsAt  time.Time        `json:"starts_at"`
+	EndsAt    time.Time        `json:"ends_at"`
+	CreatedBy *string          `json:"created_by"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+func (m *membershipDB) toAPI() Membership {
+	return Membership{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		PlanID:    m.PlanID,
+		Plan:      m.Plan.toAPI(),
+		StartsAt:  m.StartsAt,
+		EndsAt:    m.EndsAt,
+		CreatedBy: deref(m.CreatedBy),
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// ActiveAt 会员在时间 t 是否有效
+func (m Membership) ActiveAt(t time.Time) bool {
+	return !t.Before(m.StartsAt) && t.Before(m.EndsAt)
+}
+
+const membershipSelect = "*,membership_plans(*)"
+
+// ListMemberships 查询用户的会员记录（按开始时间倒序；管理员仅能看到其范围内的方案）
+func (d *DB) ListMemberships(ctx context.Context, userID string) ([]Membership, error) {
+	var out []membershipDB
+	err := d.Client.DB.From("user_memberships").
+		Select(membershipSelect).
+		OrderBy("starts_at", "desc").
+		Eq("user_id", userID).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Membership, 0, len(out))
+	for _, v := range out {
+		m := v.toAPI()
+		if !optionalVenueInScope(ctx, m.Plan.VenueID) {
+			continue
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+// ListActiveMemberships 查询用户在时间 at 有效的会员
+func (d *DB) ListActiveMemberships(ctx context.Context, userID string, at time.Time) ([]Membership, error) {
+	ts := at.UTC().Format(time.RFC3339)
+	var out []membershipDB
+	err := d.Client.DB.From("user_memberships").
+		Select(membershipSelect).
+		Eq("user_id", userID).
+		Lte("starts_at", ts).
+		Gt("ends_at", ts).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Membership, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+func (d *DB) getMembership(ctx context.Context, id int64) (*Membership, error) {
+	var out []membershipDB
+	err := d.Client.DB.From("user_memberships").
+		Select(membershipSelect).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("membership not found")
+	}
+	m := out[0].toAPI()
+	if !optionalVenueInScope(ctx, m.Plan.VenueID) {
+		return nil, ErrOutOfScope
+	}
+	return &m, nil
+}
+
+// GrantMembership 为用户发放会员（中文说明：方案须启用且在管理范围内）
+func (d *DB) GrantMembership(ctx context.Context, userID string, planID int64, start, end time.Time) (*Membership, error) {
+	plan, err := d.GetMembershipPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.IsActive {
+		return nil, errors.New("membership plan is inactive")
+	}
+	if !end.After(start) {
+		return nil, errors.New("ends_at must be after starts_at")
+	}
+	payload := map[string]interface{}{
+		"user_id":   userID,
+		"plan_id":   planID,
+		"starts_at": start.UTC().Format(time.RFC3339),
+		"ends_at":   end.UTC().Format(time.RFC3339),
+	}
+	if actor := ActorFromContext(ctx); actor != "" {
+		payload["created_by"] = actor
+	}
+	var out []membershipDB
+	if err := d.Client.DB.From("user_memberships").Insert(payload).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to grant membership")
+	}
+	m := out[0].toAPI()
+	m.Plan = *plan
+	d.audit(ctx, AuditMembershipGrant, EntityMembership, m.ID, nil, m)
+	return &m, nil
+}
+
+// RevokeMembership 提前结束会员（中文说明：保留记录，将 ends_at 置为当前时间；尚未开始的会员变为空区间）
+func (d *DB) RevokeMembership(ctx context.Context, id int64, now time.Time) (*Membership, error) {
+	before, err := d.getMembership(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !now.Before(before.EndsAt) {
+		return before, nil
+	}
+	end := now
+	if end.Before(before.StartsAt) {
+		end = before.StartsAt
+	}
+	var out []membershipDB
+	err = d.Client.DB.From("user_memberships").
+		Update(map[string]interface{}{"ends_at": end.UTC().Format(time.RFC3339)}).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("membership not found")
+	}
+	res := *before
+	res.EndsAt = out[0].EndsAt
+	d.audit(ctx, AuditMembershipRevoke, EntityMembership, id, before, res)
+	return &res, nil
+}
@@ -0,0 +1,251 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 发件箱状态
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed" // 超过重试次数
+)
+
+// ErrOutboxNotFound 发件箱记录不存在
+var ErrOutboxNotFound = errors.New("notification not found")
+
+// OutboxMessage 发件箱中的邮件（中文说明：入队时已按收件人语言渲染，Attempts 为已尝试投递次数）
+type OutboxMessage struct {
+	ID            int64      `json:"ID"`
+	UserID        string     `json:"UserID"`
+	Kind          string     `json:"Kind"`
+	BookingID     *int64     `json:"BookingID,omitempty"`
+	Locale        string     `json:"Locale"`
+	Recipient     string     `json:"Recipient"`
+	Subject       string     `json:"Subject"`
+	Body          string     `json:"Body"`
+	Status        string     `json:"Status"`
+	Attempts      int        `json:"Attempts"`
+	NextAttemptAt time.Time  `json:"NextAttemptAt"`
+	LastError     string     `json:"LastError,omitempty"`
+	CreatedAt     time.Time  `json:"CreatedAt"`
+	SentAt        *time.Time `json:"SentAt,omitempty"`
+}
+
+type outboxMessageDB struct {
+	ID            int64      `json:"id"`
+	UserID        string     `json:"user_id"`
+	Kind          string     `json:"kind"`
+	BookingID     *int64     `json:"booking_id"`
+	Locale        string     `json:"locale"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     *string    `json:"last_error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at"`
+}
+
+func (m *outboxMessageDB) toAPI() OutboxMessage {
+	return OutboxMessage{
+		ID:            m.ID,
+		UserID:        m.UserID,
+		Kind:          m.Kind,
+		BookingID:     m.BookingID,
+		Locale:        m.Locale,
+		Recipient:     m.Recipient,
+		Subject:       m.Subject,
+		Body:          m.Body,
+		Status:        m.Status,
+		Attempts:      m.Attempts,
+		NextAttemptAt: m.NextAttemptAt,
+		LastError:     deref(m.LastError),
+		CreatedAt:     m.CreatedAt,
+		SentAt:        m.SentAt,
+	}
+}
+
+func outboxToAPI(out []outboxMessageDB) []OutboxMessage {
+	res := make([]OutboxMessage, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res
+}
+
+// NotificationContact 通知收件人与退订设置（中文说明：Email 为空表示无法发送邮件）
+type NotificationContact struct {
+	Email          string `json:"email"`
+	Locale         string `json:"locale"`
+	ReminderOptOut bool   `json:"reminder_opt_out"` // 不接收开场前提醒
+	RatingOptOut   bool   `json:"rating_opt_out"`   // 不接收评分邀请
+}
+
+// NotificationSettingsUpdate 通知设置更新（中文说明：nil 字段不修改）
+type NotificationSettingsUpdate struct {
+	Locale         *string `json:"locale,omitempty"`
+	ReminderOptOut *bool   `json:"reminder_opt_out,omitempty"`
+	RatingOptOut   *bool   `json:"rating_opt_out,omitempty"`
+}
+
+// IsEmpty 是否没有任何更新字段
+func (u NotificationSettingsUpdate) IsEmpty() bool {
+	return u.Locale == nil && u.ReminderOptOut == nil && u.RatingOptOut == nil
+}
+
+// GetNotificationContact 查询用户邮箱与通知语言（数据库函数 notification_contact，可读取 Supabase 用户邮箱；
+// 仅 service_role 可执行，未配置时返回 ErrServiceRoleRequired）
+func (d *DB) GetNotificationContact(ctx context.Context, userID string) (*NotificationContact, error) {
+	if d.service == nil {
+		return nil, ErrServiceRoleRequired
+	}
+	var out []struct {
+		Email          *string `json:"email"`
+		Locale         string  `json:"locale"`
+		ReminderOptOut bool    `json:"reminder_opt_out"`
+		RatingOptOut   bool    `json:"rating_opt_out"`
+	}
+	err := d.service.DB.Rpc("notification_contact", map[string]interface{}{"p_user_id": userID}).ExecuteWithContext(ctx, &out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return &NotificationContact{}, nil
+	}
+	c := out[0]
+	return &NotificationContact{Email: deref(c.Email), Locale: c.Locale, ReminderOptOut: c.ReminderOptOut, RatingOptOut: c.RatingOptOut}, nil
+}
+
+// UpdateNotificationSettings 修改用户通知语言与退订设置（profiles 不存在时创建）
+func (d *DB) UpdateNotificationSettings(ctx context.Context, userID string, upd NotificationSettingsUpdate) error {
+	payload := map[string]interface{}{"user_id": userID}
+	if upd.Locale != nil {
+		payload["locale"] = *upd.Locale
+	}
+	if upd.ReminderOptOut != nil {
+		payload["reminder_opt_out"] = *upd.ReminderOptOut
+	}
+	if upd.RatingOptOut != nil {
+		payload["rating_opt_out"] = *upd.RatingOptOut
+	}
+	var out []map[string]interface{}
+	return d.Client.DB.From("profiles").Upsert(payload).Execute(&out)
+}
+
+// EnqueueNotification 写入发件箱，等待后台任务投递
+func (d *DB) EnqueueNotification(ctx context.Context, m OutboxMessage) (*OutboxMessage, error) {
+	payload := map[string]interface{}{
+		"user_id":    m.UserID,
+		"kind":       m.Kind,
+		"booking_id": m.BookingID,
+		"locale":     m.Locale,
+		"recipient":  m.Recipient,
+		"subject":    m.Subject,
+		"body":       m.Body,
+	}
+	var out []outboxMessageDB
+	if err := d.Client.DB.From("notification_outbox").Insert(payload).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to enqueue notification")
+	}
+	res := out[0].toAPI()
+	return &res, nil
+}
+
+// ClaimOutbox 领取到期的待投递邮件（中文说明：领取即计一次尝试，并在租期内对其它实例不可见）
+func (d *DB) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	params := map[string]interface{}{
+		"p_limit":         limit,
+		"p_lease_seconds": int(lease.Seconds()),
+	}
+	var out []outboxMessageDB
+	if err := d.Client.DB.Rpc("notification_outbox_claim", params).ExecuteWithContext(ctx, &out); err != nil {
+		return nil, err
+	}
+	return outboxToAPI(out), nil
+}
+
+// MarkOutboxSent 标记已投递
+func (d *DB) MarkOutboxSent(ctx context.Context, id int64, at time.Time) error {
+	var out []outboxMessageDB
+	return d.Client.DB.From("notification_outbox").
+		Update(map[string]interface{}{
+			"status":     OutboxSent,
+			"sent_at":    at.UTC().Format(time.RFC3339),
+			"last_error": nil,
+		}).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+}
+
+// MarkOutboxRetry 记录投递失败（中文说明：next 为 nil 表示不再重试，标记为 failed）
+func (d *DB) MarkOutboxRetry(ctx context.Context, id int64, lastError string, next *time.Time) error {
+	payload := map[string]interface{}{"last_error": lastError}
+	if next == nil {
+		payload["status"] = OutboxFailed
+	} else {
+		payload["next_attempt_at"] = next.UTC().Format(time.RFC3339)
+	}
+	var out []outboxMessageDB
+	return d.Client.DB.From("notification_outbox").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+}
+
+// ListOutbox 查询发件箱（status 为空时不过滤，按创建时间倒序）
+func (d *DB) ListOutbox(ctx context.Context, status string, limit int) ([]OutboxMessage, error) {
+	// 发件箱跨场馆且含收件人信息，仅平台管理员可查询
+	if !ScopeFromContext(ctx).All {
+		return nil, ErrOutOfScope
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	q := d.Client.DB.From("notification_outbox").
+		Select("*").
+		OrderBy("created_at", "desc").
+		Limit(limit)
+	if status != "" {
+		q.Eq("status", status)
+	}
+	var out []outboxMessageDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	return outboxToAPI(out), nil
+}
+
+// RequeueOutbox 重新投递失败的邮件（重置尝试次数）
+func (d *DB) RequeueOutbox(ctx context.Context, id int64) (*OutboxMessage, error) {
+	if !ScopeFromContext(ctx).All {
+		return nil, ErrOutOfScope
+	}
+	var out []outboxMessageDB
+	err := d.Client.DB.From("notification_outbox").
+		Update(map[string]interface{}{
+			"status":          OutboxPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now().UTC().Format(time.RFC3339),
+		}).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Eq("status", OutboxFailed).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrOutboxNotFound
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditNotificationRequeue, EntityNotification, res.ID, nil, res)
+	return &res, nil
+}
@@ -0,0 +1,193 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	postgrest "github.com/nedpals/supabase-go/postgrest/pkg"
+)
+
+// errCodeParticipantLimit 数据库函数超出单元人数上限时抛出的 SQLSTATE（见 017_booking_participants.sql）
+const errCodeParticipantLimit = "P0409"
+
+// errCodeUniqueViolation 唯一约束冲突
+const errCodeUniqueViolation = "23505"
+
+// MaxGuestNameLength 访客姓名最大长度
+const MaxGuestNameLength = 100
+
+// ErrParticipantLimit 预约人数已达单元上限
+var ErrParticipantLimit = errors.New("participant limit reached")
+
+// ErrAlreadyParticipant 用户已是该预约的预订人或参与人
+var ErrAlreadyParticipant = errors.New("user is already on this booking")
+
+// BookingParticipant 预约参与人（中文说明：UserID 与 GuestName 二选一）
+type BookingParticipant struct {
+	ID        int64     `json:"ID"`
+	BookingID int64     `json:"BookingID"`
+	UserID    string    `json:"UserID,omitempty"`
+	GuestName string    `json:"GuestName,omitempty"`
+	AddedBy   string    `json:"AddedBy,omitempty"`
+	CreatedAt time.Time `json:"CreatedAt"`
+}
+
+type bookingParticipantDB struct {
+	ID        int64     `json:"id"`
+	BookingID int64     `json:"booking_id"`
+	UserID    *string   `json:"user_id"`
+	GuestName *string   `json:"guest_name"`
+	AddedBy   *string   `json:"added_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (p *bookingParticipantDB) toAPI() BookingParticipant {
+	return BookingParticipant{
+		ID:        p.ID,
+		BookingID: p.BookingID,
+		UserID:    deref(p.UserID),
+		GuestName: deref(p.GuestName),
+		AddedBy:   deref(p.AddedBy),
+		CreatedAt: p.CreatedAt,
+	}
+}
+
+// NewParticipant 新增参与人请求
+type NewParticipant struct {
+	UserID    string `json:"user_id"`
+	GuestName string `json:"guest_name"`
+}
+
+// Normalize 去除首尾空白
+func (p NewParticipant) Normalize() NewParticipant {
+	p.UserID = strings.TrimSpace(p.UserID)
+	p.GuestName = strings.TrimSpace(p.GuestName)
+	return p
+}
+
+// Validate 校验参与人（中文说明：须且只能提供 user_id 或 guest_name）
+func (p NewParticipant) Validate() error {
+	if (p.UserID == "") == (p.GuestName == "") {
+		return errors.New("exactly one of user_id or guest_name required")
+	}
+	if len([]rune(p.GuestName)) > MaxGuestNameLength {
+		return fmt.Errorf("guest_name must be at most %d characters", MaxGuestNameLength)
+	}
+	return nil
+}
+
+// Headcount 预约人数（预订人 + 参与人）
+func Headcount(participants []BookingParticipant) int {
+	return 1 + len(participants)
+}
+
+// ListBookingParticipants 查询预约参与人（按添加顺序）
+func (d *DB) ListBookingParticipants(ctx context.Context, bookingID int64) ([]BookingParticipant, error) {
+	var out []bookingParticipantDB
+	err := d.Client.DB.From("booking_participants").
+		Select("*").
+		OrderBy("id", "asc").
+		Eq("booking_id", fmt.Sprintf("%d", bookingID)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]BookingParticipant, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// AddBookingParticipant 添加参与人（中文说明：由数据库函数按预约加锁校验单元人数上限，超限返回 ErrParticipantLimit）
+func (d *DB) AddBookingParticipant(ctx context.Context, bookingID int64, p NewParticipant) (*BookingParticipant, error) {
+	params := map[string]interface{}{
+		"p_booking_id": bookingID,
+		"p_user_id":    nil,
+		"p_guest_name": nil,
+		"p_added_by":   nil,
+	}
+	if p.UserID != "" {
+		params["p_user_id"] = p.UserID
+	}
+	if p.GuestName != "" {
+		params["p_guest_name"] = p.GuestName
+	}
+	if actor := ActorFromContext(ctx); actor != "" {
+		params["p_added_by"] = actor
+	}
+	var out []bookingParticipantDB
+	if err := d.Client.DB.Rpc("booking_participant_add", params).ExecuteWithContext(ctx, &out); err != nil {
+		var reqErr *postgrest.RequestError
+		if errors.As(err, &reqErr) {
+			switch reqErr.Code {
+			case errCodeParticipantLimit:
+				return nil, ErrParticipantLimit
+			case errCodeUniqueViolation:
+				return nil, ErrAlreadyParticipant
+			}
+		}
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to add participant")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditParticipantAdd, EntityParticipant, res.ID, nil, res)
+	return &res, nil
+}
+
+// RemoveBookingParticipant 移除参与人
+func (d *DB) RemoveBookingParticipant(ctx context.Context, bookingID, id int64) error {
+	var out []bookingParticipantDB
+	err := d.Client.DB.From("booking_participants").
+		Delete().
+		Eq("id", fmt.Sprintf("%d", id)).
+		Eq("booking_id", fmt.Sprintf("%d", bookingID)).
+		Execute(&out)
+	if err != nil {
+		return err
+	}
+	if len(out) == 0 {
+		return errors.New("participant not found")
+	}
+	d.audit(ctx, AuditParticipantRemove, EntityParticipant, id, out[0].toAPI(), nil)
+	return nil
+}
+
+// IsBookingParticipant 用户是否为预约的参与人
+func (d *DB) IsBookingParticipant(ctx context.Context, bookingID int64, userID string) (bool, error) {
+	if userID == "" {
+		return false, nil
+	}
+	var out []bookingParticipantDB
+	err := d.Client.DB.From("booking_participants").
+		Select("id").
+		Eq("booking_id", fmt.Sprintf("%d", bookingID)).
+		Eq("user_id", userID).
+		Execute(&out)
+	if err != nil {
+		return false, err
+	}
+	return len(out) > 0, nil
+}
+
+// listParticipantBookingIDs 查询用户作为参与人的预约 ID
+func (d *DB) listParticipantBookingIDs(ctx context.Context, userID string) ([]string, error) {
+	var out []bookingParticipantDB
+	err := d.Client.DB.From("booking_participants").
+		Select("booking_id").
+		Eq("user_id", userID).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(out))
+	for i, p := range out {
+		ids[i] = fmt.Sprintf("%d", p.BookingID)
+	}
+	return ids, nil
+}
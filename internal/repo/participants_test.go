@@ -0,0 +1,36 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+)
+
+// 测试参与人校验与人数统计（中文说明：user_id 与 guest_name 二选一，人数含预订人）
+func TestNewParticipantValidate(t *testing.T) {
+	if err := (NewParticipant{UserID: "u1"}).Validate(); err != nil {
+		t.Fatalf("expected valid user, got %v", err)
+	}
+	if err := (NewParticipant{GuestName: " 张三 "}).Normalize().Validate(); err != nil {
+		t.Fatalf("expected valid guest, got %v", err)
+	}
+	if (NewParticipant{}).Validate() == nil {
+		t.Fatalf("empty participant should be rejected")
+	}
+	if (NewParticipant{UserID: "u1", GuestName: "Ann"}).Validate() == nil {
+		t.Fatalf("both user_id and guest_name should be rejected")
+	}
+	if (NewParticipant{GuestName: "   "}).Normalize().Validate() == nil {
+		t.Fatalf("blank guest name should be rejected")
+	}
+	long := NewParticipant{GuestName: strings.Repeat("名", MaxGuestNameLength+1)}
+	if long.Validate() == nil {
+		t.Fatalf("overlong guest name should be rejected")
+	}
+
+	if got := Headcount(nil); got != 1 {
+		t.Fatalf("booker alone should count as 1, got %d", got)
+	}
+	if got := Headcount([]BookingParticipant{{UserID: "u1"}, {GuestName: "Ann"}}); got != 3 {
+		t.Fatalf("expected headcount 3, got %d", got)
+	}
+}
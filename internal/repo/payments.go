@@ -0,0 +1,409 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// 支付状态
+const (
+	PaymentRequiresPayment   = "requires_payment"
+	PaymentSucceeded         = "succeeded"
+	PaymentFailed            = "failed"
+	PaymentCancelled         = "cancelled"
+	PaymentPartiallyRefunded = "partially_refunded"
+	PaymentRefunded          = "refunded"
+)
+
+// 支付用途（中文说明：整单支付、分摊份额、发起人补足剩余份额）
+const (
+	PaymentKindBooking = "booking"
+	PaymentKindShare   = "share"
+	PaymentKindCover   = "cover"
+)
+
+// Payment 支付记录（中文说明：一条预约可有多次支付尝试；ProviderRef 为渠道侧支付意图 ID；未注册受邀人支付份额时 UserID 为空）
+type Payment struct {
+	ID             int64     `json:"ID"`
+	BookingID      int64     `json:"BookingID"`
+	UserID         string    `json:"UserID,omitempty"`
+	Kind           string    `json:"Kind"`
+	ShareID        *int64    `json:"ShareID,omitempty"`
+	Provider       string    `json:"Provider"`
+	ProviderRef    string    `json:"ProviderRef"`
+	Amount         float64   `json:"Amount"`
+	RefundedAmount float64   `json:"RefundedAmount"`
+	Currency       string    `json:"Currency"`
+	Status         string    `json:"Status"`
+	FailureReason  string    `json:"FailureReason,omitempty"`
+	CreatedAt      time.Time `json:"CreatedAt"`
+	UpdatedAt      time.Time `json:"UpdatedAt"`
+}
+
+type paymentDB struct {
+	ID             int64     `json:"id"`
+	BookingID      int64     `json:"booking_id"`
+	UserID         *string   `json:"user_id"`
+	Kind           string    `json:"kind"`
+	ShareID        *int64    `json:"share_id"`
+	Provider       string    `json:"provider"`
+	ProviderRef    string    `json:"provider_ref"`
+	Amount         float64   `json:"amount"`
+	RefundedAmount float64   `json:"refunded_amount"`
+	Currency       string    `json:"currency"`
+	Status         string    `json:"status"`
+	FailureReason  *string   `json:"failure_reason"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (p *paymentDB) toAPI() Payment {
+	return Payment{
+		ID:             p.ID,
+		BookingID:      p.BookingID,
+		UserID:         deref(p.UserID),
+		Kind:           p.Kind,
+		ShareID:        p.ShareID,
+		Provider:       p.Provider,
+		ProviderRef:    p.ProviderRef,
+		Amount:         p.Amount,
+		RefundedAmount: p.RefundedAmount,
+		Currency:       p.Currency,
+		Status:         p.Status,
+		FailureReason:  deref(p.FailureReason),
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+	}
+}
+
+// Refundable 剩余可退金额
+func (p Payment) Refundable() float64 {
+	if p.Status != PaymentSucceeded && p.Status != PaymentPartiallyRefunded {
+		return 0
+	}
+	return math.Max(0, roundCents(p.Amount-p.RefundedAmount))
+}
+
+// roundCents 金额保留两位小数
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// PaymentRefund 退款记录
+type PaymentRefund struct {
+	ID          int64     `json:"ID"`
+	PaymentID   int64     `json:"PaymentID"`
+	BookingID   int64     `json:"BookingID"`
+	Amount      float64   `json:"Amount"`
+	ProviderRef string    `json:"ProviderRef"`
+	Status      string    `json:"Status"`
+	Reason      string    `json:"Reason,omitempty"`
+	Policy      string    `json:"Policy,omitempty"`  // full / partial / none / override / manual
+	Percent     *int      `json:"Percent,omitempty"` // 按比例退款时的百分比
+	CreatedAt   time.Time `json:"CreatedAt"`
+}
+
+type paymentRefundDB struct {
+	ID          int64     `json:"id"`
+	PaymentID   int64     `json:"payment_id"`
+	BookingID   int64     `json:"booking_id"`
+	Amount      float64   `json:"amount"`
+	ProviderRef string    `json:"provider_ref"`
+	Status      string    `json:"status"`
+	Reason      *string   `json:"reason"`
+	Policy      *string   `json:"policy"`
+	Percent     *int      `json:"percent"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (r *paymentRefundDB) toAPI() PaymentRefund {
+	return PaymentRefund{
+		ID:          r.ID,
+		PaymentID:   r.PaymentID,
+		BookingID:   r.BookingID,
+		Amount:      r.Amount,
+		ProviderRef: r.ProviderRef,
+		Status:      r.Status,
+		Reason:      deref(r.Reason),
+		Policy:      deref(r.Policy),
+		Percent:     r.Percent,
+		CreatedAt:   r.CreatedAt,
+	}
+}
+
+// CreatePayment 新建支付记录
+func (d *DB) CreatePayment(ctx context.Context, p Payment) (*Payment, error) {
+	payload := map[string]interface{}{
+		"booking_id":   p.BookingID,
+		"kind":         PaymentKindBooking,
+		"provider":     p.Provider,
+		"provider_ref": p.ProviderRef,
+		"amount":       p.Amount,
+		"currency":     p.Currency,
+		"status":       p.Status,
+	}
+	if p.Kind != "" {
+		payload["kind"] = p.Kind
+	}
+	if p.UserID != "" {
+		payload["user_id"] = p.UserID
+	}
+	if p.ShareID != nil {
+		payload["share_id"] = *p.ShareID
+	}
+	var out []paymentDB
+	if err := d.Client.DB.From("payments").Insert(payload).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create payment")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditPaymentCreate, EntityPayment, res.ID, nil, res)
+	return &res, nil
+}
+
+func (d *DB) getPayment(column, value string) (*Payment, error) {
+	var out []paymentDB
+	err := d.Client.DB.From("payments").
+		Select("*").
+		Eq(column, value).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("payment not found")
+	}
+	res := out[0].toAPI()
+	return &res, nil
+}
+
+// GetPaymentByID 查询支付记录（中文说明：租户范围按所属预约判断）
+func (d *DB) GetPaymentByID(ctx context.Context, id int64) (*Payment, error) {
+	p, err := d.getPayment("id", fmt.Sprintf("%d", id))
+	if err != nil {
+		return nil, err
+	}
+	if !ScopeFromContext(ctx).All {
+		if _, err := d.GetBookingByID(ctx, p.BookingID); err != nil {
+			return nil, ErrOutOfScope
+		}
+	}
+	return p, nil
+}
+
+// GetPaymentByProviderRef 按渠道支付意图 ID 查询（Webhook 使用）
+func (d *DB) GetPaymentByProviderRef(ctx context.Context, provider, ref string) (*Payment, error) {
+	p, err := d.getPayment("provider_ref", ref)
+	if err != nil {
+		return nil, err
+	}
+	if p.Provider != provider {
+		return nil, errors.New("payment not found")
+	}
+	return p, nil
+}
+
+// ListPaymentsByBooking 查询预约的支付记录（按创建时间）
+func (d *DB) ListPaymentsByBooking(ctx context.Context, bookingID int64) ([]Payment, error) {
+	var out []paymentDB
+	err := d.Client.DB.From("payments").
+		Select("*").
+		OrderBy("created_at", "asc").
+		Eq("booking_id", fmt.Sprintf("%d", bookingID)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Payment, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// ListStalePayments 查询创建早于 before 仍未支付的整单支付（用于释放超时占位；份额与补足支付由分摊截止时间控制）
+func (d *DB) ListStalePayments(ctx context.Context, before time.Time) ([]Payment, error) {
+	var out []paymentDB
+	err := d.Client.DB.From("payments").
+		Select("*").
+		Eq("status", PaymentRequiresPayment).
+		Eq("kind", PaymentKindBooking).
+		Lt("created_at", before.UTC().Format(time.RFC3339)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Payment, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// TransitionPayment 条件更新支付状态（中文说明：仅当前状态在 from 中才更新，保证 Webhook 重放幂等；返回 nil 表示未更新）
+func (d *DB) TransitionPayment(ctx context.Context, id int64, from []string, to, failureReason string) (*Payment, error) {
+	before, err := d.getPayment("id", fmt.Sprintf("%d", id))
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]interface{}{
+		"status":     to,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	if failureReason != "" {
+		payload["failure_reason"] = failureReason
+	}
+	var out []paymentDB
+	err = d.Client.DB.From("payments").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		In("status", from).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditPaymentUpdate, EntityPayment, id, before, res)
+	return &res, nil
+}
+
+// refundCASRetries 并发退款时比较并更新 refunded_amount 的重试次数
+const refundCASRetries = 5
+
+// ErrRefundExceeds 退款金额超过可退金额
+var ErrRefundExceeds = errors.New("refund amount exceeds refundable")
+
+// ReserveRefund 预占退款金额并返回更新后的支付记录（中文说明：amount<=0 表示全部剩余金额；按 refunded_amount 比较并更新，
+// 并发退款不会超退或互相覆盖；渠道退款失败时须调用 ReleaseRefund 归还）
+func (d *DB) ReserveRefund(ctx context.Context, id int64, amount float64) (*Payment, float64, error) {
+	for i := 0; i < refundCASRetries; i++ {
+		p, err := d.getPayment("id", fmt.Sprintf("%d", id))
+		if err != nil {
+			return nil, 0, err
+		}
+		refundable := p.Refundable()
+		if refundable <= 0 {
+			return nil, 0, fmt.Errorf("%w: payment has nothing to refund", ErrRefundExceeds)
+		}
+		n := amount
+		if n <= 0 {
+			n = refundable
+		}
+		if n > refundable {
+			return nil, 0, fmt.Errorf("%w %.2f", ErrRefundExceeds, refundable)
+		}
+		updated, err := d.setRefundedAmount(ctx, *p, roundCents(p.RefundedAmount+n))
+		if err != nil {
+			return nil, 0, err
+		}
+		if updated != nil {
+			return updated, n, nil
+		}
+	}
+	return nil, 0, errors.New("payment refund conflict, retry later")
+}
+
+// ReleaseRefund 归还 ReserveRefund 预占但渠道未能退出的金额
+func (d *DB) ReleaseRefund(ctx context.Context, id int64, amount float64) error {
+	for i := 0; i < refundCASRetries; i++ {
+		p, err := d.getPayment("id", fmt.Sprintf("%d", id))
+		if err != nil {
+			return err
+		}
+		updated, err := d.setRefundedAmount(ctx, *p, math.Max(0, roundCents(p.RefundedAmount-amount)))
+		if err != nil {
+			return err
+		}
+		if updated != nil {
+			return nil
+		}
+	}
+	return errors.New("payment refund conflict, retry later")
+}
+
+// setRefundedAmount 仅当 refunded_amount 仍为读取时的值才更新（中文说明：状态按累计金额为 succeeded、partially_refunded 或 refunded；
+// 返回 nil 表示已被并发修改）
+func (d *DB) setRefundedAmount(ctx context.Context, p Payment, refunded float64) (*Payment, error) {
+	status := PaymentSucceeded
+	switch {
+	case refunded >= p.Amount:
+		status = PaymentRefunded
+	case refunded > 0:
+		status = PaymentPartiallyRefunded
+	}
+	var out []paymentDB
+	err := d.Client.DB.From("payments").
+		Update(map[string]interface{}{
+			"refunded_amount": refunded,
+			"status":          status,
+			"updated_at":      time.Now().UTC().Format(time.RFC3339),
+		}).
+		Eq("id", fmt.Sprintf("%d", p.ID)).
+		Eq("refunded_amount", fmt.Sprintf("%.2f", p.RefundedAmount)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditPaymentUpdate, EntityPayment, p.ID, p, res)
+	return &res, nil
+}
+
+// RecordRefund 记录渠道已完成的退款（中文说明：金额须已由 ReserveRefund 计入 refunded_amount）
+func (d *DB) RecordRefund(ctx context.Context, p Payment, r PaymentRefund) (*PaymentRefund, error) {
+	payload := map[string]interface{}{
+		"payment_id":   p.ID,
+		"booking_id":   p.BookingID,
+		"amount":       r.Amount,
+		"provider_ref": r.ProviderRef,
+		"status":       r.Status,
+	}
+	if r.Reason != "" {
+		payload["reason"] = r.Reason
+	}
+	if r.Policy != "" {
+		payload["policy"] = r.Policy
+	}
+	if r.Percent != nil {
+		payload["percent"] = *r.Percent
+	}
+	var out []paymentRefundDB
+	if err := d.Client.DB.From("payment_refunds").Insert(payload).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to record refund")
+	}
+	refund := out[0].toAPI()
+	d.audit(ctx, AuditRefundCreate, EntityRefund, refund.ID, nil, refund)
+	return &refund, nil
+}
+
+// ListRefundsByBooking 查询预约的退款记录
+func (d *DB) ListRefundsByBooking(ctx context.Context, bookingID int64) ([]PaymentRefund, error) {
+	var out []paymentRefundDB
+	err := d.Client.DB.From("payment_refunds").
+		Select("*").
+		OrderBy("created_at", "asc").
+		Eq("booking_id", fmt.Sprintf("%d", bookingID)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]PaymentRefund, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
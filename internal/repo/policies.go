@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReservationPolicy 预约策略（中文说明：场馆按设施类型配置；未配置时取设施类型目录的默认值）
+type ReservationPolicy struct {
+	VenueID                   int64  `json:"VenueID"`
+	FacilityType              string `json:"FacilityType"`
+	MinDurationMinutes        int    `json:"MinDurationMinutes"`
+	MaxDurationMinutes        int    `json:"MaxDurationMinutes"`
+	SlotGranularityMinutes    int    `json:"SlotGranularityMinutes"`
+	AdvanceBookingDays        int    `json:"AdvanceBookingDays"`
+	CancellationCutoffMinutes int    `json:"CancellationCutoffMinutes"`
+}
+
+type reservationPolicyDB struct {
+	VenueID                   int64  `json:"venue_id"`
+	FacilityType              string `json:"facility_type"`
+	MinDurationMinutes        int    `json:"min_duration_minutes"`
+	MaxDurationMinutes        int    `json:"max_duration_minutes"`
+	SlotGranularityMinutes    int    `json:"slot_granularity_minutes"`
+	AdvanceBookingDays        int    `json:"advance_booking_days"`
+	CancellationCutoffMinutes int    `json:"cancellation_cutoff_minutes"`
+}
+
+func (p *reservationPolicyDB) toAPI() ReservationPolicy {
+	return ReservationPolicy{
+		VenueID:                   p.VenueID,
+		FacilityType:              p.FacilityType,
+		MinDurationMinutes:        p.MinDurationMinutes,
+		MaxDurationMinutes:        p.MaxDurationMinutes,
+		SlotGranularityMinutes:    p.SlotGranularityMinutes,
+		AdvanceBookingDays:        p.AdvanceBookingDays,
+		CancellationCutoffMinutes: p.CancellationCutoffMinutes,
+	}
+}
+
+// GetReservationPolicy 查询场馆某设施类型的预约策略（中文说明：场馆未配置时回退到设施类型默认策略）
+func (d *DB) GetReservationPolicy(ctx context.Context, venueID int64, facilityType string) (*ReservationPolicy, error) {
+	var out []reservationPolicyDB
+	err := d.Client.DB.From("reservation_policies").
+		Select("*").
+		Eq("venue_id", fmt.Sprintf("%d", venueID)).
+		Eq("facility_type", facilityType).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > 0 {
+		p := out[0].toAPI()
+		return &p, nil
+	}
+	ft, err := d.GetFacilityType(ctx, facilityType)
+	if err != nil {
+		return nil, err
+	}
+	return &ReservationPolicy{
+		VenueID:                   venueID,
+		FacilityType:              facilityType,
+		MinDurationMinutes:        ft.MinDurationMinutes,
+		MaxDurationMinutes:        ft.MaxDurationMinutes,
+		SlotGranularityMinutes:    ft.SlotGranularityMinutes,
+		AdvanceBookingDays:        ft.AdvanceBookingDays,
+		CancellationCutoffMinutes: ft.CancellationCutoffMinutes,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	postgrest "github.com/nedpals/supabase-go/postgrest/pkg"
+)
+
+// errCodeExclusionViolation 违反排他约束（pricing_rules_no_overlap，同场馆同类型同星期时段重叠）
+const errCodeExclusionViolation = "23P01"
+
+// ErrPricingRuleNotFound 价格规则不存在
+var ErrPricingRuleNotFound = errors.New("pricing rule not found")
+
+// ErrPricingRuleOverlap 与已有价格规则时段重叠（中文说明：并发写入绕过应用层检查时由数据库约束拒绝）
+var ErrPricingRuleOverlap = errors.New("pricing rule overlaps an existing rule")
+
+// pricingWriteError 将排他约束冲突转换为 ErrPricingRuleOverlap
+func pricingWriteError(err error) error {
+	var reqErr *postgrest.RequestError
+	if errors.As(err, &reqErr) && reqErr.Code == errCodeExclusionViolation {
+		return ErrPricingRuleOverlap
+	}
+	return err
+}
+
+type pricingRuleDB struct {
+	ID           int64   `json:"id"`
+	VenueID      int64   `json:"venue_id"`
+	FacilityType string  `json:"facility_type"`
+	DayOfWeek    int     `json:"day_of_week"`
+	StartHour    int     `json:"start_hour"`
+	EndHour      int     `json:"end_hour"`
+	PricePerHour float64 `json:"price_per_hour"`
+}
+
+func (p *pricingRuleDB) toAPI() PricingRule {
+	return PricingRule{
+		ID:           p.ID,
+		VenueID:      p.VenueID,
+		FacilityType: p.FacilityType,
+		DayOfWeek:    p.DayOfWeek,
+		StartHour:    p.StartHour,
+		EndHour:      p.EndHour,
+		PricePerHour: p.PricePerHour,
+	}
+}
+
+func pricingRulePayload(rule PricingRule) map[string]interface{} {
+	return map[string]interface{}{
+		"venue_id":       rule.VenueID,
+		"facility_type":  rule.FacilityType,
+		"day_of_week":    rule.DayOfWeek,
+		"start_hour":     rule.StartHour,
+		"end_hour":       rule.EndHour,
+		"price_per_hour": rule.PricePerHour,
+	}
+}
+
+// ListPricingRules 查询价格规则（中文说明：venueID 为 0 或 facilityType 为空时不按其过滤）
+func (d *DB) ListPricingRules(ctx context.Context, venueID int64, facilityType string) ([]PricingRule, error) {
+	q := d.Client.DB.From("pricing_rules").
+		Select("*").
+		OrderBy("day_of_week", "asc")
+	applyScope(ctx, &q.FilterRequestBuilder, "venue_id")
+	if venueID > 0 {
+		q.Eq("venue_id", fmt.Sprintf("%d", venueID))
+	}
+	if facilityType != "" {
+		q.Eq("facility_type", facilityType)
+	}
+	var out []pricingRuleDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	res := make([]PricingRule, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// GetPricingRuleByID 查询单条价格规则
+func (d *DB) GetPricingRuleByID(ctx context.Context, id int64) (*PricingRule, error) {
+	var out []pricingRuleDB
+	err := d.Client.DB.From("pricing_rules").
+		Select("*").
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrPricingRuleNotFound
+	}
+	r := out[0].toAPI()
+	if !ScopeFromContext(ctx).Allows(r.VenueID) {
+		return nil, ErrOutOfScope
+	}
+	return &r, nil
+}
+
+// CreatePricingRule 创建价格规则
+func (d *DB) CreatePricingRule(ctx context.Context, rule PricingRule) (*PricingRule, error) {
+	if !ScopeFromContext(ctx).Allows(rule.VenueID) {
+		return nil, ErrOutOfScope
+	}
+	var out []pricingRuleDB
+	if err := d.Client.DB.From("pricing_rules").Insert(pricingRulePayload(rule)).Execute(&out); err != nil {
+		return nil, pricingWriteError(err)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create pricing rule")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditPricingRuleCreate, EntityPricingRule, res.ID, nil, res)
+	return &res, nil
+}
+
+// UpdatePricingRule 更新价格规则
+func (d *DB) UpdatePricingRule(ctx context.Context, id int64, rule PricingRule) (*PricingRule, error) {
+	if !ScopeFromContext(ctx).Allows(rule.VenueID) {
+		return nil, ErrOutOfScope
+	}
+	before, err := d.GetPricingRuleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var out []pricingRuleDB
+	err = d.Client.DB.From("pricing_rules").
+		Update(pricingRulePayload(rule)).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, pricingWriteError(err)
+	}
+	if len(out) == 0 {
+		return nil, ErrPricingRuleNotFound
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditPricingRuleUpdate, EntityPricingRule, id, before, res)
+	return &res, nil
+}
+
+// DeletePricingRule 删除价格规则
+func (d *DB) DeletePricingRule(ctx context.Context, id int64) error {
+	before, err := d.GetPricingRuleByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	var out []interface{}
+	if err := d.Client.DB.From("pricing_rules").Delete().Eq("id", fmt.Sprintf("%d", id)).Execute(&out); err != nil {
+		return err
+	}
+	d.audit(ctx, AuditPricingRuleDelete, EntityPricingRule, id, before, nil)
+	return nil
+}
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 测试并发更新触发排他约束时返回 ErrPricingRuleOverlap（中文说明：处理器据此返回 409 而非 500）
+func TestUpdatePricingRuleOverlap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPatch {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"code":"23P01","message":"conflicting key value violates exclusion constraint \"pricing_rules_no_overlap\""}`))
+			return
+		}
+		w.Write([]byte(`[{"id":1,"venue_id":1,"facility_type":"badminton","day_of_week":1,"start_hour":8,"end_hour":18,"price_per_hour":20}]`))
+	}))
+	defer srv.Close()
+	d, err := NewDB(srv.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule := PricingRule{VenueID: 1, FacilityType: "badminton", DayOfWeek: 1, StartHour: 8, EndHour: 20, PricePerHour: 20}
+	if _, err := d.UpdatePricingRule(context.Background(), 1, rule); !errors.Is(err, ErrPricingRuleOverlap) {
+		t.Fatalf("expected ErrPricingRuleOverlap, got %v", err)
+	}
+}
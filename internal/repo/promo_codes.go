@@ -0,0 +1,402 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	postgrest "github.com/nedpals/supabase-go/postgrest/pkg"
+)
+
+// 优惠方式
+const (
+	PromoPercent = "percent"
+	PromoFixed   = "fixed"
+)
+
+// 核销状态
+const (
+	RedemptionActive   = "active"
+	RedemptionReversed = "reversed"
+)
+
+// errCodePromoLimit 数据库函数核销超出次数上限时抛出的 SQLSTATE（见 015_promo_codes.sql）
+const errCodePromoLimit = "P0409"
+
+// ErrPromoLimitReached 优惠码已达总次数或每人次数上限
+var ErrPromoLimitReached = errors.New("promo code usage limit reached")
+
+// PromoCode 优惠码（中文说明：VenueID 为空表示全平台通用；FacilityTypes 为空表示不限类型；时间与次数上限为空表示不限）
+type PromoCode struct {
+	ID             int64      `json:"ID"`
+	Code           string     `json:"Code"`
+	Description    string     `json:"Description,omitempty"`
+	VenueID        *int64     `json:"VenueID,omitempty"`
+	DiscountType   string     `json:"DiscountType"`
+	DiscountValue  float64    `json:"DiscountValue"`
+	FacilityTypes  []string   `json:"FacilityTypes,omitempty"`
+	StartsAt       *time.Time `json:"StartsAt,omitempty"`
+	EndsAt         *time.Time `json:"EndsAt,omitempty"`
+	MaxRedemptions *int       `json:"MaxRedemptions,omitempty"`
+	PerUserLimit   *int       `json:"PerUserLimit,omitempty"`
+	IsActive       bool       `json:"IsActive"`
+	CreatedAt      time.Time  `json:"CreatedAt"`
+}
+
+type promoCodeDB struct {
+	ID             int64      `json:"id"`
+	Code           string     `json:"code"`
+	Description    *string    `json:"description"`
+	VenueID        *int64     `json:"venue_id"`
+	DiscountType   string     `json:"discount_type"`
+	DiscountValue  float64    `json:"discount_value"`
+	FacilityTypes  []string   `json:"facility_types"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	MaxRedemptions *int       `json:"max_redemptions"`
+	PerUserLimit   *int       `json:"per_user_limit"`
+	IsActive       bool       `json:"is_active"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (p *promoCodeDB) toAPI() PromoCode {
+	return PromoCode{
+		ID:             p.ID,
+		Code:           p.Code,
+		Description:    deref(p.Description),
+		VenueID:        p.VenueID,
+		DiscountType:   p.DiscountType,
+		DiscountValue:  p.DiscountValue,
+		FacilityTypes:  p.FacilityTypes,
+		StartsAt:       p.StartsAt,
+		EndsAt:         p.EndsAt,
+		MaxRedemptions: p.MaxRedemptions,
+		PerUserLimit:   p.PerUserLimit,
+		IsActive:       p.IsActive,
+		CreatedAt:      p.CreatedAt,
+	}
+}
+
+// PromoCodeUpdate 优惠码部分更新（中文说明：编码、所属场馆与优惠方式不可修改）
+type PromoCodeUpdate struct {
+	Description    *string    `json:"description,omitempty"`
+	DiscountValue  *float64   `json:"discount_value,omitempty"`
+	FacilityTypes  *[]string  `json:"facility_types,omitempty"`
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	MaxRedemptions *int       `json:"max_redemptions,omitempty"`
+	PerUserLimit   *int       `json:"per_user_limit,omitempty"`
+	IsActive       *bool      `json:"is_active,omitempty"`
+}
+
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{2,31}$`)
+
+// NormalizePromoCode 优惠码统一大写并去除首尾空白
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate 校验优惠码（中文说明：比例优惠 0-100，固定金额为正，结束时间晚于开始时间，次数上限为正）
+func (p PromoCode) Validate() error {
+	if !promoCodePattern.MatchString(p.Code) {
+		return errors.New("code must be 3-32 chars of letters, digits, underscore or dash")
+	}
+	switch p.DiscountType {
+	case PromoPercent:
+		if p.DiscountValue <= 0 || p.DiscountValue > 100 {
+			return errors.New("percent discount_value must be between 0 and 100")
+		}
+	case PromoFixed:
+		if p.DiscountValue <= 0 {
+			return errors.New("fixed discount_value must be > 0")
+		}
+	default:
+		return errors.New("discount_type must be percent or fixed")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if p.MaxRedemptions != nil && *p.MaxRedemptions <= 0 {
+		return errors.New("max_redemptions must be > 0")
+	}
+	if p.PerUserLimit != nil && *p.PerUserLimit <= 0 {
+		return errors.New("per_user_limit must be > 0")
+	}
+	return nil
+}
+
+// apply 将部分更新合并到当前值（用于整体校验）
+func (u PromoCodeUpdate) apply(p PromoCode) PromoCode {
+	if u.Description != nil {
+		p.Description = *u.Description
+	}
+	if u.DiscountValue != nil {
+		p.DiscountValue = *u.DiscountValue
+	}
+	if u.FacilityTypes != nil {
+		p.FacilityTypes = *u.FacilityTypes
+	}
+	if u.StartsAt != nil {
+		p.StartsAt = u.StartsAt
+	}
+	if u.EndsAt != nil {
+		p.EndsAt = u.EndsAt
+	}
+	if u.MaxRedemptions != nil {
+		p.MaxRedemptions = u.MaxRedemptions
+	}
+	if u.PerUserLimit != nil {
+		p.PerUserLimit = u.PerUserLimit
+	}
+	if u.IsActive != nil {
+		p.IsActive = *u.IsActive
+	}
+	return p
+}
+
+func formatOptionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (p PromoCode) toPayload() map[string]interface{} {
+	var types interface{}
+	if len(p.FacilityTypes) > 0 {
+		types = p.FacilityTypes
+	}
+	return map[string]interface{}{
+		"code":            p.Code,
+		"description":     p.Description,
+		"venue_id":        p.VenueID,
+		"discount_type":   p.DiscountType,
+		"discount_value":  p.DiscountValue,
+		"facility_types":  types,
+		"starts_at":       formatOptionalTime(p.StartsAt),
+		"ends_at":         formatOptionalTime(p.EndsAt),
+		"max_redemptions": p.MaxRedemptions,
+		"per_user_limit":  p.PerUserLimit,
+		"is_active":       p.IsActive,
+	}
+}
+
+// ListPromoCodes 查询优惠码（按管理范围过滤，按创建时间倒序）
+func (d *DB) ListPromoCodes(ctx context.Context) ([]PromoCode, error) {
+	var out []promoCodeDB
+	err := d.Client.DB.From("promo_codes").
+		Select("*").
+		OrderBy("id", "desc").
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]PromoCode, 0, len(out))
+	for _, v := range out {
+		p := v.toAPI()
+		if optionalVenueInScope(ctx, p.VenueID) {
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
+
+func (d *DB) getPromoCode(ctx context.Context, column, value string) (*PromoCode, error) {
+	var out []promoCodeDB
+	err := d.Client.DB.From("promo_codes").
+		Select("*").
+		Eq(column, value).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("promo code not found")
+	}
+	p := out[0].toAPI()
+	if !optionalVenueInScope(ctx, p.VenueID) {
+		return nil, ErrOutOfScope
+	}
+	return &p, nil
+}
+
+// GetPromoCode 按 ID 查询优惠码
+func (d *DB) GetPromoCode(ctx context.Context, id int64) (*PromoCode, error) {
+	return d.getPromoCode(ctx, "id", fmt.Sprintf("%d", id))
+}
+
+// GetPromoCodeByCode 按编码查询优惠码（不区分大小写）
+func (d *DB) GetPromoCodeByCode(ctx context.Context, code string) (*PromoCode, error) {
+	return d.getPromoCode(ctx, "code", NormalizePromoCode(code))
+}
+
+// CreatePromoCode 新增优惠码
+func (d *DB) CreatePromoCode(ctx context.Context, p PromoCode) (*PromoCode, error) {
+	if !optionalVenueInScope(ctx, p.VenueID) {
+		return nil, ErrOutOfScope
+	}
+	p.Code = NormalizePromoCode(p.Code)
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	var out []promoCodeDB
+	if err := d.Client.DB.From("promo_codes").Insert(p.toPayload()).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create promo code")
+	}
+	created := out[0].toAPI()
+	d.audit(ctx, AuditPromoCodeCreate, EntityPromoCode, created.ID, nil, created)
+	return &created, nil
+}
+
+// UpdatePromoCode 更新优惠码（中文说明：已核销的记录不受影响）
+func (d *DB) UpdatePromoCode(ctx context.Context, id int64, upd PromoCodeUpdate) (*PromoCode, error) {
+	before, err := d.GetPromoCode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	merged := upd.apply(*before)
+	if err := merged.Validate(); err != nil {
+		return nil, err
+	}
+	payload := merged.toPayload()
+	delete(payload, "code")
+	delete(payload, "venue_id")
+	delete(payload, "discount_type")
+	var out []promoCodeDB
+	err = d.Client.DB.From("promo_codes").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("promo code not found")
+	}
+	updated := out[0].toAPI()
+	d.audit(ctx, AuditPromoCodeUpdate, EntityPromoCode, id, before, updated)
+	return &updated, nil
+}
+
+// PromoRedemption 优惠码核销记录
+type PromoRedemption struct {
+	ID             int64      `json:"ID"`
+	PromoCodeID    int64      `json:"PromoCodeID"`
+	BookingID      int64      `json:"BookingID"`
+	UserID         string     `json:"UserID"`
+	DiscountAmount float64    `json:"DiscountAmount"`
+	Status         string     `json:"Status"`
+	CreatedAt      time.Time  `json:"CreatedAt"`
+	ReversedAt     *time.Time `json:"ReversedAt,omitempty"`
+}
+
+type promoRedemptionDB struct {
+	ID             int64      `json:"id"`
+	PromoCodeID    int64      `json:"promo_code_id"`
+	BookingID      int64      `json:"booking_id"`
+	UserID         string     `json:"user_id"`
+	DiscountAmount float64    `json:"discount_amount"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReversedAt     *time.Time `json:"reversed_at"`
+}
+
+func (r *promoRedemptionDB) toAPI() PromoRedemption {
+	return PromoRedemption{
+		ID:             r.ID,
+		PromoCodeID:    r.PromoCodeID,
+		BookingID:      r.BookingID,
+		UserID:         r.UserID,
+		DiscountAmount: r.DiscountAmount,
+		Status:         r.Status,
+		CreatedAt:      r.CreatedAt,
+		ReversedAt:     r.ReversedAt,
+	}
+}
+
+// CountPromoRedemptions 统计有效核销次数（总数与指定用户）
+func (d *DB) CountPromoRedemptions(ctx context.Context, codeID int64, userID string) (total, mine int, err error) {
+	var out []promoRedemptionDB
+	err = d.Client.DB.From("promo_redemptions").
+		Select("id,user_id").
+		Eq("promo_code_id", fmt.Sprintf("%d", codeID)).
+		Eq("status", RedemptionActive).
+		Execute(&out)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, r := range out {
+		if r.UserID == userID {
+			mine++
+		}
+	}
+	return len(out), mine, nil
+}
+
+// RedeemPromoCode 记录核销（中文说明：由数据库函数按优惠码加锁校验次数上限，超限返回 ErrPromoLimitReached）
+func (d *DB) RedeemPromoCode(ctx context.Context, codeID, bookingID int64, userID string, discount float64) (*PromoRedemption, error) {
+	params := map[string]interface{}{
+		"p_code_id":    codeID,
+		"p_booking_id": bookingID,
+		"p_user_id":    userID,
+		"p_discount":   roundCents(discount),
+	}
+	var out []promoRedemptionDB
+	if err := d.Client.DB.Rpc("promo_redeem", params).ExecuteWithContext(ctx, &out); err != nil {
+		var reqErr *postgrest.RequestError
+		if errors.As(err, &reqErr) && reqErr.Code == errCodePromoLimit {
+			return nil, ErrPromoLimitReached
+		}
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to redeem promo code")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditPromoRedeem, EntityPromoRedemption, res.ID, nil, res)
+	return &res, nil
+}
+
+// ReversePromoRedemptions 撤销预约的有效核销（预约取消时调用，撤销后不再占用次数）
+func (d *DB) ReversePromoRedemptions(ctx context.Context, bookingID int64) error {
+	var out []promoRedemptionDB
+	err := d.Client.DB.From("promo_redemptions").
+		Update(map[string]interface{}{
+			"status":      RedemptionReversed,
+			"reversed_at": time.Now().UTC().Format(time.RFC3339),
+		}).
+		Eq("booking_id", fmt.Sprintf("%d", bookingID)).
+		Eq("status", RedemptionActive).
+		Execute(&out)
+	if err != nil {
+		return err
+	}
+	for _, r := range out {
+		d.audit(ctx, AuditPromoReverse, EntityPromoRedemption, r.ID, nil, r.toAPI())
+	}
+	return nil
+}
+
+// ListPromoRedemptions 查询优惠码的核销记录（按时间倒序）
+func (d *DB) ListPromoRedemptions(ctx context.Context, codeID int64) ([]PromoRedemption, error) {
+	var out []promoRedemptionDB
+	err := d.Client.DB.From("promo_redemptions").
+		Select("*").
+		OrderBy("id", "desc").
+		Eq("promo_code_id", fmt.Sprintf("%d", codeID)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]PromoRedemption, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
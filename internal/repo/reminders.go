@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	postgrest "github.com/nedpals/supabase-go/postgrest/pkg"
+)
+
+// ListBookingsStartingBetween 查询开场时间在 [from, to) 内的已确认预约（定时提醒使用，不受租户范围限制）
+func (d *DB) ListBookingsStartingBetween(ctx context.Context, from, to time.Time) ([]Booking, error) {
+	return d.listConfirmedBetween(ctx, "start_time", from, to)
+}
+
+// ListBookingsEndingBetween 查询结束时间在 [from, to) 内的已确认预约（评分邀请使用）
+func (d *DB) ListBookingsEndingBetween(ctx context.Context, from, to time.Time) ([]Booking, error) {
+	return d.listConfirmedBetween(ctx, "end_time", from, to)
+}
+
+func (d *DB) listConfirmedBetween(ctx context.Context, column string, from, to time.Time) ([]Booking, error) {
+	var out []bookingDB
+	err := d.Client.DB.From("bookings").
+		Select("*").
+		OrderBy(column, "asc").
+		Eq("status", "confirmed").
+		Gte(column, from.UTC().Format(time.RFC3339)).
+		Lt(column, to.UTC().Format(time.RFC3339)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Booking, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// ClaimBookingReminder 登记某预约的某种提醒（中文说明：已登记返回 false，保证每种提醒只发送一次）
+func (d *DB) ClaimBookingReminder(ctx context.Context, bookingID int64, kind string) (bool, error) {
+	var out []map[string]interface{}
+	err := d.Client.DB.From("booking_reminders").
+		Insert(map[string]interface{}{"booking_id": bookingID, "kind": kind}).
+		Execute(&out)
+	if err != nil {
+		var reqErr *postgrest.RequestError
+		if errors.As(err, &reqErr) && reqErr.Code == errCodeUniqueViolation {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseBookingReminder 撤销提醒登记（中文说明：通知入队失败时调用，下次扫描重试）
+func (d *DB) ReleaseBookingReminder(ctx context.Context, bookingID int64, kind string) error {
+	var out []map[string]interface{}
+	return d.Client.DB.From("booking_reminders").
+		Delete().
+		Eq("booking_id", fmt.Sprintf("%d", bookingID)).
+		Eq("kind", kind).
+		Execute(&out)
+}
+
+// AcquireSchedulerLease 获取或续约定时任务租约（中文说明：返回 true 表示本实例在 ttl 内负责执行该任务）
+func (d *DB) AcquireSchedulerLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	params := map[string]interface{}{
+		"p_name":        name,
+		"p_holder":      holder,
+		"p_ttl_seconds": int(ttl.Seconds()),
+	}
+	var ok bool
+	if err := d.Client.DB.Rpc("scheduler_acquire", params).ExecuteWithContext(ctx, &ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	postgrest "github.com/nedpals/supabase-go/postgrest/pkg"
+)
+
+// ErrOutOfScope 实体不属于当前租户范围（中文说明：对外按“未找到”处理，避免泄露其它场馆数据）
+var ErrOutOfScope = errors.New("not found")
+
+// Scope 租户（场馆）范围
+// 中文说明：平台管理员 All=true；场馆管理员仅能访问 VenueIDs；context 中未设置时视为不限（公开接口与后台任务）
+type Scope struct {
+	All      bool
+	VenueIDs []int64
+}
+
+type scopeKey struct{}
+
+// WithScope 在 context 中设置租户范围
+func WithScope(ctx context.Context, s Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, s)
+}
+
+// ScopeFromContext 读取租户范围
+func ScopeFromContext(ctx context.Context) Scope {
+	s, ok := ctx.Value(scopeKey{}).(Scope)
+	if !ok {
+		return Scope{All: true}
+	}
+	return s
+}
+
+// Allows 是否允许访问指定场馆
+func (s Scope) Allows(venueID int64) bool {
+	if s.All {
+		return true
+	}
+	for _, id := range s.VenueIDs {
+		if id == venueID {
+			return true
+		}
+	}
+	return false
+}
+
+// optionalVenueInScope 可选场馆归属的实体（会员方案、优惠码）：通用实体仅平台管理员可管理，场馆实体按租户范围判断
+func optionalVenueInScope(ctx context.Context, venueID *int64) bool {
+	s := ScopeFromContext(ctx)
+	if venueID == nil {
+		return s.All
+	}
+	return s.Allows(*venueID)
+}
+
+// venueIDStrings 供 PostgREST in 过滤使用；空范围返回一个不存在的 ID，保证查询结果为空
+func (s Scope) venueIDStrings() []string {
+	if len(s.VenueIDs) == 0 {
+		return []string{"0"}
+	}
+	res := make([]string, len(s.VenueIDs))
+	for i, id := range s.VenueIDs {
+		res[i] = strconv.FormatInt(id, 10)
+	}
+	return res
+}
+
+// applyScope 按租户范围过滤查询（中文说明：column 可为嵌入资源列，如 facilities.venue_id）
+func applyScope(ctx context.Context, q *postgrest.FilterRequestBuilder, column string) {
+	if s := ScopeFromContext(ctx); !s.All {
+		q.In(column, s.venueIDStrings())
+	}
+}
+
+// venueRef 嵌入查询返回的场馆引用
+type venueRef struct {
+	VenueID int64 `json:"venue_id"`
+}
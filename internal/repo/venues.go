@@ -0,0 +1,307 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认营业时间（场馆本地时间，与 026_venue_opening_hours.sql 的列默认值一致）
+const (
+	DefaultOpensAt  = "08:00"
+	DefaultClosesAt = "22:00"
+)
+
+// ErrInvalidOpeningHours 营业时间格式错误或开门不早于关门
+var ErrInvalidOpeningHours = errors.New("opening hours must be HH:MM with opens_at before closes_at")
+
+// Venue 场馆（组织）实体：设施、价格规则、预约策略与管理员均归属于场馆
+type Venue struct {
+	ID        int64     `json:"ID"`
+	Name      string    `json:"Name"`
+	Slug      string    `json:"Slug"`
+	Timezone  string    `json:"Timezone"`
+	OpensAt   string    `json:"OpensAt"`  // 场馆本地时间 HH:MM
+	ClosesAt  string    `json:"ClosesAt"` // 场馆本地时间 HH:MM，24:00 表示午夜
+	IsActive  bool      `json:"IsActive"`
+	CreatedAt time.Time `json:"CreatedAt"`
+}
+
+type venueDB struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	Timezone  string    `json:"timezone"`
+	OpensAt   string    `json:"opens_at"`
+	ClosesAt  string    `json:"closes_at"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (v *venueDB) toAPI() Venue {
+	return Venue{
+		ID:        v.ID,
+		Name:      v.Name,
+		Slug:      v.Slug,
+		Timezone:  v.Timezone,
+		OpensAt:   clockOrDefault(v.OpensAt, DefaultOpensAt),
+		ClosesAt:  clockOrDefault(v.ClosesAt, DefaultClosesAt),
+		IsActive:  v.IsActive,
+		CreatedAt: v.CreatedAt,
+	}
+}
+
+// clockOrDefault 将数据库 TIME（HH:MM:SS）转为 HH:MM，缺失时使用默认值
+func clockOrDefault(s, def string) string {
+	if len(s) < 5 {
+		return def
+	}
+	return s[:5]
+}
+
+// ParseClock 解析 HH:MM（00:00 至 24:00），返回自 0 点起的分钟数
+func ParseClock(s string) (int, bool) {
+	h, m, ok := strings.Cut(s, ":")
+	if !ok || len(h) != 2 || len(m) != 2 {
+		return 0, false
+	}
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hh < 0 || hh > 24 || mm < 0 || mm > 59 || (hh == 24 && mm != 0) {
+		return 0, false
+	}
+	return hh*60 + mm, true
+}
+
+// ValidOpeningHours 营业时间格式正确且开门早于关门
+func ValidOpeningHours(opensAt, closesAt string) bool {
+	o, ok1 := ParseClock(opensAt)
+	c, ok2 := ParseClock(closesAt)
+	return ok1 && ok2 && o < c
+}
+
+// Location 场馆时区（中文说明：未设置或无法识别时为 UTC）
+func (v Venue) Location() *time.Location {
+	if v.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(v.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// NewVenue 创建场馆参数（中文说明：Timezone、OpensAt、ClosesAt 为空时使用数据库默认值）
+type NewVenue struct {
+	Name     string
+	Slug     string
+	Timezone string
+	OpensAt  string
+	ClosesAt string
+}
+
+// VenueUpdate 场馆部分更新
+type VenueUpdate struct {
+	Name     *string `json:"name,omitempty"`
+	Slug     *string `json:"slug,omitempty"`
+	Timezone *string `json:"timezone,omitempty"`
+	OpensAt  *string `json:"opens_at,omitempty"`
+	ClosesAt *string `json:"closes_at,omitempty"`
+	IsActive *bool   `json:"is_active,omitempty"`
+}
+
+// ListVenues 查询场馆列表（受租户范围限制）
+func (d *DB) ListVenues(ctx context.Context) ([]Venue, error) {
+	q := d.Client.DB.From("venues").
+		Select("*").
+		OrderBy("id", "asc")
+	applyScope(ctx, &q.FilterRequestBuilder, "id")
+	var out []venueDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	res := make([]Venue, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// GetVenueByID 查询单个场馆
+func (d *DB) GetVenueByID(ctx context.Context, id int64) (*Venue, error) {
+	if !ScopeFromContext(ctx).Allows(id) {
+		return nil, ErrOutOfScope
+	}
+	var out []venueDB
+	err := d.Client.DB.From("venues").
+		Select("*").
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("venue not found")
+	}
+	v := out[0].toAPI()
+	return &v, nil
+}
+
+// CreateVenue 创建场馆
+func (d *DB) CreateVenue(ctx context.Context, nv NewVenue) (*Venue, error) {
+	opens, closes := nv.OpensAt, nv.ClosesAt
+	if opens == "" {
+		opens = DefaultOpensAt
+	}
+	if closes == "" {
+		closes = DefaultClosesAt
+	}
+	if !ValidOpeningHours(opens, closes) {
+		return nil, ErrInvalidOpeningHours
+	}
+	payload := map[string]interface{}{
+		"name":      nv.Name,
+		"slug":      nv.Slug,
+		"is_active": true,
+	}
+	if nv.Timezone != "" {
+		payload["timezone"] = nv.Timezone
+	}
+	if nv.OpensAt != "" {
+		payload["opens_at"] = nv.OpensAt
+	}
+	if nv.ClosesAt != "" {
+		payload["closes_at"] = nv.ClosesAt
+	}
+	var out []venueDB
+	if err := d.Client.DB.From("venues").Insert(payload).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create venue")
+	}
+	v := out[0].toAPI()
+	d.audit(ctx, AuditVenueCreate, EntityVenue, v.ID, nil, v)
+	return &v, nil
+}
+
+// UpdateVenue 更新场馆
+func (d *DB) UpdateVenue(ctx context.Context, id int64, upd VenueUpdate) (*Venue, error) {
+	before, err := d.GetVenueByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]interface{}{}
+	if upd.Name != nil {
+		payload["name"] = *upd.Name
+	}
+	if upd.Slug != nil {
+		payload["slug"] = *upd.Slug
+	}
+	if upd.Timezone != nil {
+		payload["timezone"] = *upd.Timezone
+	}
+	if upd.OpensAt != nil || upd.ClosesAt != nil {
+		opens, closes := before.OpensAt, before.ClosesAt
+		if upd.OpensAt != nil {
+			opens = *upd.OpensAt
+		}
+		if upd.ClosesAt != nil {
+			closes = *upd.ClosesAt
+		}
+		if !ValidOpeningHours(opens, closes) {
+			return nil, ErrInvalidOpeningHours
+		}
+		payload["opens_at"] = opens
+		payload["closes_at"] = closes
+	}
+	if upd.IsActive != nil {
+		payload["is_active"] = *upd.IsActive
+	}
+	if len(payload) == 0 {
+		return nil, errors.New("nothing to update")
+	}
+	var out []venueDB
+	err = d.Client.DB.From("venues").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("venue not found")
+	}
+	v := out[0].toAPI()
+	d.audit(ctx, AuditVenueUpdate, EntityVenue, id, before, v)
+	return &v, nil
+}
+
+// ListAdminVenueIDs 查询用户担任管理员的场馆
+func (d *DB) ListAdminVenueIDs(ctx context.Context, userID string) ([]int64, error) {
+	var out []venueRef
+	err := d.Client.DB.From("venue_admins").
+		Select("venue_id").
+		Eq("user_id", userID).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int64, len(out))
+	for i, v := range out {
+		res[i] = v.VenueID
+	}
+	return res, nil
+}
+
+// ListVenueAdmins 查询场馆管理员用户ID
+func (d *DB) ListVenueAdmins(ctx context.Context, venueID int64) ([]string, error) {
+	if !ScopeFromContext(ctx).Allows(venueID) {
+		return nil, ErrOutOfScope
+	}
+	var out []struct {
+		UserID string `json:"user_id"`
+	}
+	err := d.Client.DB.From("venue_admins").
+		Select("user_id").
+		Eq("venue_id", fmt.Sprintf("%d", venueID)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, len(out))
+	for i, v := range out {
+		res[i] = v.UserID
+	}
+	return res, nil
+}
+
+// AddVenueAdmin 授予场馆管理员
+func (d *DB) AddVenueAdmin(ctx context.Context, venueID int64, userID string) error {
+	payload := map[string]interface{}{"venue_id": venueID, "user_id": userID}
+	var out []interface{}
+	if err := d.Client.DB.From("venue_admins").Upsert(payload).Execute(&out); err != nil {
+		return err
+	}
+	d.audit(ctx, AuditVenueAdminAdd, EntityVenue, venueID, nil, payload)
+	return nil
+}
+
+// RemoveVenueAdmin 撤销场馆管理员
+func (d *DB) RemoveVenueAdmin(ctx context.Context, venueID int64, userID string) error {
+	var out []interface{}
+	err := d.Client.DB.From("venue_admins").
+		Delete().
+		Eq("venue_id", fmt.Sprintf("%d", venueID)).
+		Eq("user_id", userID).
+		Execute(&out)
+	if err != nil {
+		return err
+	}
+	d.audit(ctx, AuditVenueAdminRemove, EntityVenue, venueID, map[string]interface{}{"venue_id": venueID, "user_id": userID}, nil)
+	return nil
+}
@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+	postgrest "github.com/nedpals/supabase-go/postgrest/pkg"
+)
+
+// WalletProvider 钱包支付在 payments.provider 中的取值
+const WalletProvider = "wallet"
+
+// 钱包流水类型
+const (
+	WalletTopUp         = "topup"
+	WalletBookingCharge = "booking_charge"
+	WalletRefund        = "refund"
+	WalletAdjustment    = "adjustment"
+)
+
+// errCodeInsufficientBalance 数据库函数余额不足时抛出的 SQLSTATE（见 013_wallet.sql）
+const errCodeInsufficientBalance = "P0402"
+
+// ErrInsufficientBalance 钱包余额不足
+var ErrInsufficientBalance = errors.New("insufficient wallet balance")
+
+// WalletTransaction 钱包流水（中文说明：只追加不修改；Amount 入账为正、扣款为负，余额由流水累计得出）
+type WalletTransaction struct {
+	ID           int64     `json:"ID"`
+	UserID       string    `json:"UserID"`
+	Type         string    `json:"Type"`
+	Amount       float64   `json:"Amount"`
+	BalanceAfter float64   `json:"BalanceAfter"`
+	BookingID    *int64    `json:"BookingID,omitempty"`
+	Note         string    `json:"Note,omitempty"`
+	CreatedBy    string    `json:"CreatedBy,omitempty"`
+	CreatedAt    time.Time `json:"CreatedAt"`
+}
+
+type walletTransactionDB struct {
+	ID           int64     `json:"id"`
+	UserID       string    `json:"user_id"`
+	Type         string    `json:"type"`
+	Amount       float64   `json:"amount"`
+	BalanceAfter float64   `json:"balance_after"`
+	BookingID    *int64    `json:"booking_id"`
+	Note         *string   `json:"note"`
+	CreatedBy    *string   `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (w *walletTransactionDB) toAPI() WalletTransaction {
+	return WalletTransaction{
+		ID:           w.ID,
+		UserID:       w.UserID,
+		Type:         w.Type,
+		Amount:       w.Amount,
+		BalanceAfter: w.BalanceAfter,
+		BookingID:    w.BookingID,
+		Note:         deref(w.Note),
+		CreatedBy:    deref(w.CreatedBy),
+		CreatedAt:    w.CreatedAt,
+	}
+}
+
+// walletError 将数据库余额不足错误映射为 ErrInsufficientBalance
+func walletError(err error) error {
+	var reqErr *postgrest.RequestError
+	if errors.As(err, &reqErr) && reqErr.Code == errCodeInsufficientBalance {
+		return ErrInsufficientBalance
+	}
+	return err
+}
+
+// PostWalletTransaction 追加一条钱包流水（中文说明：由数据库函数按用户加锁计算余额，余额不足时拒绝扣款；函数仅 service_role 可执行，未配置时返回 ErrServiceRoleRequired）
+func (d *DB) PostWalletTransaction(ctx context.Context, tx WalletTransaction) (*WalletTransaction, error) {
+	if tx.Amount == 0 {
+		return nil, errors.New("amount must not be zero")
+	}
+	params := map[string]interface{}{
+		"p_user_id":    tx.UserID,
+		"p_type":       tx.Type,
+		"p_amount":     roundCents(tx.Amount),
+		"p_booking_id": tx.BookingID,
+		"p_note":       tx.Note,
+		"p_created_by": nil,
+	}
+	if actor := ActorFromContext(ctx); actor != "" {
+		params["p_created_by"] = actor
+	}
+	if d.service == nil {
+		return nil, ErrServiceRoleRequired
+	}
+	var out []walletTransactionDB
+	if err := d.service.DB.Rpc("wallet_post", params).ExecuteWithContext(ctx, &out); err != nil {
+		return nil, walletError(err)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to post wallet transaction")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditWalletTransaction, EntityWalletTransaction, res.ID, nil, res)
+	return &res, nil
+}
+
+// PayBookingFromWallet 用钱包余额支付占位预约（中文说明：扣款、确认预约、写入支付记录在同一数据库事务中完成；经 service_role 调用）
+func (d *DB) PayBookingFromWallet(ctx context.Context, bookingID int64, userID string, amount float64, currency string) (*Payment, error) {
+	params := map[string]interface{}{
+		"p_booking_id": bookingID,
+		"p_user_id":    userID,
+		"p_amount":     roundCents(amount),
+		"p_currency":   currency,
+	}
+	if d.service == nil {
+		return nil, ErrServiceRoleRequired
+	}
+	var out []paymentDB
+	if err := d.service.DB.Rpc("wallet_pay_booking", params).ExecuteWithContext(ctx, &out); err != nil {
+		return nil, walletError(err)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("wallet payment failed")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditPaymentCreate, EntityPayment, res.ID, nil, res)
+	// 预约在数据库函数中确认，读取确认后的预约发布事件
+	if d.publisher != nil {
+		if b, err := d.GetBookingByID(ctx, bookingID); err == nil {
+			d.publish(ctx, events.BookingConfirmed, *b, nil, bookingTarget(*b))
+		}
+	}
+	return &res, nil
+}
+
+// GetWalletBalance 查询钱包余额（中文说明：取最新流水的 balance_after，无流水时为 0）
+func (d *DB) GetWalletBalance(ctx context.Context, userID string) (float64, error) {
+	var out []walletTransactionDB
+	err := d.Client.DB.From("wallet_transactions").
+		Select("balance_after").
+		OrderBy("id", "desc").
+		Limit(1).
+		Eq("user_id", userID).
+		Execute(&out)
+	if err != nil {
+		return 0, err
+	}
+	if len(out) == 0 {
+		return 0, nil
+	}
+	return out[0].BalanceAfter, nil
+}
+
+// ListWalletTransactions 查询钱包流水（按时间倒序）
+func (d *DB) ListWalletTransactions(ctx context.Context, userID string, limit int) ([]WalletTransaction, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	var out []walletTransactionDB
+	err := d.Client.DB.From("wallet_transactions").
+		Select("*").
+		OrderBy("id", "desc").
+		Limit(limit).
+		Eq("user_id", userID).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]WalletTransaction, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// walletRefundRef 钱包退款流水在 payment_refunds.provider_ref 中的引用
+func walletRefundRef(txID int64) string {
+	return fmt.Sprintf("wallet_tx_%d", txID)
+}
+
+// RefundToWallet 将退款记入钱包并返回退款引用
+func (d *DB) RefundToWallet(ctx context.Context, p Payment, amount float64, note string) (string, error) {
+	bookingID := p.BookingID
+	tx, err := d.PostWalletTransaction(ctx, WalletTransaction{
+		UserID:    p.UserID,
+		Type:      WalletRefund,
+		Amount:    amount,
+		BookingID: &bookingID,
+		Note:      note,
+	})
+	if err != nil {
+		return "", err
+	}
+	return walletRefundRef(tx.ID), nil
+}
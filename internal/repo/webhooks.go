@@ -0,0 +1,505 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Webhook 投递状态
+const (
+	WebhookPending   = "pending"
+	WebhookSucceeded = "succeeded"
+	WebhookFailed    = "failed" // 超过重试次数
+)
+
+var (
+	// ErrWebhookEndpointNotFound Webhook 地址不存在
+	ErrWebhookEndpointNotFound = errors.New("webhook endpoint not found")
+	// ErrWebhookDeliveryNotFound Webhook 投递记录不存在
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// WebhookEndpoint 对外回调地址（中文说明：Secret 仅在创建时返回，列表与审计中置空）
+type WebhookEndpoint struct {
+	ID          int64     `json:"ID"`
+	URL         string    `json:"URL"`
+	Secret      string    `json:"Secret,omitempty"`
+	EventTypes  []string  `json:"EventTypes"`
+	Description string    `json:"Description,omitempty"`
+	IsActive    bool      `json:"IsActive"`
+	CreatedBy   string    `json:"CreatedBy,omitempty"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+}
+
+type webhookEndpointDB struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret"`
+	EventTypes  []string  `json:"event_types"`
+	Description *string   `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	CreatedBy   *string   `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (e *webhookEndpointDB) toAPI() WebhookEndpoint {
+	return WebhookEndpoint{
+		ID:          e.ID,
+		URL:         e.URL,
+		Secret:      e.Secret,
+		EventTypes:  e.EventTypes,
+		Description: deref(e.Description),
+		IsActive:    e.IsActive,
+		CreatedBy:   deref(e.CreatedBy),
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+// redacted 去掉签名密钥的副本
+func (e WebhookEndpoint) redacted() WebhookEndpoint {
+	e.Secret = ""
+	return e
+}
+
+// WebhookEndpointUpdate Webhook 地址更新（中文说明：nil 字段不修改）
+type WebhookEndpointUpdate struct {
+	URL         *string   `json:"url,omitempty"`
+	EventTypes  *[]string `json:"event_types,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	IsActive    *bool     `json:"is_active,omitempty"`
+}
+
+// IsEmpty 是否没有任何更新字段
+func (u WebhookEndpointUpdate) IsEmpty() bool {
+	return u.URL == nil && u.EventTypes == nil && u.Description == nil && u.IsActive == nil
+}
+
+// WebhookDelivery 一次事件投递（中文说明：Payload 为请求体；Attempts 为已尝试次数，AttemptLog 仅查询单条时填充）
+type WebhookDelivery struct {
+	ID             int64            `json:"ID"`
+	EndpointID     int64            `json:"EndpointID"`
+	EventID        string           `json:"EventID"`
+	EventType      string           `json:"EventType"`
+	Payload        json.RawMessage  `json:"Payload"`
+	Status         string           `json:"Status"`
+	Attempts       int              `json:"Attempts"`
+	NextAttemptAt  time.Time        `json:"NextAttemptAt"`
+	LastStatusCode *int             `json:"LastStatusCode,omitempty"`
+	LastError      string           `json:"LastError,omitempty"`
+	ReplayOf       *int64           `json:"ReplayOf,omitempty"`
+	CreatedAt      time.Time        `json:"CreatedAt"`
+	DeliveredAt    *time.Time       `json:"DeliveredAt,omitempty"`
+	AttemptLog     []WebhookAttempt `json:"AttemptLog,omitempty"`
+}
+
+type webhookDeliveryDB struct {
+	ID             int64           `json:"id"`
+	EndpointID     int64           `json:"endpoint_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code"`
+	LastError      *string         `json:"last_error"`
+	ReplayOf       *int64          `json:"replay_of"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+func (w *webhookDeliveryDB) toAPI() WebhookDelivery {
+	return WebhookDelivery{
+		ID:             w.ID,
+		EndpointID:     w.EndpointID,
+		EventID:        w.EventID,
+		EventType:      w.EventType,
+		Payload:        w.Payload,
+		Status:         w.Status,
+		Attempts:       w.Attempts,
+		NextAttemptAt:  w.NextAttemptAt,
+		LastStatusCode: w.LastStatusCode,
+		LastError:      deref(w.LastError),
+		ReplayOf:       w.ReplayOf,
+		CreatedAt:      w.CreatedAt,
+		DeliveredAt:    w.DeliveredAt,
+	}
+}
+
+func deliveriesToAPI(out []webhookDeliveryDB) []WebhookDelivery {
+	res := make([]WebhookDelivery, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res
+}
+
+// WebhookAttempt 一次投递尝试（中文说明：StatusCode 为空表示未收到响应，如连接失败或超时）
+type WebhookAttempt struct {
+	DeliveryID int64     `json:"DeliveryID"`
+	Attempt    int       `json:"Attempt"`
+	StatusCode *int      `json:"StatusCode,omitempty"`
+	Error      string    `json:"Error,omitempty"`
+	DurationMs int       `json:"DurationMs"`
+	CreatedAt  time.Time `json:"CreatedAt"`
+}
+
+type webhookAttemptDB struct {
+	DeliveryID int64     `json:"delivery_id"`
+	Attempt    int       `json:"attempt"`
+	StatusCode *int      `json:"status_code"`
+	Error      *string   `json:"error"`
+	DurationMs int       `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreateWebhookEndpoint 登记 Webhook 地址（平台管理员）
+func (d *DB) CreateWebhookEndpoint(ctx context.Context, e WebhookEndpoint) (*WebhookEndpoint, error) {
+	// Webhook 接收全部场馆的事件，仅平台管理员可管理
+	if !ScopeFromContext(ctx).All {
+		return nil, ErrOutOfScope
+	}
+	payload := map[string]interface{}{
+		"url":         e.URL,
+		"secret":      e.Secret,
+		"event_types": e.EventTypes,
+		"description": nil,
+		"is_active":   true,
+		"created_by":  nil,
+	}
+	if e.Description != "" {
+		payload["description"] = e.Description
+	}
+	if actor := ActorFromContext(ctx); actor != "" {
+		payload["created_by"] = actor
+	}
+	var out []webhookEndpointDB
+	if err := d.Client.DB.From("webhook_endpoints").Insert(payload).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to create webhook endpoint")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditWebhookCreate, EntityWebhookEndpoint, res.ID, nil, res.redacted())
+	return &res, nil
+}
+
+// ListWebhookEndpoints 查询全部 Webhook 地址（平台管理员，不含密钥）
+func (d *DB) ListWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint, error) {
+	if !ScopeFromContext(ctx).All {
+		return nil, ErrOutOfScope
+	}
+	var out []webhookEndpointDB
+	err := d.Client.DB.From("webhook_endpoints").
+		Select("*").
+		OrderBy("id", "asc").
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]WebhookEndpoint, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI().redacted()
+	}
+	return res, nil
+}
+
+// getWebhookEndpoint 查询单个 Webhook 地址（含密钥）
+func (d *DB) getWebhookEndpoint(ctx context.Context, id int64) (*WebhookEndpoint, error) {
+	var out []webhookEndpointDB
+	err := d.Client.DB.From("webhook_endpoints").
+		Select("*").
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrWebhookEndpointNotFound
+	}
+	res := out[0].toAPI()
+	return &res, nil
+}
+
+// UpdateWebhookEndpoint 修改 Webhook 地址、订阅事件或启用状态（平台管理员）
+func (d *DB) UpdateWebhookEndpoint(ctx context.Context, id int64, upd WebhookEndpointUpdate) (*WebhookEndpoint, error) {
+	if !ScopeFromContext(ctx).All {
+		return nil, ErrOutOfScope
+	}
+	before, err := d.getWebhookEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]interface{}{}
+	if upd.URL != nil {
+		payload["url"] = *upd.URL
+	}
+	if upd.EventTypes != nil {
+		payload["event_types"] = *upd.EventTypes
+	}
+	if upd.Description != nil {
+		payload["description"] = *upd.Description
+	}
+	if upd.IsActive != nil {
+		payload["is_active"] = *upd.IsActive
+	}
+	var out []webhookEndpointDB
+	err = d.Client.DB.From("webhook_endpoints").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrWebhookEndpointNotFound
+	}
+	res := out[0].toAPI().redacted()
+	d.audit(ctx, AuditWebhookUpdate, EntityWebhookEndpoint, id, before.redacted(), res)
+	return &res, nil
+}
+
+// DeleteWebhookEndpoint 删除 Webhook 地址（投递记录随之删除）
+func (d *DB) DeleteWebhookEndpoint(ctx context.Context, id int64) error {
+	if !ScopeFromContext(ctx).All {
+		return ErrOutOfScope
+	}
+	before, err := d.getWebhookEndpoint(ctx, id)
+	if err != nil {
+		return err
+	}
+	var out []interface{}
+	if err := d.Client.DB.From("webhook_endpoints").Delete().Eq("id", fmt.Sprintf("%d", id)).Execute(&out); err != nil {
+		return err
+	}
+	d.audit(ctx, AuditWebhookDelete, EntityWebhookEndpoint, id, before.redacted(), nil)
+	return nil
+}
+
+// ListSubscribedWebhookEndpoints 查询订阅了某事件类型的启用地址（事件发布时使用，不受租户范围限制）
+func (d *DB) ListSubscribedWebhookEndpoints(ctx context.Context, eventType string) ([]WebhookEndpoint, error) {
+	var out []webhookEndpointDB
+	err := d.Client.DB.From("webhook_endpoints").
+		Select("id").
+		Eq("is_active", "true").
+		Cs("event_types", []string{eventType}).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]WebhookEndpoint, len(out))
+	for i, v := range out {
+		res[i] = v.toAPI()
+	}
+	return res, nil
+}
+
+// GetWebhookEndpointsByIDs 批量查询 Webhook 地址（含密钥，投递任务签名使用）
+func (d *DB) GetWebhookEndpointsByIDs(ctx context.Context, ids []int64) (map[int64]WebhookEndpoint, error) {
+	res := map[int64]WebhookEndpoint{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = fmt.Sprintf("%d", id)
+	}
+	var out []webhookEndpointDB
+	if err := d.Client.DB.From("webhook_endpoints").Select("*").In("id", keys).Execute(&out); err != nil {
+		return nil, err
+	}
+	for _, v := range out {
+		res[v.ID] = v.toAPI()
+	}
+	return res, nil
+}
+
+// EnqueueWebhookDeliveries 批量写入待投递记录
+func (d *DB) EnqueueWebhookDeliveries(ctx context.Context, deliveries []WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	rows := make([]map[string]interface{}, len(deliveries))
+	for i, w := range deliveries {
+		rows[i] = map[string]interface{}{
+			"endpoint_id": w.EndpointID,
+			"event_id":    w.EventID,
+			"event_type":  w.EventType,
+			"payload":     w.Payload,
+			"replay_of":   w.ReplayOf,
+		}
+	}
+	var out []webhookDeliveryDB
+	return d.Client.DB.From("webhook_deliveries").Insert(rows).Execute(&out)
+}
+
+// ClaimWebhookDeliveries 领取到期的待投递记录（中文说明：领取即计一次尝试，并在租期内对其它实例不可见）
+func (d *DB) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	params := map[string]interface{}{
+		"p_limit":         limit,
+		"p_lease_seconds": int(lease.Seconds()),
+	}
+	var out []webhookDeliveryDB
+	if err := d.Client.DB.Rpc("webhook_deliveries_claim", params).ExecuteWithContext(ctx, &out); err != nil {
+		return nil, err
+	}
+	return deliveriesToAPI(out), nil
+}
+
+// RecordWebhookAttempt 记录一次投递尝试
+func (d *DB) RecordWebhookAttempt(ctx context.Context, a WebhookAttempt) error {
+	payload := map[string]interface{}{
+		"delivery_id": a.DeliveryID,
+		"attempt":     a.Attempt,
+		"status_code": a.StatusCode,
+		"error":       nil,
+		"duration_ms": a.DurationMs,
+	}
+	if a.Error != "" {
+		payload["error"] = a.Error
+	}
+	var out []webhookAttemptDB
+	return d.Client.DB.From("webhook_delivery_attempts").Insert(payload).Execute(&out)
+}
+
+// MarkWebhookDelivered 标记投递成功
+func (d *DB) MarkWebhookDelivered(ctx context.Context, id int64, statusCode int, at time.Time) error {
+	var out []webhookDeliveryDB
+	return d.Client.DB.From("webhook_deliveries").
+		Update(map[string]interface{}{
+			"status":           WebhookSucceeded,
+			"last_status_code": statusCode,
+			"last_error":       nil,
+			"delivered_at":     at.UTC().Format(time.RFC3339),
+		}).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+}
+
+// MarkWebhookRetry 记录投递失败（中文说明：next 为 nil 表示不再重试，标记为 failed）
+func (d *DB) MarkWebhookRetry(ctx context.Context, id int64, statusCode *int, lastError string, next *time.Time) error {
+	payload := map[string]interface{}{
+		"last_status_code": statusCode,
+		"last_error":       lastError,
+	}
+	if next == nil {
+		payload["status"] = WebhookFailed
+	} else {
+		payload["next_attempt_at"] = next.UTC().Format(time.RFC3339)
+	}
+	var out []webhookDeliveryDB
+	return d.Client.DB.From("webhook_deliveries").
+		Update(payload).
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+}
+
+// WebhookDeliveryFilter 投递记录查询条件（中文说明：零值字段表示不过滤）
+type WebhookDeliveryFilter struct {
+	EndpointID int64
+	EventType  string
+	Status     string
+	Limit      int
+}
+
+// ListWebhookDeliveries 查询最近的投递记录（平台管理员，按创建时间倒序）
+func (d *DB) ListWebhookDeliveries(ctx context.Context, f WebhookDeliveryFilter) ([]WebhookDelivery, error) {
+	if !ScopeFromContext(ctx).All {
+		return nil, ErrOutOfScope
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	q := d.Client.DB.From("webhook_deliveries").
+		Select("*").
+		OrderBy("created_at", "desc").
+		Limit(limit)
+	if f.EndpointID > 0 {
+		q.Eq("endpoint_id", fmt.Sprintf("%d", f.EndpointID))
+	}
+	if f.EventType != "" {
+		q.Eq("event_type", f.EventType)
+	}
+	if f.Status != "" {
+		q.Eq("status", f.Status)
+	}
+	var out []webhookDeliveryDB
+	if err := q.Execute(&out); err != nil {
+		return nil, err
+	}
+	return deliveriesToAPI(out), nil
+}
+
+// GetWebhookDelivery 查询单条投递记录及全部尝试（平台管理员）
+func (d *DB) GetWebhookDelivery(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	if !ScopeFromContext(ctx).All {
+		return nil, ErrOutOfScope
+	}
+	var out []webhookDeliveryDB
+	err := d.Client.DB.From("webhook_deliveries").
+		Select("*").
+		Eq("id", fmt.Sprintf("%d", id)).
+		Execute(&out)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	var attempts []webhookAttemptDB
+	err = d.Client.DB.From("webhook_delivery_attempts").
+		Select("*").
+		OrderBy("attempt", "asc").
+		Eq("delivery_id", fmt.Sprintf("%d", id)).
+		Execute(&attempts)
+	if err != nil {
+		return nil, err
+	}
+	res := out[0].toAPI()
+	res.AttemptLog = make([]WebhookAttempt, len(attempts))
+	for i, a := range attempts {
+		res.AttemptLog[i] = WebhookAttempt{
+			DeliveryID: a.DeliveryID,
+			Attempt:    a.Attempt,
+			StatusCode: a.StatusCode,
+			Error:      deref(a.Error),
+			DurationMs: a.DurationMs,
+			CreatedAt:  a.CreatedAt,
+		}
+	}
+	return &res, nil
+}
+
+// ReplayWebhookDelivery 重放一次投递（中文说明：以相同的事件 ID 与请求体新建投递记录，原记录及其尝试保留不变）
+func (d *DB) ReplayWebhookDelivery(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	orig, err := d.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]interface{}{
+		"endpoint_id": orig.EndpointID,
+		"event_id":    orig.EventID,
+		"event_type":  orig.EventType,
+		"payload":     orig.Payload,
+		"replay_of":   orig.ID,
+	}
+	var out []webhookDeliveryDB
+	if err := d.Client.DB.From("webhook_deliveries").Insert(payload).Execute(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("failed to replay webhook delivery")
+	}
+	res := out[0].toAPI()
+	d.audit(ctx, AuditWebhookReplay, EntityWebhookDelivery, res.ID, nil, map[string]interface{}{
+		"replay_of":   orig.ID,
+		"endpoint_id": res.EndpointID,
+		"event_id":    res.EventID,
+	})
+	return &res, nil
+}
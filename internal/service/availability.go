@@ -2,6 +2,8 @@ package service
 
 import (
     "time"
+
+    "github.com/Juny09/sport_backend/internal/repo"
 )
 
 // TimeRange 表示一个时间段
@@ -10,12 +12,21 @@ type TimeRange struct {
     End   time.Time
 }
 
-// openingHours 返回默认营业时间（中文说明：简化默认 08:00-22:00，可后续配置化）
-func OpeningHoursForDay(day time.Time) TimeRange {
+// OpeningHoursForDay 返回场馆某天的营业时间（中文说明：day 的年月日按场馆时区解释，开门与关门为场馆本地时间；
+// 夏令时切换日按本地时钟计算）
+func OpeningHoursForDay(day time.Time, v repo.Venue) TimeRange {
+    opens, ok := repo.ParseClock(v.OpensAt)
+    if !ok {
+        opens, _ = repo.ParseClock(repo.DefaultOpensAt)
+    }
+    closes, ok := repo.ParseClock(v.ClosesAt)
+    if !ok || closes <= opens {
+        closes, _ = repo.ParseClock(repo.DefaultClosesAt)
+    }
     y, m, d := day.Date()
-    loc := time.UTC
-    start := time.Date(y, m, d, 8, 0, 0, 0, loc)
-    end := time.Date(y, m, d, 22, 0, 0, 0, loc)
+    loc := v.Location()
+    start := time.Date(y, m, d, 0, opens, 0, 0, loc)
+    end := time.Date(y, m, d, 0, closes, 0, 0, loc)
     return TimeRange{Start: start, End: end}
 }
 
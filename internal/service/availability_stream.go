@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// UnitAvailability 单元某天的空闲时段（字段与 GET /availability 的结果一致）
+type UnitAvailability struct {
+	UnitID int64       `json:"unit_id"`
+	Label  string      `json:"label"`
+	Free   []TimeRange `json:"free"`
+}
+
+// AvailabilityWatch 跟踪一组单元某天的空闲时段（中文说明：供实时推送使用；根据事件找出受影响的单元，
+// 重新计算后只返回与上次结果不同的单元，避免重复推送）
+type AvailabilityWatch struct {
+	db         *repo.DB
+	units      map[int64]repo.ResourceUnit
+	order      []int64
+	byFacility map[int64][]int64
+	day        time.Time
+	dayStart   time.Time
+	dayEnd     time.Time
+	minDur     time.Duration
+	last       map[int64]string
+	windows    map[int64]TimeRange // 各单元按场馆时区计算的营业窗口，Refresh 时更新
+}
+
+// NewAvailabilityWatch 创建空闲时段跟踪（day 的年月日按各单元所属场馆时区取当天，首次 Refresh 前按 UTC 日期筛选事件）
+func NewAvailabilityWatch(db *repo.DB, units []repo.ResourceUnit, day time.Time, minDur time.Duration) *AvailabilityWatch {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	w := &AvailabilityWatch{
+		db:         db,
+		units:      map[int64]repo.ResourceUnit{},
+		byFacility: map[int64][]int64{},
+		day:        day,
+		dayStart:   start,
+		dayEnd:     start.Add(24 * time.Hour),
+		minDur:     minDur,
+		last:       map[int64]string{},
+		windows:    map[int64]TimeRange{},
+	}
+	for _, u := range units {
+		w.units[u.ID] = u
+		w.order = append(w.order, u.ID)
+		w.byFacility[u.FacilityID] = append(w.byFacility[u.FacilityID], u.ID)
+	}
+	return w
+}
+
+// Affected 事件影响的本视图单元（中文说明：单元匹配或设施级封场覆盖，且时段与该单元当天的营业窗口相交）
+func (w *AvailabilityWatch) Affected(e events.Event) []int64 {
+	seen := map[int64]bool{}
+	var ids []int64
+	add := func(id int64, t events.Target) {
+		start, end := w.dayStart, w.dayEnd
+		if oh, ok := w.windows[id]; ok {
+			start, end = oh.Start, oh.End
+		}
+		if !seen[id] && t.Overlaps(start, end) {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, t := range e.Targets {
+		if _, ok := w.units[t.UnitID]; ok {
+			add(t.UnitID, t)
+		}
+		for _, id := range w.byFacility[t.FacilityID] {
+			add(id, t)
+		}
+	}
+	return ids
+}
+
+// Refresh 重新计算单元的空闲时段，返回有变化的单元（unitIDs 为空时计算全部单元）
+func (w *AvailabilityWatch) Refresh(ctx context.Context, unitIDs []int64) ([]UnitAvailability, error) {
+	if len(unitIDs) == 0 {
+		unitIDs = w.order
+	}
+	changed := []UnitAvailability{}
+	for _, id := range unitIDs {
+		u, ok := w.units[id]
+		if !ok {
+			continue
+		}
+		oh, ok := w.windows[id]
+		if !ok {
+			v, err := unitVenue(ctx, w.db, u)
+			if err != nil {
+				return nil, err
+			}
+			oh = OpeningHoursForDay(w.day, *v)
+			w.windows[id] = oh
+		}
+		free, err := unitFreeRangesWithin(ctx, w.db, u, oh, w.minDur)
+		if err != nil {
+			return nil, err
+		}
+		key, err := json.Marshal(free)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := w.last[id]; ok && prev == string(key) {
+			continue
+		}
+		w.last[id] = string(key)
+		changed = append(changed, UnitAvailability{UnitID: u.ID, Label: u.Label, Free: free})
+	}
+	return changed, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 测试事件路由：单元匹配、设施级封场覆盖同设施全部单元、其它日期与其它单元忽略
+func TestAvailabilityWatchAffected(t *testing.T) {
+	day := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	units := []repo.ResourceUnit{
+		{ID: 1, FacilityID: 10},
+		{ID: 2, FacilityID: 10},
+		{ID: 3, FacilityID: 20},
+	}
+	w := NewAvailabilityWatch(nil, units, day, 30*time.Minute)
+	at := func(h int) (time.Time, time.Time) {
+		return day.Add(time.Duration(h) * time.Hour), day.Add(time.Duration(h+1) * time.Hour)
+	}
+
+	s, e := at(10)
+	got := w.Affected(events.Event{Targets: []events.Target{{UnitID: 3, Start: s, End: e}}})
+	if len(got) != 1 || got[0] != 3 {
+		t.Fatalf("expected unit 3, got %v", got)
+	}
+
+	got = w.Affected(events.Event{Targets: []events.Target{{FacilityID: 10, Start: s, End: e}, {UnitID: 1, Start: s, End: e}}})
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("expected units 1 and 2 once each, got %v", got)
+	}
+
+	s2, e2 := at(34) // 次日
+	if got := w.Affected(events.Event{Targets: []events.Target{{UnitID: 1, Start: s2, End: e2}}}); len(got) != 0 {
+		t.Fatalf("expected other day ignored, got %v", got)
+	}
+	if got := w.Affected(events.Event{Targets: []events.Target{{UnitID: 99, Start: s, End: e}}}); len(got) != 0 {
+		t.Fatalf("expected unknown unit ignored, got %v", got)
+	}
+
+	// 改签到其它日期：旧时段仍在当天，需要刷新
+	got = w.Affected(events.Event{Targets: []events.Target{{UnitID: 2, Start: s, End: e}, {UnitID: 2, Start: s2, End: e2}}})
+	if len(got) != 1 || got[0] != 2 {
+		t.Fatalf("expected unit 2 from previous slot, got %v", got)
+	}
+}
+
+// 测试按场馆时区的营业窗口筛选事件：UTC 次日凌晨仍属于 UTC+8 场馆的当天
+func TestAvailabilityWatchAffectedVenueWindow(t *testing.T) {
+	day := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	w := NewAvailabilityWatch(nil, []repo.ResourceUnit{{ID: 1, FacilityID: 10}}, day, 30*time.Minute)
+	oh := OpeningHoursForDay(day, repo.Venue{Timezone: "Asia/Shanghai", OpensAt: "08:00", ClosesAt: "22:00"})
+	if want := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC); !oh.Start.Equal(want) {
+		t.Fatalf("expected opening at %v, got %v", want, oh.Start)
+	}
+	w.windows[1] = oh
+
+	early := day.Add(-time.Hour) // 当地 07:00，营业前
+	if got := w.Affected(events.Event{Targets: []events.Target{{UnitID: 1, Start: early, End: day}}}); len(got) != 0 {
+		t.Fatalf("expected slot before opening ignored, got %v", got)
+	}
+	s := day.Add(10 * time.Hour) // 当地 18:00
+	if got := w.Affected(events.Event{Targets: []events.Target{{FacilityID: 10, Start: s, End: s.Add(time.Hour)}}}); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("expected unit 1, got %v", got)
+	}
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 封场影响处理方式
+const (
+	ImpactCancel   = "cancel"
+	ImpactRelocate = "relocate"
+)
+
+// 处理结果
+const (
+	OutcomeCancelled  = "cancelled"
+	OutcomeRelocated  = "relocated"
+	OutcomeUnresolved = "unresolved"
+)
+
+// ImpactOutcome 单个受影响预约的处理结果
+type ImpactOutcome struct {
+	BookingID int64
+	UserID    string
+	Outcome   string
+	FromUnit  int64
+	ToUnit    int64  `json:",omitempty"`
+	Error     string `json:",omitempty"`
+	// RefundAmount 取消时全额退回的金额；RefundError 非空表示已取消但退款失败，需管理员手动处理
+	RefundAmount float64 `json:",omitempty"`
+	RefundError  string  `json:",omitempty"`
+}
+
+// ImpactRequest 封场影响处理参数
+type ImpactRequest struct {
+	Action string // cancel 或 relocate
+	Reason string // 取消原因（写入 cancel_reason 并通知用户）
+	// CancelIfNoUnit 迁移时找不到空闲单元则取消
+	CancelIfNoUnit bool
+	// BookingIDs 仅处理指定预约；为空表示处理全部受影响预约
+	BookingIDs []int64
+}
+
+// resolveOptions 单个预约的处理参数（中文说明：封场与停用共用，通知类型不同；Provider 用于取消时退款）
+type resolveOptions struct {
+	Reason         string
+	CancelIfNoUnit bool
+	CancelKind     string
+	RelocateKind   string
+	Provider       payment.Provider
+}
+
+// FindRelocationUnit 为预约寻找同设施下同时段空闲的其它单元（中文说明：与 /availability 相同的空闲计算逻辑）
+func FindRelocationUnit(ctx context.Context, db *repo.DB, b repo.Booking) (*repo.ResourceUnit, error) {
+	current, err := db.GetResourceUnitByID(ctx, b.ResourceUnitID)
+	if err != nil {
+		return nil, err
+	}
+	units, err := db.ListUnitsByFacility(ctx, current.FacilityID)
+	if err != nil {
+		return nil, err
+	}
+	return findFreeUnit(ctx, db, b, units, nil)
+}
+
+// findFreeUnit 在候选单元中寻找能容纳该预约时段的激活单元（中文说明：按各单元所属场馆时区取预约开始当天的营业时间；
+// reserved 为已计划迁入各单元的时段，视同占用）
+func findFreeUnit(ctx context.Context, db *repo.DB, b repo.Booking, candidates []repo.ResourceUnit, reserved map[int64][]TimeRange) (*repo.ResourceUnit, error) {
+	hours := map[int64]TimeRange{} // 按场馆缓存营业窗口
+	for _, u := range candidates {
+		if u.ID == b.ResourceUnitID || !u.IsActive || overlapsAny(reserved[u.ID], b.StartTime, b.EndTime) {
+			continue
+		}
+		oh, ok := hours[u.VenueID]
+		if !ok || u.VenueID == 0 {
+			v, err := unitVenue(ctx, db, u)
+			if err != nil {
+				return nil, err
+			}
+			oh = OpeningHoursForDay(b.StartTime.In(v.Location()), *v)
+			hours[v.ID] = oh
+		}
+		free, err := unitFreeRangesWithin(ctx, db, u, oh, b.EndTime.Sub(b.StartTime))
+		if err != nil {
+			return nil, err
+		}
+		if fits(free, b.StartTime, b.EndTime) {
+			unit := u
+			return &unit, nil
+		}
+	}
+	return nil, nil
+}
+
+// overlapsAny 时段 [start, end) 是否与任一区间相交
+func overlapsAny(ranges []TimeRange, start, end time.Time) bool {
+	for _, r := range ranges {
+		if r.Start.Before(end) && start.Before(r.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// relocateBooking 将预约迁移到指定单元并通知用户
+func relocateBooking(ctx context.Context, db *repo.DB, notifier notify.Notifier, b repo.Booking, unit repo.ResourceUnit, opt resolveOptions) ImpactOutcome {
+	o := ImpactOutcome{BookingID: b.ID, UserID: b.UserID, FromUnit: b.ResourceUnitID, Outcome: OutcomeUnresolved}
+	if _, err := db.MoveBooking(ctx, b.ID, unit.ID); err != nil {
+		o.Error = err.Error()
+		return o
+	}
+	o.Outcome = OutcomeRelocated
+	o.ToUnit = unit.ID
+	notifyBooking(ctx, notifier, b, opt.RelocateKind, map[string]interface{}{
+		"reason":        opt.Reason,
+		"new_unit_id":   unit.ID,
+		"new_unit_name": unit.Label,
+	})
+	return o
+}
+
+// resolveBooking 取消或迁移单个预约并通知用户
+func resolveBooking(ctx context.Context, db *repo.DB, notifier notify.Notifier, b repo.Booking, action string, candidates []repo.ResourceUnit, opt resolveOptions) ImpactOutcome {
+	o := ImpactOutcome{BookingID: b.ID, UserID: b.UserID, FromUnit: b.ResourceUnitID, Outcome: OutcomeUnresolved}
+
+	if action == ImpactRelocate {
+		unit, err := findFreeUnit(ctx, db, b, candidates, nil)
+		switch {
+		case err != nil:
+			o.Error = err.Error()
+		case unit != nil:
+			o = relocateBooking(ctx, db, notifier, b, *unit, opt)
+		default:
+			o.Error = "no free unit available"
+		}
+		if o.Outcome == OutcomeRelocated || !opt.CancelIfNoUnit {
+			return o
+		}
+	}
+
+	// 场馆原因取消：全额退款并取消未支付的支付意图
+	full := 100
+	out, err := CancelBookingWithRefund(ctx, db, notifier, opt.Provider, &b, time.Now(), CancelOptions{
+		Reason:          opt.Reason,
+		OverridePercent: &full,
+		NotifyKind:      opt.CancelKind,
+	})
+	if err != nil {
+		o.Error = fmt.Sprintf("cancel failed: %v", err)
+		return o
+	}
+	o.Outcome = OutcomeCancelled
+	o.Error = ""
+	o.RefundAmount = out.RefundAmount
+	o.RefundError = out.RefundError
+	return o
+}
+
+// ResolveBlackoutImpact 处理封场影响的预约：取消（全额退款）或迁移到同设施空闲单元，并通知用户
+func ResolveBlackoutImpact(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, blackout repo.Blackout, req ImpactRequest) ([]ImpactOutcome, error) {
+	if req.Action != ImpactCancel && req.Action != ImpactRelocate {
+		return nil, errors.New("action must be cancel or relocate")
+	}
+	affected, err := db.ListBlackoutAffectedBookings(ctx, blackout)
+	if err != nil {
+		return nil, err
+	}
+	selected := map[int64]bool{}
+	for _, id := range req.BookingIDs {
+		selected[id] = true
+	}
+	opt := resolveOptions{
+		Reason:         firstNonEmpty(req.Reason, blackout.Reason, "facility closed"),
+		CancelIfNoUnit: req.CancelIfNoUnit,
+		CancelKind:     notify.KindBlackoutCancelled,
+		RelocateKind:   notify.KindBlackoutRelocated,
+		Provider:       provider,
+	}
+
+	// 同设施单元只查询一次
+	unitsByFacility := map[int64][]repo.ResourceUnit{}
+	outcomes := make([]ImpactOutcome, 0, len(affected))
+	for _, b := range affected {
+		if len(selected) > 0 && !selected[b.ID] {
+			continue
+		}
+		var candidates []repo.ResourceUnit
+		if req.Action == ImpactRelocate {
+			current, err := db.GetResourceUnitByID(ctx, b.ResourceUnitID)
+			if err != nil {
+				outcomes = append(outcomes, ImpactOutcome{BookingID: b.ID, UserID: b.UserID, FromUnit: b.ResourceUnitID, Outcome: OutcomeUnresolved, Error: err.Error()})
+				continue
+			}
+			units, ok := unitsByFacility[current.FacilityID]
+			if !ok {
+				if units, err = db.ListUnitsByFacility(ctx, current.FacilityID); err != nil {
+					return outcomes, err
+				}
+				unitsByFacility[current.FacilityID] = units
+			}
+			candidates = units
+		}
+		outcomes = append(outcomes, resolveBooking(ctx, db, notifier, b, req.Action, candidates, opt))
+	}
+	return outcomes, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 变更流校验参数
+const (
+	bookingEventPage     = 1000
+	bookingVerifyRetries = 3 // 读取期间预约又有变更时重读
+)
+
+// ErrNoBookingEvents 预约没有变更记录（或不在管理范围内）
+var ErrNoBookingEvents = errors.New("no booking events")
+
+// BookingVerification 变更流校验结果（中文说明：Rebuilt 为按变更流重建的预约，删除后为空；Current 为 bookings 表中的当前状态）
+type BookingVerification struct {
+	BookingID  int64         `json:"booking_id"`
+	Events     int           `json:"events"`
+	LastSeq    int64         `json:"last_seq"`
+	Consistent bool          `json:"consistent"`
+	Mismatches []string      `json:"mismatches,omitempty"`
+	Rebuilt    *repo.Booking `json:"rebuilt,omitempty"`
+	Current    *repo.Booking `json:"current,omitempty"`
+}
+
+// listAllBookingEvents 分页读取预约的全部变更
+func listAllBookingEvents(ctx context.Context, db *repo.DB, bookingID, after int64) ([]repo.BookingEvent, error) {
+	var all []repo.BookingEvent
+	for {
+		page, err := db.ListBookingEvents(ctx, repo.BookingEventFilter{After: after, BookingID: bookingID, Limit: bookingEventPage})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < bookingEventPage {
+			return all, nil
+		}
+		after = page[len(page)-1].Seq
+	}
+}
+
+// VerifyBookingEvents 按变更流重建预约并与当前状态比较（中文说明：读取当前状态后若又有新的变更则重读，避免并发修改误报）
+func VerifyBookingEvents(ctx context.Context, db *repo.DB, bookingID int64) (*BookingVerification, error) {
+	list, err := listAllBookingEvents(ctx, db, bookingID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNoBookingEvents
+	}
+	for i := 0; ; i++ {
+		rebuilt, err := repo.ReplayBookingEvents(list)
+		if err != nil {
+			return nil, err
+		}
+		v := &BookingVerification{BookingID: bookingID, Events: len(list), LastSeq: list[len(list)-1].Seq}
+		if b, ok := rebuilt[bookingID]; ok {
+			v.Rebuilt = &b
+			if v.Current, err = db.GetBookingByID(ctx, bookingID); err != nil {
+				return nil, err
+			}
+		} else if cur, err := db.GetBookingByID(ctx, bookingID); err == nil {
+			v.Current = cur
+		}
+
+		more, err := listAllBookingEvents(ctx, db, bookingID, v.LastSeq)
+		if err != nil {
+			return nil, err
+		}
+		if len(more) == 0 || i+1 >= bookingVerifyRetries {
+			v.Mismatches = diffBookings(v.Rebuilt, v.Current)
+			v.Consistent = len(v.Mismatches) == 0
+			return v, nil
+		}
+		list = append(list, more...)
+	}
+}
+
+// diffBookings 比较两个预约，返回不一致的字段名（中文说明：一方为空时返回 exists）
+func diffBookings(a, b *repo.Booking) []string {
+	if a == nil || b == nil {
+		if a == nil && b == nil {
+			return nil
+		}
+		return []string{"exists"}
+	}
+	var out []string
+	check := func(name string, equal bool) {
+		if !equal {
+			out = append(out, name)
+		}
+	}
+	check("resource_unit_id", a.ResourceUnitID == b.ResourceUnitID)
+	check("user_id", a.UserID == b.UserID)
+	check("status", a.Status == b.Status)
+	check("start_time", a.StartTime.Equal(b.StartTime))
+	check("end_time", a.EndTime.Equal(b.EndTime))
+	check("price", a.Price == b.Price)
+	check("notes", a.Notes == b.Notes)
+	check("cancel_reason", a.CancelReason == b.CancelReason)
+	check("checked_in_at", equalTimePtr(a.CheckedInAt, b.CheckedInAt))
+	check("checked_in_by", a.CheckedInBy == b.CheckedInBy)
+	check("checked_in_count", (a.CheckedInCount == nil) == (b.CheckedInCount == nil) && (a.CheckedInCount == nil || *a.CheckedInCount == *b.CheckedInCount))
+	check("no_show_at", equalTimePtr(a.NoShowAt, b.NoShowAt))
+	return out
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 测试重建结果与当前状态比较：时间按时刻比较，签到字段与存在性不一致时报告字段名
+func TestDiffBookings(t *testing.T) {
+	start := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	n := 4
+	a := repo.Booking{ID: 1, ResourceUnitID: 2, Status: "confirmed", StartTime: start, EndTime: start.Add(time.Hour), CheckedInCount: &n}
+	b := a
+	b.StartTime = start.In(time.FixedZone("UTC+8", 8*3600))
+	m := 4
+	b.CheckedInCount = &m
+	if got := diffBookings(&a, &b); len(got) != 0 {
+		t.Fatalf("expected consistent, got %v", got)
+	}
+
+	at := start.Add(-5 * time.Minute)
+	b.CheckedInAt = &at
+	b.Status = "cancelled"
+	got := diffBookings(&a, &b)
+	if len(got) != 2 || got[0] != "status" || got[1] != "checked_in_at" {
+		t.Fatalf("expected status and checked_in_at, got %v", got)
+	}
+
+	if got := diffBookings(nil, &b); len(got) != 1 || got[0] != "exists" {
+		t.Fatalf("expected exists mismatch, got %v", got)
+	}
+	if got := diffBookings(nil, nil); len(got) != 0 {
+		t.Fatalf("expected deleted booking consistent, got %v", got)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 数据库事件转发参数
+const (
+	BusPollInterval  = time.Second
+	BusEventTTL      = 10 * time.Minute // 超过后删除，订阅方只关心实时变化
+	busPruneInterval = 5 * time.Minute
+	busBatchSize     = 500
+)
+
+// instanceName 本实例标识（主机名 + 随机后缀），用于定时任务租约与事件来源
+func instanceName() (string, error) {
+	host, _ := os.Hostname()
+	suffix, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	return host + "-" + suffix[:8], nil
+}
+
+// DBBroker 经 bus_events 表在实例之间转发事件（中文说明：实现 events.Broker；每个实例按序号轮询，
+// 跳过本实例写入的事件；适用于无法使用 LISTEN/NOTIFY 的 Supabase HTTP 访问）
+type DBBroker struct {
+	db     *repo.DB
+	origin string
+	poll   time.Duration
+}
+
+// NewDBBroker 创建数据库事件转发器（poll 为轮询间隔）
+func NewDBBroker(db *repo.DB, poll time.Duration) (*DBBroker, error) {
+	origin, err := instanceName()
+	if err != nil {
+		return nil, err
+	}
+	return &DBBroker{db: db, origin: origin, poll: poll}, nil
+}
+
+// Publish 实现 events.Broker 接口
+func (b *DBBroker) Publish(ctx context.Context, e events.Event) error {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.db.AppendBusEvent(ctx, b.origin, raw)
+}
+
+// Receive 实现 events.Broker 接口（中文说明：从当前最大序号开始轮询，不回放历史事件；顺带清理过期事件；
+// 写入由 027_bus_events_serialize.sql 串行化，提交顺序与 seq 一致，按序号读取不会跳过稍后提交的事件）
+func (b *DBBroker) Receive(ctx context.Context, deliver func(events.Event)) error {
+	seq, err := b.db.LatestBusEventSeq(ctx)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(b.poll)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			list, err := b.db.ListBusEventsAfter(ctx, seq, busBatchSize)
+			if err != nil {
+				return err
+			}
+			for _, be := range list {
+				seq = be.Seq
+				if be.Origin == b.origin {
+					continue
+				}
+				var e events.Event
+				if err := json.Unmarshal(be.Event, &e); err != nil {
+					slog.Warn("decode bus event failed", "seq", be.Seq, "err", err)
+					continue
+				}
+				deliver(e)
+			}
+			if now.Sub(lastPrune) >= busPruneInterval {
+				lastPrune = now
+				if err := b.db.PruneBusEvents(ctx, now.Add(-BusEventTTL)); err != nil {
+					slog.Warn("prune bus events failed", "err", err)
+				}
+			}
+		}
+	}
+}
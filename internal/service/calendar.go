@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/ical"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// CalendarFeedLookback 订阅中保留最近结束的预约（中文说明：使近期取消的预约以 STATUS:CANCELLED 同步到客户端）
+const CalendarFeedLookback = 7 * 24 * time.Hour
+
+// FacilityFeedHorizon 设施占用订阅向后覆盖的时间范围
+const FacilityFeedHorizon = 90 * 24 * time.Hour
+
+// bookingPlace 预约所在设施、单元与场馆时区
+type bookingPlace struct {
+	facility *repo.Facility
+	unit     *repo.ResourceUnit
+	loc      *time.Location
+}
+
+// placeResolver 按单元缓存设施与场馆时区，避免同一订阅内重复查询
+type placeResolver struct {
+	db    *repo.DB
+	units map[int64]*bookingPlace
+	tz    map[int64]*time.Location
+}
+
+func newPlaceResolver(db *repo.DB) *placeResolver {
+	return &placeResolver{db: db, units: map[int64]*bookingPlace{}, tz: map[int64]*time.Location{}}
+}
+
+func (r *placeResolver) resolve(ctx context.Context, unitID int64) (*bookingPlace, error) {
+	if p, ok := r.units[unitID]; ok {
+		return p, nil
+	}
+	unit, err := r.db.GetResourceUnitByID(ctx, unitID)
+	if err != nil {
+		return nil, err
+	}
+	facility, err := r.db.GetFacilityByID(ctx, unit.FacilityID)
+	if err != nil {
+		return nil, err
+	}
+	loc, ok := r.tz[facility.VenueID]
+	if !ok {
+		// 场馆时区缺失或无效时按 UTC 输出
+		if v, err := r.db.GetVenueByID(ctx, facility.VenueID); err == nil && v.Timezone != "" {
+			loc, _ = time.LoadLocation(v.Timezone)
+		}
+		r.tz[facility.VenueID] = loc
+	}
+	p := &bookingPlace{facility: facility, unit: unit, loc: loc}
+	r.units[unitID] = p
+	return p, nil
+}
+
+// bookingEventStatus 预约状态映射为日历事件状态（中文说明：待支付为 TENTATIVE，已取消为 CANCELLED）
+func bookingEventStatus(status string) string {
+	switch status {
+	case "pending":
+		return ical.StatusTentative
+	case "cancelled":
+		return ical.StatusCancelled
+	}
+	return ical.StatusConfirmed
+}
+
+// bookingEvent 预约对应的日历事件（UID 固定，客户端据此更新同一事件）
+func bookingEvent(b repo.Booking, p *bookingPlace) ical.Event {
+	place := p.facility.Name
+	if p.facility.Address != "" {
+		place += ", " + p.facility.Address
+	}
+	return ical.Event{
+		UID:         fmt.Sprintf("booking-%d@sport_backend", b.ID),
+		Start:       b.StartTime,
+		End:         b.EndTime,
+		Summary:     fmt.Sprintf("%s - %s", p.facility.Name, p.unit.Label),
+		Description: fmt.Sprintf("Booking #%d", b.ID),
+		Place:       place,
+		Status:      bookingEventStatus(b.Status),
+		Location:    p.loc,
+	}
+}
+
+// BookingCalendar 单个预约的日历（.ics 下载）
+func BookingCalendar(ctx context.Context, db *repo.DB, b *repo.Booking) (ical.Calendar, error) {
+	p, err := newPlaceResolver(db).resolve(ctx, b.ResourceUnitID)
+	if err != nil {
+		return ical.Calendar{}, err
+	}
+	return ical.Calendar{Events: []ical.Event{bookingEvent(*b, p)}}, nil
+}
+
+// UserCalendar 个人预约订阅：即将开始与最近结束的预约（含作为参与人加入的预约），按开始时间排序
+func UserCalendar(ctx context.Context, db *repo.DB, userID string, now time.Time) (ical.Calendar, error) {
+	list, err := db.ListBookingsByUser(ctx, userID)
+	if err != nil {
+		return ical.Calendar{}, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartTime.Before(list[j].StartTime) })
+	cal := ical.Calendar{Name: "My bookings"}
+	places := newPlaceResolver(db)
+	since := now.Add(-CalendarFeedLookback)
+	for _, b := range list {
+		if b.EndTime.Before(since) {
+			continue
+		}
+		p, err := places.resolve(ctx, b.ResourceUnitID)
+		if err != nil {
+			return ical.Calendar{}, err
+		}
+		cal.Events = append(cal.Events, bookingEvent(b, p))
+	}
+	return cal, nil
+}
+
+// FacilityBusyCalendar 设施占用订阅：已确认预约的时段（中文说明：供工作人员查看，不含预约人信息）
+func FacilityBusyCalendar(ctx context.Context, db *repo.DB, facilityID int64, now time.Time) (ical.Calendar, error) {
+	facility, err := db.GetFacilityByID(ctx, facilityID)
+	if err != nil {
+		return ical.Calendar{}, err
+	}
+	list, err := db.ListConfirmedBookings(ctx, facilityID, 0, now.Add(-CalendarFeedLookback), now.Add(FacilityFeedHorizon))
+	if err != nil {
+		return ical.Calendar{}, err
+	}
+	cal := ical.Calendar{Name: facility.Name + " (busy)"}
+	places := newPlaceResolver(db)
+	for _, b := range list {
+		p, err := places.resolve(ctx, b.ResourceUnitID)
+		if err != nil {
+			return ical.Calendar{}, err
+		}
+		e := bookingEvent(b, p)
+		e.Summary = "Booked: " + p.unit.Label
+		cal.Events = append(cal.Events, e)
+	}
+	return cal, nil
+}
+
+// CalendarFeed 获取订阅；不存在或 rotate 为 true 时生成新凭证（旧地址随即失效）
+func CalendarFeed(ctx context.Context, db *repo.DB, userID string, facilityID *int64, rotate bool) (*repo.CalendarFeed, error) {
+	if !rotate {
+		f, err := db.GetCalendarFeed(ctx, userID, facilityID)
+		if err != nil || f != nil {
+			return f, err
+		}
+	}
+	if err := db.RevokeCalendarFeeds(ctx, userID, facilityID); err != nil {
+		return nil, err
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	return db.CreateCalendarFeed(ctx, userID, facilityID, token)
+}
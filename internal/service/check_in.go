@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// NoShowLookback 爽约扫描只处理最近开场的预约（中文说明：避免服务停机后把很久以前的预约批量判为爽约）
+const NoShowLookback = 24 * time.Hour
+
+// ErrCheckInWindow 不在签到时间窗口内
+var ErrCheckInWindow = errors.New("outside the check-in window")
+
+// ErrInvalidCheckInCode 自助签到的场地签到码不匹配
+var ErrInvalidCheckInCode = errors.New("invalid check-in code")
+
+// ErrBookingBanned 用户因爽约被暂停预约
+var ErrBookingBanned = errors.New("booking privileges suspended")
+
+// NoShowPolicy 签到与爽约规则（中文说明：BanThreshold 为 0 表示爽约不处罚）
+type NoShowPolicy struct {
+	CheckInOpens time.Duration // 开场前多久开放自助签到
+	Grace        time.Duration // 开场后多久仍无人签到判为爽约
+	BanThreshold int           // 统计窗口内爽约次数达到该值时暂停预约
+	BanWindow    time.Duration // 爽约次数统计窗口
+	BanDuration  time.Duration // 暂停预约时长
+}
+
+// DefaultNoShowPolicy 默认规则：开场前 30 分钟至开场后 15 分钟可自助签到；30 天内爽约 3 次暂停预约 7 天
+var DefaultNoShowPolicy = NoShowPolicy{
+	CheckInOpens: 30 * time.Minute,
+	Grace:        15 * time.Minute,
+	BanThreshold: 3,
+	BanWindow:    30 * 24 * time.Hour,
+	BanDuration:  7 * 24 * time.Hour,
+}
+
+// CheckInWindow 签到时间窗口 [open, close)（中文说明：工作人员签到不受 close 限制，可持续到预约结束）
+func (p NoShowPolicy) CheckInWindow(b *repo.Booking) (time.Time, time.Time) {
+	return b.StartTime.Add(-p.CheckInOpens), b.StartTime.Add(p.Grace)
+}
+
+// CanCheckIn 校验预约当前是否可签到（中文说明：仅已确认且未签到；本人自助签到限于签到窗口，工作人员可签到至预约结束）
+func (p NoShowPolicy) CanCheckIn(b *repo.Booking, now time.Time, staff bool) error {
+	if b.Status != "confirmed" || b.CheckedInAt != nil {
+		return repo.ErrNotCheckInable
+	}
+	open, closeAt := p.CheckInWindow(b)
+	if staff {
+		closeAt = b.EndTime
+	}
+	if now.Before(open) || !now.Before(closeAt) {
+		return fmt.Errorf("%w: %s - %s", ErrCheckInWindow, open.UTC().Format(time.RFC3339), closeAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ShouldBan 爽约次数是否达到暂停预约的阈值
+func (p NoShowPolicy) ShouldBan(noShows int) bool {
+	return p.BanThreshold > 0 && p.BanDuration > 0 && noShows >= p.BanThreshold
+}
+
+// NewCheckInCode 生成场地签到码
+func NewCheckInCode() (string, error) {
+	return newToken()
+}
+
+// CheckIn 签到（中文说明：staff 为 false 时为本人或参与人扫描场地二维码自助签到，须提交单元签到码；headcount 为 0 时取预订人加参与人数）
+func CheckIn(ctx context.Context, db *repo.DB, p NoShowPolicy, b *repo.Booking, code string, headcount int, staff bool, now time.Time) (*repo.Booking, error) {
+	if err := p.CanCheckIn(b, now, staff); err != nil {
+		return nil, err
+	}
+	if !staff {
+		expected, err := db.GetUnitCheckInCode(ctx, b.ResourceUnitID)
+		if err != nil {
+			return nil, err
+		}
+		if code == "" || subtle.ConstantTimeCompare([]byte(code), []byte(expected)) != 1 {
+			return nil, ErrInvalidCheckInCode
+		}
+	}
+	if headcount <= 0 {
+		participants, err := db.ListBookingParticipants(ctx, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		headcount = repo.Headcount(participants)
+	}
+	return db.CheckInBooking(ctx, b.ID, headcount, now)
+}
+
+// CheckBookingBan 用户存在生效中的禁令时返回 ErrBookingBanned
+func CheckBookingBan(ctx context.Context, db *repo.DB, userID string, now time.Time) error {
+	ban, err := db.GetActiveBan(ctx, userID, now)
+	if err != nil {
+		return err
+	}
+	if ban != nil {
+		return fmt.Errorf("%w until %s", ErrBookingBanned, ban.EndsAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// SweepNoShows 将开场后宽限期内无人签到的预约标记为爽约并按规则暂停预约，返回标记数量
+// 中文说明：标记后剩余时段即可被重新预约；爽约预约不退款
+func SweepNoShows(ctx context.Context, db *repo.DB, p NoShowPolicy, now time.Time) (int, error) {
+	cutoff := now.Add(-p.Grace)
+	due, err := db.ListCheckInDue(ctx, cutoff.Add(-NoShowLookback), cutoff)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, b := range due {
+		marked, err := db.MarkNoShow(ctx, b.ID, now)
+		if err != nil {
+			return n, err
+		}
+		if !marked {
+			continue
+		}
+		n++
+		if err := penalizeNoShow(ctx, db, p, b.UserID, now); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// penalizeNoShow 统计窗口内爽约次数达到阈值且当前没有生效禁令时暂停预约
+func penalizeNoShow(ctx context.Context, db *repo.DB, p NoShowPolicy, userID string, now time.Time) error {
+	if p.BanThreshold <= 0 {
+		return nil
+	}
+	count, err := db.CountNoShows(ctx, userID, now.Add(-p.BanWindow))
+	if err != nil {
+		return err
+	}
+	if !p.ShouldBan(count) {
+		return nil
+	}
+	active, err := db.GetActiveBan(ctx, userID, now)
+	if err != nil || active != nil {
+		return err
+	}
+	_, err = db.CreateBookingBan(ctx, repo.BookingBan{
+		UserID:      userID,
+		Reason:      fmt.Sprintf("%d no-shows within %d days", count, int(p.BanWindow/(24*time.Hour))),
+		NoShowCount: count,
+		StartsAt:    now,
+		EndsAt:      now.Add(p.BanDuration),
+	})
+	return err
+}
+
+// RunNoShowSweep 定期标记爽约预约，直到 ctx 结束
+func RunNoShowSweep(ctx context.Context, db *repo.DB, p NoShowPolicy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := SweepNoShows(ctx, db, p, now); err != nil {
+				slog.Warn("no-show sweep failed", "err", err)
+			} else if n > 0 {
+				slog.Info("marked no-show bookings", "count", n)
+			}
+		}
+	}
+}
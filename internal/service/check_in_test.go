@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 测试签到窗口（中文说明：自助签到限于开场前后窗口，工作人员可签到至预约结束；已签到或未确认不可签到）
+func TestCanCheckIn(t *testing.T) {
+	p := DefaultNoShowPolicy
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	b := &repo.Booking{Status: "confirmed", StartTime: start, EndTime: start.Add(time.Hour)}
+
+	if err := p.CanCheckIn(b, start.Add(-31*time.Minute), false); !errors.Is(err, ErrCheckInWindow) {
+		t.Fatalf("expected window error before opening, got %v", err)
+	}
+	if err := p.CanCheckIn(b, start.Add(-30*time.Minute), false); err != nil {
+		t.Fatalf("expected window open, got %v", err)
+	}
+	if err := p.CanCheckIn(b, start.Add(15*time.Minute), false); !errors.Is(err, ErrCheckInWindow) {
+		t.Fatalf("self check-in should close after grace, got %v", err)
+	}
+	if err := p.CanCheckIn(b, start.Add(45*time.Minute), true); err != nil {
+		t.Fatalf("staff should check in until end, got %v", err)
+	}
+	if err := p.CanCheckIn(b, start.Add(time.Hour), true); !errors.Is(err, ErrCheckInWindow) {
+		t.Fatalf("staff check-in should close at end, got %v", err)
+	}
+
+	at := start
+	done := *b
+	done.CheckedInAt = &at
+	if err := p.CanCheckIn(&done, start, true); !errors.Is(err, repo.ErrNotCheckInable) {
+		t.Fatalf("expected already checked in, got %v", err)
+	}
+	pending := *b
+	pending.Status = "pending"
+	if err := p.CanCheckIn(&pending, start, true); !errors.Is(err, repo.ErrNotCheckInable) {
+		t.Fatalf("expected pending booking rejected, got %v", err)
+	}
+}
+
+func TestShouldBan(t *testing.T) {
+	p := DefaultNoShowPolicy
+	if p.ShouldBan(2) || !p.ShouldBan(3) {
+		t.Fatalf("expected ban from %d no-shows", p.BanThreshold)
+	}
+	p.BanThreshold = 0
+	if p.ShouldBan(10) {
+		t.Fatal("threshold 0 should disable penalties")
+	}
+}
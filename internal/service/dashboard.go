@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 看板消息类型
+const (
+	DashboardSnapshotType = "snapshot"
+	DashboardUpdateType   = "update"
+)
+
+// DashboardUnit 单元当天的预约与封场（按开始时间排序）
+type DashboardUnit struct {
+	UnitID    int64           `json:"unit_id"`
+	Label     string          `json:"label"`
+	Bookings  []repo.Booking  `json:"bookings"`
+	Blackouts []repo.Blackout `json:"blackouts"`
+}
+
+// DashboardFacility 设施及其单元（中文说明：From/To 为场馆时区的当天 0 点至次日 0 点）
+type DashboardFacility struct {
+	FacilityID int64           `json:"facility_id"`
+	Name       string          `json:"name"`
+	VenueID    int64           `json:"venue_id"`
+	Timezone   string          `json:"timezone"`
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	Units      []DashboardUnit `json:"units"`
+}
+
+// DashboardSnapshot 看板初始快照
+type DashboardSnapshot struct {
+	Type        string              `json:"type"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Facilities  []DashboardFacility `json:"facilities"`
+}
+
+// DashboardUpdate 看板增量更新（中文说明：Object 为变更后的预约或封场，Previous 为变更前，删除封场时 Object 为空）
+type DashboardUpdate struct {
+	Type        string      `json:"type"`
+	Event       string      `json:"event"`
+	OccurredAt  time.Time   `json:"occurred_at"`
+	FacilityIDs []int64     `json:"facility_ids"`
+	UnitIDs     []int64     `json:"unit_ids,omitempty"`
+	Object      interface{} `json:"object,omitempty"`
+	Previous    interface{} `json:"previous,omitempty"`
+}
+
+// dayWindow 设施所在场馆时区的当天范围
+type dayWindow struct {
+	from, to time.Time
+}
+
+// Dashboard 运营看板（中文说明：快照覆盖管理范围内全部设施当天的预约与封场，之后只转发与这些设施当天相关的事件；
+// 跨过场馆时区的 0 点后需重新生成快照）
+type Dashboard struct {
+	db         *repo.DB
+	facilityID int64 // 非 0 时只看该设施
+
+	units   map[int64]int64 // 单元 → 设施
+	windows map[int64]dayWindow
+}
+
+// NewDashboard 创建运营看板（facilityID 为 0 表示管理范围内全部设施）
+func NewDashboard(db *repo.DB, facilityID int64) *Dashboard {
+	return &Dashboard{db: db, facilityID: facilityID}
+}
+
+// Snapshot 生成当天快照（中文说明：设施范围取 ctx 中的租户范围），并据此更新事件过滤条件
+func (d *Dashboard) Snapshot(ctx context.Context, now time.Time) (*DashboardSnapshot, error) {
+	var facilities []repo.Facility
+	if d.facilityID > 0 {
+		f, err := d.db.GetFacilityByID(ctx, d.facilityID)
+		if err != nil {
+			return nil, err
+		}
+		facilities = []repo.Facility{*f}
+	} else {
+		list, err := d.db.ListFacilities(ctx)
+		if err != nil {
+			return nil, err
+		}
+		facilities = list
+	}
+
+	tz := map[int64]*time.Location{}
+	units := map[int64]int64{}
+	windows := map[int64]dayWindow{}
+	snap := &DashboardSnapshot{Type: DashboardSnapshotType, GeneratedAt: now.UTC(), Facilities: []DashboardFacility{}}
+	var earliest, latest time.Time
+	for _, f := range facilities {
+		if !f.IsActive {
+			continue
+		}
+		loc, ok := tz[f.VenueID]
+		if !ok {
+			loc = time.UTC
+			if v, err := d.db.GetVenueByID(ctx, f.VenueID); err == nil && v.Timezone != "" {
+				if l, err := time.LoadLocation(v.Timezone); err == nil {
+					loc = l
+				}
+			}
+			tz[f.VenueID] = loc
+		}
+		local := now.In(loc)
+		from := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		w := dayWindow{from: from, to: from.AddDate(0, 0, 1)}
+		windows[f.ID] = w
+		if earliest.IsZero() || w.from.Before(earliest) {
+			earliest = w.from
+		}
+		if w.to.After(latest) {
+			latest = w.to
+		}
+
+		list, err := d.db.ListUnitsByFacility(ctx, f.ID)
+		if err != nil {
+			return nil, err
+		}
+		df := DashboardFacility{FacilityID: f.ID, Name: f.Name, VenueID: f.VenueID, Timezone: loc.String(), From: w.from, To: w.to, Units: []DashboardUnit{}}
+		for _, u := range list {
+			if !u.IsActive {
+				continue
+			}
+			units[u.ID] = f.ID
+			df.Units = append(df.Units, DashboardUnit{UnitID: u.ID, Label: u.Label, Bookings: []repo.Booking{}, Blackouts: []repo.Blackout{}})
+		}
+		snap.Facilities = append(snap.Facilities, df)
+	}
+	if len(units) > 0 {
+		if err := d.fill(ctx, snap, units, windows, earliest, latest); err != nil {
+			return nil, err
+		}
+	}
+	d.units, d.windows = units, windows
+	return snap, nil
+}
+
+// fill 一次查询全部单元的预约与封场，再按设施当天范围分配到单元
+func (d *Dashboard) fill(ctx context.Context, snap *DashboardSnapshot, units map[int64]int64, windows map[int64]dayWindow, from, to time.Time) error {
+	ids := make([]int64, 0, len(units))
+	for id := range units {
+		ids = append(ids, id)
+	}
+	bookings, err := d.db.ListBookingsForUnitsBetween(ctx, ids, from, to)
+	if err != nil {
+		return err
+	}
+	blackouts, err := d.db.ListBlackouts(ctx, repo.BlackoutFilter{From: from, To: to})
+	if err != nil {
+		return err
+	}
+	index := map[int64]*DashboardUnit{}
+	for i := range snap.Facilities {
+		f := &snap.Facilities[i]
+		for j := range f.Units {
+			index[f.Units[j].UnitID] = &f.Units[j]
+		}
+	}
+	for _, b := range bookings {
+		u, ok := index[b.ResourceUnitID]
+		w := windows[units[b.ResourceUnitID]]
+		if ok && b.StartTime.Before(w.to) && b.EndTime.After(w.from) {
+			u.Bookings = append(u.Bookings, b)
+		}
+	}
+	for _, b := range blackouts {
+		for id, u := range index {
+			facilityID := units[id]
+			onUnit := b.ResourceUnitID != nil && *b.ResourceUnitID == id
+			onFacility := b.FacilityID != nil && *b.FacilityID == facilityID
+			if !onUnit && !onFacility {
+				continue
+			}
+			w := windows[facilityID]
+			u.Blackouts = append(u.Blackouts, b.Occurrences(w.from, w.to)...)
+		}
+	}
+	return nil
+}
+
+// Expired 是否有设施已跨过当天范围，需要重新生成快照
+func (d *Dashboard) Expired(now time.Time) bool {
+	for _, w := range d.windows {
+		if !now.Before(w.to) {
+			return true
+		}
+	}
+	return false
+}
+
+// Update 将事件转换为看板更新（中文说明：事件不涉及看板中设施当天的时段时返回 false）
+func (d *Dashboard) Update(e events.Event) (*DashboardUpdate, bool) {
+	facilitySeen := map[int64]bool{}
+	unitSeen := map[int64]bool{}
+	u := &DashboardUpdate{Type: DashboardUpdateType, Event: e.Type, OccurredAt: e.OccurredAt, Object: e.Data, Previous: e.Previous}
+	for _, t := range e.Targets {
+		facilityID := t.FacilityID
+		if t.UnitID > 0 {
+			id, ok := d.units[t.UnitID]
+			if !ok {
+				continue
+			}
+			facilityID = id
+		}
+		w, ok := d.windows[facilityID]
+		if !ok || !t.Overlaps(w.from, w.to) {
+			continue
+		}
+		if !facilitySeen[facilityID] {
+			facilitySeen[facilityID] = true
+			u.FacilityIDs = append(u.FacilityIDs, facilityID)
+		}
+		if t.UnitID > 0 && !unitSeen[t.UnitID] {
+			unitSeen[t.UnitID] = true
+			u.UnitIDs = append(u.UnitIDs, t.UnitID)
+		}
+	}
+	return u, len(u.FacilityIDs) > 0
+}
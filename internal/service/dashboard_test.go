@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+)
+
+// 测试看板事件过滤：管理范围外的单元与设施、其它日期忽略；设施级封场与重复封场转发；跨日后需重新快照
+func TestDashboardUpdate(t *testing.T) {
+	day := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	d := &Dashboard{
+		units:   map[int64]int64{1: 10, 2: 10, 3: 20},
+		windows: map[int64]dayWindow{10: {from: day, to: day.Add(24 * time.Hour)}, 20: {from: day.Add(-8 * time.Hour), to: day.Add(16 * time.Hour)}},
+	}
+	at := func(h int) (time.Time, time.Time) {
+		return day.Add(time.Duration(h) * time.Hour), day.Add(time.Duration(h+1) * time.Hour)
+	}
+
+	s, e := at(10)
+	u, ok := d.Update(events.Event{Type: events.BookingCheckedIn, Targets: []events.Target{{UnitID: 1, Start: s, End: e}}})
+	if !ok || len(u.FacilityIDs) != 1 || u.FacilityIDs[0] != 10 || len(u.UnitIDs) != 1 || u.UnitIDs[0] != 1 {
+		t.Fatalf("expected unit 1 in facility 10, got %+v", u)
+	}
+	if u.Type != DashboardUpdateType || u.Event != events.BookingCheckedIn {
+		t.Fatalf("unexpected update %+v", u)
+	}
+
+	if _, ok := d.Update(events.Event{Targets: []events.Target{{UnitID: 99, Start: s, End: e}}}); ok {
+		t.Fatal("expected unmanaged unit ignored")
+	}
+	if _, ok := d.Update(events.Event{Targets: []events.Target{{FacilityID: 30, Start: s, End: e}}}); ok {
+		t.Fatal("expected unmanaged facility ignored")
+	}
+
+	// 设施 20 的当天按场馆时区为 [-8h, 16h)，18 点已是次日
+	s2, e2 := at(18)
+	if _, ok := d.Update(events.Event{Targets: []events.Target{{UnitID: 3, Start: s2, End: e2}}}); ok {
+		t.Fatal("expected other local day ignored")
+	}
+	if u, ok := d.Update(events.Event{Targets: []events.Target{{UnitID: 1, Start: s2, End: e2}, {UnitID: 3, Start: s2, End: e2}}}); !ok || len(u.FacilityIDs) != 1 || u.FacilityIDs[0] != 10 {
+		t.Fatalf("expected only facility 10, got %+v", u)
+	}
+
+	u, ok = d.Update(events.Event{Type: events.BlackoutCreated, Targets: []events.Target{{FacilityID: 20, Start: s2.AddDate(0, 0, -7), End: e2.AddDate(0, 0, -7), Recurring: true}}})
+	if !ok || len(u.FacilityIDs) != 1 || u.FacilityIDs[0] != 20 || len(u.UnitIDs) != 0 {
+		t.Fatalf("expected recurring facility blackout forwarded, got %+v", u)
+	}
+
+	if d.Expired(day.Add(15 * time.Hour)) {
+		t.Fatal("expected not expired")
+	}
+	if !d.Expired(day.Add(16 * time.Hour)) {
+		t.Fatal("expected expired after facility 20 day ends")
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 停用设施/单元时对未来已确认预约的处理策略
+const (
+	PolicyBlock    = "block"    // 存在未来预约时拒绝停用（默认）
+	PolicyCancel   = "cancel"   // 取消全部未来预约并全额退款
+	PolicyRelocate = "relocate" // 迁移到其它空闲单元，任一预约无法迁移时不迁移任何预约并拒绝停用
+)
+
+// FutureBookingsError 停用对象仍有未处理的未来已确认预约
+type FutureBookingsError struct {
+	Bookings []repo.Booking
+	Outcomes []ImpactOutcome
+}
+
+func (e *FutureBookingsError) Error() string {
+	if len(e.Outcomes) > 0 {
+		return "some future bookings could not be resolved"
+	}
+	return fmt.Sprintf("%d future confirmed bookings; choose policy cancel or relocate", len(e.Bookings))
+}
+
+// ValidPolicy 是否为支持的停用策略（空字符串视为 block）
+func ValidPolicy(policy string) bool {
+	switch policy {
+	case "", PolicyBlock, PolicyCancel, PolicyRelocate:
+		return true
+	}
+	return false
+}
+
+// PrepareUnitDeactivation 停用单元前按策略处理其未来预约（中文说明：迁移目标为同设施的其它激活单元）
+func PrepareUnitDeactivation(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, unitID int64, policy, reason string) ([]ImpactOutcome, error) {
+	bookings, err := db.ListConfirmedBookings(ctx, 0, unitID, time.Now().UTC(), time.Time{})
+	if err != nil || len(bookings) == 0 {
+		return nil, err
+	}
+	var candidates []repo.ResourceUnit
+	if policy == PolicyRelocate {
+		unit, err := db.GetResourceUnitByID(ctx, unitID)
+		if err != nil {
+			return nil, err
+		}
+		if candidates, err = db.ListUnitsByFacility(ctx, unit.FacilityID); err != nil {
+			return nil, err
+		}
+	}
+	return applyDeactivationPolicy(ctx, db, notifier, provider, bookings, policy, firstNonEmpty(reason, "court closed"), candidates)
+}
+
+// PrepareFacilityDeactivation 停用设施前按策略处理其未来预约（中文说明：迁移目标为同场馆其它同类型激活设施的单元，不会迁往其它场馆）
+func PrepareFacilityDeactivation(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, facility repo.Facility, policy, reason string) ([]ImpactOutcome, error) {
+	bookings, err := db.ListConfirmedBookings(ctx, facility.ID, 0, time.Now().UTC(), time.Time{})
+	if err != nil || len(bookings) == 0 {
+		return nil, err
+	}
+	var candidates []repo.ResourceUnit
+	if policy == PolicyRelocate {
+		units, err := db.ListUnitsByFacilityType(ctx, facility.Type)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range units {
+			if u.FacilityID != facility.ID && u.VenueID == facility.VenueID {
+				candidates = append(candidates, u)
+			}
+		}
+	}
+	return applyDeactivationPolicy(ctx, db, notifier, provider, bookings, policy, firstNonEmpty(reason, "facility closed"), candidates)
+}
+
+func applyDeactivationPolicy(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, bookings []repo.Booking, policy, reason string, candidates []repo.ResourceUnit) ([]ImpactOutcome, error) {
+	if policy == "" || policy == PolicyBlock {
+		return nil, &FutureBookingsError{Bookings: bookings}
+	}
+	action := ImpactCancel
+	if policy == PolicyRelocate {
+		action = ImpactRelocate
+	}
+	opt := resolveOptions{
+		Reason:       reason,
+		CancelKind:   notify.KindClosureCancelled,
+		RelocateKind: notify.KindClosureRelocated,
+		Provider:     provider,
+	}
+	var targets []*repo.ResourceUnit
+	if action == ImpactRelocate {
+		var err error
+		if targets, err = planRelocations(ctx, db, bookings, candidates); err != nil {
+			return nil, err
+		}
+		var planned []ImpactOutcome
+		for i, b := range bookings {
+			if targets[i] == nil {
+				planned = append(planned, ImpactOutcome{BookingID: b.ID, UserID: b.UserID, FromUnit: b.ResourceUnitID, Outcome: OutcomeUnresolved, Error: "no free unit available"})
+			}
+		}
+		if len(planned) > 0 {
+			return planned, &FutureBookingsError{Bookings: bookings, Outcomes: planned}
+		}
+	}
+	outcomes := make([]ImpactOutcome, 0, len(bookings))
+	unresolved := false
+	for i, b := range bookings {
+		var o ImpactOutcome
+		if action == ImpactRelocate {
+			o = relocateBooking(ctx, db, notifier, b, *targets[i], opt)
+		} else {
+			o = resolveBooking(ctx, db, notifier, b, action, candidates, opt)
+		}
+		if o.Outcome == OutcomeUnresolved {
+			unresolved = true
+		}
+		outcomes = append(outcomes, o)
+	}
+	if unresolved {
+		return outcomes, &FutureBookingsError{Outcomes: outcomes}
+	}
+	return outcomes, nil
+}
+
+// planRelocations 迁移前为每个预约选定目标单元（中文说明：已选定的时段在后续预约中视同占用，
+// 避免两个预约迁入同一单元的重叠时段；无法迁移的预约对应 nil）
+func planRelocations(ctx context.Context, db *repo.DB, bookings []repo.Booking, candidates []repo.ResourceUnit) ([]*repo.ResourceUnit, error) {
+	targets := make([]*repo.ResourceUnit, len(bookings))
+	reserved := map[int64][]TimeRange{}
+	for i, b := range bookings {
+		unit, err := findFreeUnit(ctx, db, b, candidates, reserved)
+		if err != nil {
+			return nil, err
+		}
+		if unit != nil {
+			reserved[unit.ID] = append(reserved[unit.ID], TimeRange{Start: b.StartTime, End: b.EndTime})
+		}
+		targets[i] = unit
+	}
+	return targets, nil
+}
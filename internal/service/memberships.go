@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// ErrPolicyViolation 预约不满足预约策略（时长、粒度或提前预订天数）
+var ErrPolicyViolation = errors.New("reservation policy violation")
+
+// MemberBenefits 用户在某场馆的会员权益（中文说明：持有多个有效方案时折扣取最高，覆盖项取最宽松）
+type MemberBenefits struct {
+	Plans              []string `json:"plans,omitempty"`
+	DiscountPercent    int      `json:"discount_percent"`
+	AdvanceBookingDays *int     `json:"advance_booking_days,omitempty"`
+	MaxDurationMinutes *int     `json:"max_duration_minutes,omitempty"`
+}
+
+// BenefitsFor 汇总适用于场馆的会员权益
+func BenefitsFor(memberships []repo.Membership, venueID int64) MemberBenefits {
+	var b MemberBenefits
+	for _, m := range memberships {
+		p := m.Plan
+		if !p.AppliesToVenue(venueID) {
+			continue
+		}
+		b.Plans = append(b.Plans, p.Name)
+		if p.DiscountPercent > b.DiscountPercent {
+			b.DiscountPercent = p.DiscountPercent
+		}
+		if v := p.AdvanceBookingDays; v != nil && (b.AdvanceBookingDays == nil || *v > *b.AdvanceBookingDays) {
+			b.AdvanceBookingDays = v
+		}
+		if v := p.MaxDurationMinutes; v != nil && (b.MaxDurationMinutes == nil || *v > *b.MaxDurationMinutes) {
+			b.MaxDurationMinutes = v
+		}
+	}
+	return b
+}
+
+// MemberDiscount 按折扣比例计算优惠金额（保留两位小数）
+func MemberDiscount(price float64, percent int) float64 {
+	if price <= 0 || percent <= 0 {
+		return 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	return math.Round(price*float64(percent)) / 100
+}
+
+// CheckReservationPolicy 校验预约是否满足策略（中文说明：会员方案的覆盖项替换对应的默认值）
+func CheckReservationPolicy(p repo.ReservationPolicy, b MemberBenefits, start, end, now time.Time) error {
+	if !end.After(start) {
+		return fmt.Errorf("%w: end_time must be after start_time", ErrPolicyViolation)
+	}
+	if start.Before(now) {
+		return fmt.Errorf("%w: start_time is in the past", ErrPolicyViolation)
+	}
+	maxMinutes := p.MaxDurationMinutes
+	if b.MaxDurationMinutes != nil {
+		maxMinutes = *b.MaxDurationMinutes
+	}
+	advanceDays := p.AdvanceBookingDays
+	if b.AdvanceBookingDays != nil {
+		advanceDays = *b.AdvanceBookingDays
+	}
+	minutes := int(end.Sub(start) / time.Minute)
+	if minutes < p.MinDurationMinutes {
+		return fmt.Errorf("%w: duration must be at least %d minutes", ErrPolicyViolation, p.MinDurationMinutes)
+	}
+	if maxMinutes > 0 && minutes > maxMinutes {
+		return fmt.Errorf("%w: duration must be at most %d minutes", ErrPolicyViolation, maxMinutes)
+	}
+	if g := p.SlotGranularityMinutes; g > 0 && minutes%g != 0 {
+		return fmt.Errorf("%w: duration must be a multiple of %d minutes", ErrPolicyViolation, g)
+	}
+	if start.After(now.AddDate(0, 0, advanceDays)) {
+		return fmt.Errorf("%w: bookings open %d days in advance", ErrPolicyViolation, advanceDays)
+	}
+	return nil
+}
+
+// Quote 预约报价（中文说明：BasePrice 为价格规则计算结果，Price 为依次扣除会员折扣与优惠码后的应付金额）
+type Quote struct {
+	BasePrice      float64        `json:"base_price"`
+	MemberDiscount float64        `json:"member_discount"`
+	PromoCode      string         `json:"promo_code,omitempty"`
+	PromoDiscount  float64        `json:"promo_discount"`
+	Price          float64        `json:"price"`
+	Benefits       MemberBenefits `json:"membership"`
+
+	promo *repo.PromoCode // 创建预约时据此核销
+}
+
+// bookingTerms 预约适用的场馆、设施类型策略与用户会员权益
+type bookingTerms struct {
+	facility *repo.Facility
+	location *time.Location // 场馆时区，价格规则按当地星期与小时取价
+	policy   *repo.ReservationPolicy
+	benefits MemberBenefits
+}
+
+func loadBookingTerms(ctx context.Context, db *repo.DB, userID string, unitID int64, now time.Time) (*bookingTerms, error) {
+	unit, err := db.GetResourceUnitByID(ctx, unitID)
+	if err != nil {
+		return nil, err
+	}
+	facility, err := db.GetFacilityByID(ctx, unit.FacilityID)
+	if err != nil {
+		return nil, err
+	}
+	venue, err := db.GetVenueByID(ctx, facility.VenueID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := db.GetReservationPolicy(ctx, facility.VenueID, facility.Type)
+	if err != nil {
+		return nil, err
+	}
+	t := &bookingTerms{facility: facility, location: venue.Location(), policy: policy}
+	if userID != "" {
+		memberships, err := db.ListActiveMemberships(ctx, userID, now)
+		if err != nil {
+			return nil, err
+		}
+		t.benefits = BenefitsFor(memberships, facility.VenueID)
+	}
+	return t, nil
+}
+
+// CheckBookingPolicy 按用户会员权益校验预约策略（改签时使用）
+func CheckBookingPolicy(ctx context.Context, db *repo.DB, userID string, unitID int64, start, end, now time.Time) error {
+	t, err := loadBookingTerms(ctx, db, userID, unitID, now)
+	if err != nil {
+		return err
+	}
+	return CheckReservationPolicy(*t.policy, t.benefits, start, end, now)
+}
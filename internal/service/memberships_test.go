@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+func intPtr(v int) *int { return &v }
+
+// 测试会员权益汇总（中文说明：仅计入适用于该场馆的方案，折扣取最高，覆盖项取最宽松）
+func TestBenefitsFor(t *testing.T) {
+	venue := int64(1)
+	other := int64(2)
+	list := []repo.Membership{
+		{Plan: repo.MembershipPlan{Name: "Silver", DiscountPercent: 10, AdvanceBookingDays: intPtr(14)}},
+		{Plan: repo.MembershipPlan{Name: "Gold", VenueID: &venue, DiscountPercent: 20, AdvanceBookingDays: intPtr(30), MaxDurationMinutes: intPtr(240)}},
+		{Plan: repo.MembershipPlan{Name: "Elsewhere", VenueID: &other, DiscountPercent: 50}},
+	}
+	b := BenefitsFor(list, venue)
+	if len(b.Plans) != 2 || b.DiscountPercent != 20 {
+		t.Fatalf("unexpected benefits: %+v", b)
+	}
+	if *b.AdvanceBookingDays != 30 || *b.MaxDurationMinutes != 240 {
+		t.Fatalf("expected widest overrides, got %+v", b)
+	}
+	if b := BenefitsFor(list, other); b.DiscountPercent != 50 || *b.AdvanceBookingDays != 14 || b.MaxDurationMinutes != nil {
+		t.Fatalf("unexpected benefits for other venue: %+v", b)
+	}
+}
+
+func TestMemberDiscount(t *testing.T) {
+	if d := MemberDiscount(45, 20); d != 9 {
+		t.Fatalf("expected 9, got %v", d)
+	}
+	if d := MemberDiscount(10.05, 15); d != 1.51 {
+		t.Fatalf("expected 1.51, got %v", d)
+	}
+	if d := MemberDiscount(30, 0); d != 0 {
+		t.Fatalf("expected 0, got %v", d)
+	}
+}
+
+// 测试预约策略校验与会员覆盖
+func TestCheckReservationPolicy(t *testing.T) {
+	p := repo.ReservationPolicy{MinDurationMinutes: 60, MaxDurationMinutes: 120, SlotGranularityMinutes: 30, AdvanceBookingDays: 7}
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	at := func(days int, hours float64) time.Time {
+		return now.AddDate(0, 0, days).Add(time.Duration(hours * float64(time.Hour)))
+	}
+	public := MemberBenefits{}
+	gold := MemberBenefits{AdvanceBookingDays: intPtr(30), MaxDurationMinutes: intPtr(240)}
+
+	cases := []struct {
+		name       string
+		b          MemberBenefits
+		start, end time.Time
+		ok         bool
+	}{
+		{"within policy", public, at(1, 2), at(1, 3.5), true},
+		{"too short", public, at(1, 2), at(1, 2.5), false},
+		{"too long", public, at(1, 2), at(1, 5), false},
+		{"member longer", gold, at(1, 2), at(1, 5), true},
+		{"off granularity", public, at(1, 2), at(1, 3.25), false},
+		{"too far ahead", public, at(20, 2), at(20, 3), false},
+		{"member further ahead", gold, at(20, 2), at(20, 3), true},
+		{"in the past", gold, at(0, -2), at(0, -1), false},
+	}
+	for _, tc := range cases {
+		err := CheckReservationPolicy(p, tc.b, tc.start, tc.end, now)
+		if tc.ok && err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		}
+		if !tc.ok && !errors.Is(err, ErrPolicyViolation) {
+			t.Fatalf("%s: expected policy violation, got %v", tc.name, err)
+		}
+	}
+}
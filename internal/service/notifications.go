@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 发件箱投递参数
+const (
+	OutboxBatchSize   = 20
+	OutboxLease       = 2 * time.Minute // 领取后租期，实例中途退出时租期过后重新投递
+	MaxOutboxAttempts = 8
+	maxOutboxBackoff  = time.Hour
+)
+
+// 邮件中的时间格式（场馆本地时间）
+const (
+	mailDateTimeLayout = "2006-01-02 15:04"
+	mailTimeLayout     = "15:04"
+)
+
+// OutboxBackoff 第 attempts 次投递失败后的等待时间（中文说明：1 分钟起按 2 倍递增，最长 1 小时）
+func OutboxBackoff(attempts int) time.Duration {
+	return expBackoff(attempts, time.Minute, maxOutboxBackoff)
+}
+
+// expBackoff 指数退避：第 1 次失败等待 base，之后按 2 倍递增，不超过 max
+func expBackoff(attempts int, base, max time.Duration) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := base
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// OutboxNotifier 将通知按收件人语言渲染为邮件并写入发件箱（中文说明：实现 notify.Notifier，由 RunOutboxDispatch 投递）
+type OutboxNotifier struct {
+	db *repo.DB
+}
+
+// NewOutboxNotifier 创建发件箱通知器
+func NewOutboxNotifier(db *repo.DB) *OutboxNotifier {
+	return &OutboxNotifier{db: db}
+}
+
+// Notify 实现 notify.Notifier 接口（中文说明：用户没有邮箱时跳过）
+func (o *OutboxNotifier) Notify(ctx context.Context, n notify.Notification) error {
+	contact, err := o.db.GetNotificationContact(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+	if contact.Email == "" {
+		slog.Info("notification skipped: no email", "user_id", n.UserID, "kind", n.Kind)
+		return nil
+	}
+	msg, err := notify.Render(n.Kind, contact.Locale, messageData(ctx, o.db, n))
+	if err != nil {
+		return err
+	}
+	m := repo.OutboxMessage{
+		UserID:    n.UserID,
+		Kind:      n.Kind,
+		Locale:    contact.Locale,
+		Recipient: contact.Email,
+		Subject:   msg.Subject,
+		Body:      msg.Body,
+	}
+	if n.BookingID != 0 {
+		m.BookingID = &n.BookingID
+	}
+	_, err = o.db.EnqueueNotification(ctx, m)
+	return err
+}
+
+// messageData 模板变量：在通知数据基础上补充设施、单元名称，并将时间按场馆时区格式化
+func messageData(ctx context.Context, db *repo.DB, n notify.Notification) map[string]interface{} {
+	data := map[string]interface{}{"booking_id": n.BookingID, "facility": "", "unit": "", "start": "", "end": ""}
+	for k, v := range n.Data {
+		data[k] = v
+	}
+	loc := time.UTC
+	if unitID, ok := n.Data["unit_id"].(int64); ok {
+		if p, err := newPlaceResolver(db).resolve(ctx, unitID); err == nil {
+			data["facility"] = p.facility.Name
+			data["unit"] = p.unit.Label
+			if p.loc != nil {
+				loc = p.loc
+			}
+		}
+	}
+	start, _ := n.Data["start_time"].(time.Time)
+	end, _ := n.Data["end_time"].(time.Time)
+	if !start.IsZero() {
+		data["start"] = start.In(loc).Format(mailDateTimeLayout)
+	}
+	if !end.IsZero() {
+		// 同一天结束时只显示时刻
+		layout := mailDateTimeLayout
+		if start.In(loc).Format(time.DateOnly) == end.In(loc).Format(time.DateOnly) {
+			layout = mailTimeLayout
+		}
+		data["end"] = end.In(loc).Format(layout)
+	}
+	if prev, ok := n.Data["previous_start_time"].(time.Time); ok {
+		data["previous_start"] = prev.In(loc).Format(mailDateTimeLayout)
+	}
+	return data
+}
+
+// notifyBooking 发送与预约相关的通知（中文说明：补充预约时间与单元；发送失败只记录日志，不影响业务操作）
+func notifyBooking(ctx context.Context, notifier notify.Notifier, b repo.Booking, kind string, data map[string]interface{}) {
+	if notifier == nil {
+		return
+	}
+	if err := notifier.Notify(ctx, bookingNotification(b, kind, data)); err != nil {
+		slog.Warn("notification failed", "booking_id", b.ID, "kind", kind, "err", err)
+	}
+}
+
+// bookingNotification 构造预约通知，补充预约时间与单元
+func bookingNotification(b repo.Booking, kind string, data map[string]interface{}) notify.Notification {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["start_time"] = b.StartTime
+	data["end_time"] = b.EndTime
+	data["unit_id"] = b.ResourceUnitID
+	return notify.Notification{UserID: b.UserID, Kind: kind, BookingID: b.ID, Data: data}
+}
+
+// notifyConfirmed 支付完成确认预约后通知预约人
+func notifyConfirmed(ctx context.Context, db *repo.DB, notifier notify.Notifier, bookingID int64) {
+	if notifier == nil {
+		return
+	}
+	b, err := db.GetBookingByID(ctx, bookingID)
+	if err != nil {
+		slog.Warn("notification failed", "booking_id", bookingID, "kind", notify.KindBookingConfirmed, "err", err)
+		return
+	}
+	notifyBooking(ctx, notifier, *b, notify.KindBookingConfirmed, nil)
+}
+
+// NotifyBookingRescheduled 通知预约改签（中文说明：b 为改签后的预约，previous 为原开始时间）
+func NotifyBookingRescheduled(ctx context.Context, notifier notify.Notifier, b repo.Booking, previous time.Time) {
+	notifyBooking(ctx, notifier, b, notify.KindBookingRescheduled, map[string]interface{}{"previous_start_time": previous})
+}
+
+// DispatchOutbox 投递一批到期邮件，返回成功数量（中文说明：失败按 OutboxBackoff 退避，超过 MaxOutboxAttempts 次标记为 failed）
+func DispatchOutbox(ctx context.Context, db *repo.DB, mailer notify.Mailer, now time.Time) (int, error) {
+	msgs, err := db.ClaimOutbox(ctx, OutboxBatchSize, OutboxLease)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, m := range msgs {
+		sendErr := mailer.Send(ctx, notify.Message{To: m.Recipient, Subject: m.Subject, Body: m.Body})
+		if sendErr == nil {
+			if err := db.MarkOutboxSent(ctx, m.ID, now); err != nil {
+				slog.Warn("mark notification sent failed", "id", m.ID, "err", err)
+			}
+			sent++
+			continue
+		}
+		var next *time.Time
+		if m.Attempts < MaxOutboxAttempts {
+			t := now.Add(OutboxBackoff(m.Attempts))
+			next = &t
+		}
+		slog.Warn("send notification failed", "id", m.ID, "attempts", m.Attempts, "err", sendErr)
+		if err := db.MarkOutboxRetry(ctx, m.ID, sendErr.Error(), next); err != nil {
+			slog.Warn("record notification failure failed", "id", m.ID, "err", err)
+		}
+	}
+	return sent, nil
+}
+
+// RunOutboxDispatch 定期投递发件箱中的邮件，直到 ctx 结束
+func RunOutboxDispatch(ctx context.Context, db *repo.DB, mailer notify.Mailer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := DispatchOutbox(ctx, db, mailer, now); err != nil {
+				slog.Warn("dispatch notifications failed", "err", err)
+			} else if n > 0 {
+				slog.Info("sent notifications", "count", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// 测试发件箱退避：1 分钟起翻倍，封顶 1 小时
+func TestOutboxBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  time.Minute,
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		7:  time.Hour,
+		30: time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := OutboxBackoff(attempts); got != want {
+			t.Fatalf("attempts %d: expected %v, got %v", attempts, want, got)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// PaymentHoldTTL 待支付占位的保留时间，超时未支付则释放时段
+const PaymentHoldTTL = 15 * time.Minute
+
+// 支付方式
+const (
+	PayWithProvider = ""       // 支付渠道（默认）
+	PayWithWallet   = "wallet" // 钱包余额
+)
+
+// Checkout 创建预约结果（中文说明：需要支付时 Payment 与 ClientSecret 非空，预约为 pending）
+type Checkout struct {
+	Booking      *repo.Booking
+	Payment      *repo.Payment
+	ClientSecret string
+	Quote        *Quote
+	Shares       []repo.BookingShare // 分摊支付时的份额（含支付链接凭证）
+}
+
+// PlaceOptions 创建预约的可选项
+type PlaceOptions struct {
+	PayWith   string        // 支付方式：PayWithProvider 或 PayWithWallet
+	PromoCode string        // 优惠码，可为空
+	Split     *SplitRequest // 分摊支付，可为空
+}
+
+// QuoteBooking 按单元所属场馆与设施类型的价格规则计算金额，依次扣除会员折扣与优惠码
+// 中文说明：同时校验预约策略（会员方案可放宽提前预订天数与最长时长），不满足时返回 ErrPolicyViolation；优惠码不可用时返回 ErrPromoInvalid
+func QuoteBooking(ctx context.Context, db *repo.DB, nb repo.NewBooking, promoCode string, now time.Time) (*Quote, error) {
+	t, err := loadBookingTerms(ctx, db, nb.UserID, nb.ResourceUnitID, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckReservationPolicy(*t.policy, t.benefits, nb.Start, nb.End, now); err != nil {
+		return nil, err
+	}
+	rules, err := db.ListPricingRules(ctx, t.facility.VenueID, t.facility.Type)
+	if err != nil {
+		return nil, err
+	}
+	q := &Quote{BasePrice: BookingPrice(rules, nb.Start, nb.End, t.location), Benefits: t.benefits}
+	q.MemberDiscount = MemberDiscount(q.BasePrice, t.benefits.DiscountPercent)
+	q.Price = math.Round((q.BasePrice-q.MemberDiscount)*100) / 100
+	if promoCode != "" {
+		if err := applyPromo(ctx, db, q, promoCode, nb.UserID, t.facility, now); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// PlaceBooking 创建预约
+// 中文说明：因爽约被暂停预约的用户返回 ErrBookingBanned；金额为 0 或未配置支付渠道时直接确认；钱包支付时扣款并立即确认；否则创建 pending 占位与支付意图，支付成功后才确认
+// 使用优惠码时在创建预约后核销，核销失败则释放预约；分摊支付时不创建整单支付意图，改为按人数生成份额；预约确认时通知预约人
+func PlaceBooking(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, currency string, nb repo.NewBooking, opt PlaceOptions) (*Checkout, error) {
+	now := time.Now()
+	if err := CheckBookingBan(ctx, db, nb.UserID, now); err != nil {
+		return nil, err
+	}
+	quote, err := QuoteBooking(ctx, db, nb, opt.PromoCode, now)
+	if err != nil {
+		return nil, err
+	}
+	price := quote.Price
+	nb.Price = price
+	wallet := opt.PayWith == PayWithWallet && price > 0
+	viaProvider := !wallet && price > 0 && provider != nil
+	var deadline time.Time
+	if opt.Split != nil {
+		if !viaProvider {
+			return nil, fmt.Errorf("%w: requires a paid booking via the payment provider", ErrSplitUnavailable)
+		}
+		if err := ValidateInvitees(nb.UserID, opt.Split.Invitees); err != nil {
+			return nil, err
+		}
+		if deadline, err = SplitDeadline(opt.Split.Deadline, now, nb.Start); err != nil {
+			return nil, err
+		}
+	}
+	nb.Status = "confirmed"
+	if wallet || viaProvider {
+		nb.Status = "pending"
+	}
+	b, err := db.CreateBooking(ctx, nb)
+	if err != nil {
+		return nil, err
+	}
+	if quote.promo != nil {
+		if _, err := db.RedeemPromoCode(ctx, quote.promo.ID, b.ID, nb.UserID, quote.PromoDiscount); err != nil {
+			_ = db.CancelBooking(ctx, b.ID, "promo code could not be redeemed")
+			return nil, err
+		}
+	}
+	out := &Checkout{Booking: b, Quote: quote}
+	switch {
+	case opt.Split != nil:
+		shares, err := createShares(ctx, db, b, nb.UserID, *opt.Split, deadline)
+		if err != nil {
+			_ = db.CancelBooking(ctx, b.ID, "split payment could not be started")
+			return nil, err
+		}
+		out.Shares = shares
+	case wallet:
+		p, err := db.PayBookingFromWallet(ctx, b.ID, nb.UserID, price, currency)
+		if err != nil {
+			_ = db.CancelBooking(ctx, b.ID, "wallet payment failed")
+			return nil, err
+		}
+		b.Status = "confirmed"
+		out.Payment = p
+		notifyBooking(ctx, notifier, *b, notify.KindBookingConfirmed, nil)
+	case viaProvider:
+		p, secret, err := startPayment(ctx, db, provider, currency, repo.Payment{
+			BookingID: b.ID,
+			UserID:    nb.UserID,
+			Amount:    price,
+		}, fmt.Sprintf("Booking #%d", b.ID))
+		if err != nil {
+			_ = db.CancelBooking(ctx, b.ID, "payment could not be started")
+			return nil, err
+		}
+		out.Payment = p
+		out.ClientSecret = secret
+	default:
+		notifyBooking(ctx, notifier, *b, notify.KindBookingConfirmed, nil)
+	}
+	return out, nil
+}
+
+// HandlePaymentEvent 处理已验签的 Webhook 事件（中文说明：重复事件不会重复处理；支付成功确认预约后通知预约人）
+func HandlePaymentEvent(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, ev payment.Event) error {
+	p, err := db.GetPaymentByProviderRef(ctx, provider.Name(), ev.PaymentRef)
+	if err != nil {
+		return err
+	}
+	switch ev.Type {
+	case payment.EventPaymentSucceeded:
+		updated, err := db.TransitionPayment(ctx, p.ID, []string{repo.PaymentRequiresPayment}, repo.PaymentSucceeded, "")
+		if err != nil {
+			return err
+		}
+		if updated == nil {
+			// 支付意图已因占位过期、预约取消或失败而作废，渠道仍扣款成功：记为成功后全额退回
+			late, err := db.TransitionPayment(ctx, p.ID, []string{repo.PaymentCancelled, repo.PaymentFailed}, repo.PaymentSucceeded, "")
+			if err != nil || late == nil {
+				return err
+			}
+			_, err = refund(ctx, db, provider, late, repo.PaymentRefund{Reason: "payment no longer expected", Policy: RefundPolicyFull})
+			return err
+		}
+		switch updated.Kind {
+		case repo.PaymentKindShare:
+			return settleSharePayment(ctx, db, notifier, provider, updated)
+		case repo.PaymentKindCover:
+			return settleCoverPayment(ctx, db, notifier, provider, updated)
+		}
+		confirmed, err := db.ConfirmBooking(ctx, p.BookingID)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			// 占位已过期或已取消：支付到账后全额退回
+			_, err := refund(ctx, db, provider, updated, repo.PaymentRefund{Reason: "booking no longer held", Policy: RefundPolicyFull})
+			return err
+		}
+		notifyConfirmed(ctx, db, notifier, p.BookingID)
+		return nil
+	case payment.EventPaymentFailed:
+		updated, err := db.TransitionPayment(ctx, p.ID, []string{repo.PaymentRequiresPayment}, repo.PaymentFailed, ev.FailureReason)
+		if err != nil || updated == nil {
+			return err
+		}
+		if updated.Kind != repo.PaymentKindBooking {
+			// 份额与补足支付失败可重新发起，预约保留到分摊截止时间
+			return nil
+		}
+		return releaseHold(ctx, db, p.BookingID, "payment failed")
+	case payment.EventRefundSucceeded:
+		// 本地渠道退款同步完成；异步渠道的退款确认仅记录日志
+		slog.Info("refund confirmed by provider", "payment_id", p.ID, "refund_ref", ev.RefundRef)
+		return nil
+	}
+	return payment.ErrUnknownEvent
+}
+
+// RefundPayment 管理员手动退款（中文说明：amount<=0 表示退回全部剩余金额）
+func RefundPayment(ctx context.Context, db *repo.DB, provider payment.Provider, p *repo.Payment, amount float64, reason string) (*repo.PaymentRefund, error) {
+	return refund(ctx, db, provider, p, repo.PaymentRefund{Amount: amount, Reason: reason, Policy: RefundPolicyManual})
+}
+
+// refund 通过支付渠道退款并记录（中文说明：r.Amount<=0 表示退回全部剩余金额；先预占退款金额再调用渠道，
+// 并发退款不会重复退出同一笔钱，渠道失败时归还预占）
+func refund(ctx context.Context, db *repo.DB, provider payment.Provider, p *repo.Payment, r repo.PaymentRefund) (*repo.PaymentRefund, error) {
+	if p.Provider != repo.WalletProvider && (provider == nil || provider.Name() != p.Provider) {
+		return nil, fmt.Errorf("payment provider %q not available", p.Provider)
+	}
+	reserved, amount, err := db.ReserveRefund(ctx, p.ID, r.Amount)
+	if err != nil {
+		return nil, err
+	}
+	r.Amount = amount
+	if p.Provider == repo.WalletProvider {
+		// 钱包支付退回钱包
+		ref, err := db.RefundToWallet(ctx, *reserved, r.Amount, firstNonEmpty(r.Reason, "booking refund"))
+		if err != nil {
+			releaseRefund(ctx, db, p.ID, amount)
+			return nil, err
+		}
+		r.ProviderRef = ref
+		r.Status = payment.IntentSucceeded
+		return db.RecordRefund(ctx, *reserved, r)
+	}
+	res, err := provider.Refund(ctx, payment.RefundRequest{PaymentRef: p.ProviderRef, Amount: r.Amount, Reason: r.Reason})
+	if err != nil {
+		releaseRefund(ctx, db, p.ID, amount)
+		return nil, err
+	}
+	r.ProviderRef = res.Ref
+	r.Status = res.Status
+	return db.RecordRefund(ctx, *reserved, r)
+}
+
+// releaseRefund 归还预占的退款金额，失败时记录日志由管理员核对
+func releaseRefund(ctx context.Context, db *repo.DB, paymentID int64, amount float64) {
+	if err := db.ReleaseRefund(ctx, paymentID, amount); err != nil {
+		slog.Error("release reserved refund failed", "payment_id", paymentID, "amount", amount, "err", err)
+	}
+}
+
+// CancelOpenPayments 取消预约下尚未支付的支付意图（预约被取消时调用）
+func CancelOpenPayments(ctx context.Context, db *repo.DB, bookingID int64) error {
+	list, err := db.ListPaymentsByBooking(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+	for _, p := range list {
+		if p.Status != repo.PaymentRequiresPayment {
+			continue
+		}
+		if _, err := db.TransitionPayment(ctx, p.ID, []string{repo.PaymentRequiresPayment}, repo.PaymentCancelled, "booking cancelled"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpirePaymentHolds 释放超时未支付的占位，返回释放数量
+func ExpirePaymentHolds(ctx context.Context, db *repo.DB, now time.Time) (int, error) {
+	stale, err := db.ListStalePayments(ctx, now.Add(-PaymentHoldTTL))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, p := range stale {
+		updated, err := db.TransitionPayment(ctx, p.ID, []string{repo.PaymentRequiresPayment}, repo.PaymentCancelled, "payment hold expired")
+		if err != nil {
+			return n, err
+		}
+		if updated == nil {
+			continue
+		}
+		if err := releaseHold(ctx, db, p.BookingID, "payment hold expired"); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// RunPaymentHoldExpiry 定期释放超时占位与分摊逾期的预约，直到 ctx 结束
+func RunPaymentHoldExpiry(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := ExpirePaymentHolds(ctx, db, now); err != nil {
+				slog.Warn("expire payment holds failed", "err", err)
+			} else if n > 0 {
+				slog.Info("released expired payment holds", "count", n)
+			}
+			if n, err := ExpireSplitBookings(ctx, db, notifier, provider, now); err != nil {
+				slog.Warn("expire split payments failed", "err", err)
+			} else if n > 0 {
+				slog.Info("released bookings with unpaid shares", "count", n)
+			}
+		}
+	}
+}
+
+// releaseHold 取消仍处于 pending 的占位预约
+func releaseHold(ctx context.Context, db *repo.DB, bookingID int64, reason string) error {
+	b, err := db.GetBookingByID(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+	if b.Status != "pending" {
+		return nil
+	}
+	if err := db.CancelBooking(ctx, bookingID, reason); err != nil && !errors.Is(err, repo.ErrBookingNotActive) {
+		return err
+	}
+	return nil
+}
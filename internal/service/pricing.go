@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// ErrPricingOverlap 价格规则时段冲突
+var ErrPricingOverlap = errors.New("pricing rule overlaps an existing rule")
+
+// ValidatePricingRule 校验价格规则字段范围（中文说明：与数据库 CHECK 保持一致，提前返回可读错误）
+func ValidatePricingRule(r repo.PricingRule) error {
+	if r.FacilityType == "" {
+		return errors.New("facility_type required")
+	}
+	if r.DayOfWeek < 0 || r.DayOfWeek > 6 {
+		return errors.New("day_of_week must be between 0 and 6")
+	}
+	if r.StartHour < 0 || r.StartHour > 23 || r.EndHour < 1 || r.EndHour > 24 || r.EndHour <= r.StartHour {
+		return errors.New("invalid hour range")
+	}
+	if r.PricePerHour < 0 {
+		return errors.New("price_per_hour must be >= 0")
+	}
+	return nil
+}
+
+// FindPricingOverlap 查找与 r 冲突的已有规则（中文说明：同场馆、同设施类型、同星期且小时段相交即冲突；忽略 ID 相同的规则以支持更新）
+func FindPricingOverlap(r repo.PricingRule, existing []repo.PricingRule) error {
+	for _, e := range existing {
+		if r.ID != 0 && e.ID == r.ID {
+			continue
+		}
+		if e.VenueID != r.VenueID || e.FacilityType != r.FacilityType || e.DayOfWeek != r.DayOfWeek {
+			continue
+		}
+		if r.StartHour < e.EndHour && e.StartHour < r.EndHour {
+			return fmt.Errorf("%w: rule %d covers %02d:00-%02d:00", ErrPricingOverlap, e.ID, e.StartHour, e.EndHour)
+		}
+	}
+	return nil
+}
+
+// DayRates 某一天每小时的有效价格（中文说明：HourlyRates[h] 为 h:00-h+1:00 的价格，无规则时为 nil）
+type DayRates struct {
+	DayOfWeek   int
+	HourlyRates []*float64
+}
+
+// PriceGrid 生成一周 7 天 × 24 小时的价格表
+func PriceGrid(rules []repo.PricingRule) []DayRates {
+	grid := make([]DayRates, 7)
+	for d := range grid {
+		grid[d] = DayRates{DayOfWeek: d, HourlyRates: make([]*float64, 24)}
+	}
+	for _, r := range rules {
+		if r.DayOfWeek < 0 || r.DayOfWeek > 6 {
+			continue
+		}
+		price := r.PricePerHour
+		for h := r.StartHour; h < r.EndHour && h < 24; h++ {
+			if h >= 0 {
+				grid[r.DayOfWeek].HourlyRates[h] = &price
+			}
+		}
+	}
+	return grid
+}
+
+// BookingPrice 按价格规则计算预约金额（中文说明：逐小时按场馆时区 loc 的星期与小时取价，不足一小时按分钟折算；无规则的时段不计费）
+func BookingPrice(rules []repo.PricingRule, start, end time.Time, loc *time.Location) float64 {
+	grid := PriceGrid(rules)
+	total := 0.0
+	for t := start.In(loc); t.Before(end); {
+		// 按当地整点切分（时区偏移可能不是整小时）
+		next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if next.After(end) {
+			next = end
+		}
+		if rate := grid[int(t.Weekday())].HourlyRates[t.Hour()]; rate != nil {
+			total += *rate * next.Sub(t).Minutes() / 60
+		}
+		t = next
+	}
+	return math.Round(total*100) / 100
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 测试价格规则冲突检测与价格表（中文说明：相邻时段不冲突，相交时段冲突）
+func TestFindPricingOverlapAndGrid(t *testing.T) {
+	existing := []repo.PricingRule{
+		{ID: 1, FacilityType: "badminton", DayOfWeek: 1, StartHour: 8, EndHour: 18, PricePerHour: 20},
+		{ID: 2, FacilityType: "badminton", DayOfWeek: 1, StartHour: 18, EndHour: 22, PricePerHour: 30},
+	}
+
+	adjacent := repo.PricingRule{FacilityType: "badminton", DayOfWeek: 1, StartHour: 22, EndHour: 24, PricePerHour: 25}
+	if err := FindPricingOverlap(adjacent, existing); err != nil {
+		t.Fatalf("adjacent rule should not overlap: %v", err)
+	}
+	overlap := repo.PricingRule{FacilityType: "badminton", DayOfWeek: 1, StartHour: 17, EndHour: 19}
+	if err := FindPricingOverlap(overlap, existing); !errors.Is(err, ErrPricingOverlap) {
+		t.Fatalf("expected overlap, got %v", err)
+	}
+	otherDay := repo.PricingRule{FacilityType: "badminton", DayOfWeek: 2, StartHour: 17, EndHour: 19}
+	if err := FindPricingOverlap(otherDay, existing); err != nil {
+		t.Fatalf("other weekday should not overlap: %v", err)
+	}
+	otherVenue := repo.PricingRule{VenueID: 2, FacilityType: "badminton", DayOfWeek: 1, StartHour: 17, EndHour: 19}
+	if err := FindPricingOverlap(otherVenue, existing); err != nil {
+		t.Fatalf("other venue should not overlap: %v", err)
+	}
+	self := existing[0]
+	self.EndHour = 17
+	if err := FindPricingOverlap(self, existing); err != nil {
+		t.Fatalf("updating a rule should ignore itself: %v", err)
+	}
+
+	grid := PriceGrid(existing)
+	if grid[1].HourlyRates[7] != nil {
+		t.Fatalf("07:00 should have no rate")
+	}
+	if got := *grid[1].HourlyRates[8]; got != 20 {
+		t.Fatalf("08:00 expected 20, got %v", got)
+	}
+	if got := *grid[1].HourlyRates[21]; got != 30 {
+		t.Fatalf("21:00 expected 30, got %v", got)
+	}
+}
+
+// 测试预约金额计算（中文说明：跨价格时段按分钟折算，无规则时段不计费）
+func TestBookingPrice(t *testing.T) {
+	rules := []repo.PricingRule{
+		{FacilityType: "badminton", DayOfWeek: 1, StartHour: 8, EndHour: 18, PricePerHour: 20},
+		{FacilityType: "badminton", DayOfWeek: 1, StartHour: 18, EndHour: 22, PricePerHour: 30},
+	}
+	monday := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	// 17:30-19:00：半小时 20 + 一小时 30
+	if got := BookingPrice(rules, monday.Add(17*time.Hour+30*time.Minute), monday.Add(19*time.Hour), time.UTC); got != 40 {
+		t.Fatalf("expected 40, got %v", got)
+	}
+	// 21:00-23:00：22 点之后无规则
+	if got := BookingPrice(rules, monday.Add(21*time.Hour), monday.Add(23*time.Hour), time.UTC); got != 30 {
+		t.Fatalf("expected 30, got %v", got)
+	}
+	// 周二无规则
+	if got := BookingPrice(rules, monday.Add(24*time.Hour+10*time.Hour), monday.Add(24*time.Hour+11*time.Hour), time.UTC); got != 0 {
+		t.Fatalf("expected 0, got %v", got)
+	}
+
+	// 场馆时区：上海周一 18:00-19:00 为 UTC 10:00-11:00，按当地时段取 30
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := BookingPrice(rules, monday.Add(10*time.Hour), monday.Add(11*time.Hour), shanghai); got != 30 {
+		t.Fatalf("expected 30 in venue time, got %v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// ErrPromoInvalid 优惠码不存在、未生效、已过期或不适用于该预约
+var ErrPromoInvalid = errors.New("promo code not applicable")
+
+// PromoDiscount 计算优惠码在应付金额上的优惠（中文说明：校验启用状态、有效期、场馆、设施类型与次数上限；固定金额不超过应付金额）
+// used 为有效核销总次数，usedByUser 为当前用户的有效核销次数
+func PromoDiscount(pc repo.PromoCode, venueID int64, facilityType string, price float64, now time.Time, used, usedByUser int) (float64, error) {
+	if !pc.IsActive {
+		return 0, fmt.Errorf("%w: inactive", ErrPromoInvalid)
+	}
+	if pc.StartsAt != nil && now.Before(*pc.StartsAt) {
+		return 0, fmt.Errorf("%w: not yet valid", ErrPromoInvalid)
+	}
+	if pc.EndsAt != nil && !now.Before(*pc.EndsAt) {
+		return 0, fmt.Errorf("%w: expired", ErrPromoInvalid)
+	}
+	if pc.VenueID != nil && *pc.VenueID != venueID {
+		return 0, fmt.Errorf("%w: not valid at this venue", ErrPromoInvalid)
+	}
+	if len(pc.FacilityTypes) > 0 && !slices.Contains(pc.FacilityTypes, facilityType) {
+		return 0, fmt.Errorf("%w: not valid for %s", ErrPromoInvalid, facilityType)
+	}
+	if pc.MaxRedemptions != nil && used >= *pc.MaxRedemptions {
+		return 0, repo.ErrPromoLimitReached
+	}
+	if pc.PerUserLimit != nil && usedByUser >= *pc.PerUserLimit {
+		return 0, repo.ErrPromoLimitReached
+	}
+	if price <= 0 {
+		return 0, nil
+	}
+	var discount float64
+	switch pc.DiscountType {
+	case repo.PromoPercent:
+		discount = math.Round(price*pc.DiscountValue) / 100
+	case repo.PromoFixed:
+		discount = pc.DiscountValue
+	}
+	return math.Min(price, math.Round(discount*100)/100), nil
+}
+
+// applyPromo 查询优惠码并从报价中扣除（中文说明：在会员折扣之后计算）
+func applyPromo(ctx context.Context, db *repo.DB, q *Quote, code, userID string, facility *repo.Facility, now time.Time) error {
+	pc, err := db.GetPromoCodeByCode(ctx, code)
+	if err != nil {
+		return fmt.Errorf("%w: unknown code", ErrPromoInvalid)
+	}
+	used, mine, err := db.CountPromoRedemptions(ctx, pc.ID, userID)
+	if err != nil {
+		return err
+	}
+	discount, err := PromoDiscount(*pc, facility.VenueID, facility.Type, q.Price, now, used, mine)
+	if err != nil {
+		return err
+	}
+	q.promo = pc
+	q.PromoCode = pc.Code
+	q.PromoDiscount = discount
+	q.Price = math.Round((q.Price-discount)*100) / 100
+	return nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 测试优惠码计算与适用条件（中文说明：比例与固定金额、有效期、场馆与设施类型限制、次数上限）
+func TestPromoDiscount(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	venue := int64(1)
+	starts, ends := now.Add(-time.Hour), now.Add(24*time.Hour)
+	half := repo.PromoCode{Code: "LAUNCH50", DiscountType: repo.PromoPercent, DiscountValue: 50, IsActive: true, StartsAt: &starts, EndsAt: &ends}
+
+	if d, err := PromoDiscount(half, venue, "badminton", 45, now, 0, 0); err != nil || d != 22.5 {
+		t.Fatalf("expected 22.5, got %v %v", d, err)
+	}
+	fixed := repo.PromoCode{Code: "CORP20", DiscountType: repo.PromoFixed, DiscountValue: 20, IsActive: true}
+	if d, err := PromoDiscount(fixed, venue, "tennis", 15, now, 0, 0); err != nil || d != 15 {
+		t.Fatalf("fixed discount must not exceed price, got %v %v", d, err)
+	}
+
+	limit := 1
+	other := int64(2)
+	invalid := []struct {
+		name string
+		pc   repo.PromoCode
+		now  time.Time
+	}{
+		{"inactive", repo.PromoCode{DiscountType: repo.PromoPercent, DiscountValue: 10}, now},
+		{"not started", half, starts.Add(-time.Minute)},
+		{"expired", half, ends},
+		{"other venue", repo.PromoCode{VenueID: &other, DiscountType: repo.PromoFixed, DiscountValue: 5, IsActive: true}, now},
+		{"facility type", repo.PromoCode{FacilityTypes: []string{"tennis"}, DiscountType: repo.PromoFixed, DiscountValue: 5, IsActive: true}, now},
+	}
+	for _, tc := range invalid {
+		if _, err := PromoDiscount(tc.pc, venue, "badminton", 45, tc.now, 0, 0); !errors.Is(err, ErrPromoInvalid) {
+			t.Fatalf("%s: expected ErrPromoInvalid, got %v", tc.name, err)
+		}
+	}
+
+	capped := repo.PromoCode{DiscountType: repo.PromoPercent, DiscountValue: 10, IsActive: true, MaxRedemptions: &limit}
+	if _, err := PromoDiscount(capped, venue, "badminton", 45, now, 1, 0); !errors.Is(err, repo.ErrPromoLimitReached) {
+		t.Fatalf("expected global cap, got %v", err)
+	}
+	perUser := repo.PromoCode{DiscountType: repo.PromoPercent, DiscountValue: 10, IsActive: true, PerUserLimit: &limit}
+	if _, err := PromoDiscount(perUser, venue, "badminton", 45, now, 5, 0); err != nil {
+		t.Fatalf("other users' redemptions must not count, got %v", err)
+	}
+	if _, err := PromoDiscount(perUser, venue, "badminton", 45, now, 5, 1); !errors.Is(err, repo.ErrPromoLimitReached) {
+		t.Fatalf("expected per-user cap, got %v", err)
+	}
+}
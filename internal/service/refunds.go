@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 退款策略结果
+const (
+	RefundPolicyFull     = "full"
+	RefundPolicyPartial  = "partial"
+	RefundPolicyNone     = "none"
+	RefundPolicyOverride = "override" // 管理员指定比例
+	RefundPolicyManual   = "manual"   // 管理员手动退款
+)
+
+// ErrAlreadyCancelled 预约已取消
+var ErrAlreadyCancelled = errors.New("booking already cancelled")
+
+// ErrBookingAttended 预约已签到或已判为爽约，不能再取消
+var ErrBookingAttended = errors.New("booking already checked in or marked as no-show")
+
+// RefundDecision 退款规则判定结果
+type RefundDecision struct {
+	Policy           string  `json:"policy"`
+	Percent          int     `json:"percent"`
+	HoursBeforeStart float64 `json:"hours_before_start"`
+}
+
+// EvaluateRefund 按设施类型的退款规则判定退款比例
+// 中文说明：距开场不少于 RefundFullHours 全额；不少于 RefundPartialHours 按比例；否则不退
+func EvaluateRefund(ft repo.FacilityType, start, now time.Time) RefundDecision {
+	hours := start.Sub(now).Hours()
+	d := RefundDecision{Policy: RefundPolicyNone, HoursBeforeStart: math.Round(hours*100) / 100}
+	switch {
+	case hours >= float64(ft.RefundFullHours):
+		d.Policy, d.Percent = RefundPolicyFull, 100
+	case ft.RefundPartialPercent > 0 && hours >= float64(ft.RefundPartialHours):
+		d.Policy, d.Percent = RefundPolicyPartial, ft.RefundPartialPercent
+	}
+	return d
+}
+
+// CancelOptions 取消参数（中文说明：OverridePercent 仅管理员可设置，覆盖退款规则；NotifyKind 为空时发送 booking_cancelled 通知）
+type CancelOptions struct {
+	Reason          string
+	OverridePercent *int
+	NotifyKind      string
+}
+
+// CancelOutcome 取消结果（中文说明：RefundError 非空表示预约已取消但退款失败，需管理员手动处理）
+type CancelOutcome struct {
+	Booking      *repo.Booking        `json:"booking"`
+	Refund       RefundDecision       `json:"refund"`
+	RefundAmount float64              `json:"refund_amount"`
+	Refunds      []repo.PaymentRefund `json:"refunds"`
+	RefundError  string               `json:"refund_error,omitempty"`
+}
+
+// CancelBookingWithRefund 取消预约并按退款规则对已支付金额退款，并通知预约人
+func CancelBookingWithRefund(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, b *repo.Booking, now time.Time, opt CancelOptions) (*CancelOutcome, error) {
+	if b.Status == "cancelled" {
+		return nil, ErrAlreadyCancelled
+	}
+	if b.Status == "no_show" || b.CheckedInAt != nil {
+		return nil, ErrBookingAttended
+	}
+	var decision RefundDecision
+	if opt.OverridePercent != nil {
+		decision = RefundDecision{
+			Policy:           RefundPolicyOverride,
+			Percent:          *opt.OverridePercent,
+			HoursBeforeStart: math.Round(b.StartTime.Sub(now).Hours()*100) / 100,
+		}
+	} else {
+		ft, err := bookingFacilityType(ctx, db, b)
+		if err != nil {
+			return nil, err
+		}
+		decision = EvaluateRefund(*ft, b.StartTime, now)
+	}
+
+	// 条件取消：并发取消时只有状态实际被改为 cancelled 的请求继续退款
+	if err := db.CancelBooking(ctx, b.ID, opt.Reason); errors.Is(err, repo.ErrBookingNotActive) {
+		return nil, ErrAlreadyCancelled
+	} else if err != nil {
+		return nil, err
+	}
+	if err := CancelOpenPayments(ctx, db, b.ID); err != nil {
+		return nil, err
+	}
+	out := &CancelOutcome{Refund: decision, Refunds: []repo.PaymentRefund{}}
+	if cancelled, err := db.GetBookingByID(ctx, b.ID); err == nil {
+		out.Booking = cancelled
+	}
+	defer func() {
+		data := map[string]interface{}{"reason": opt.Reason}
+		if out.RefundAmount > 0 {
+			data["refund_amount"] = out.RefundAmount
+		}
+		notifyBooking(ctx, notifier, *b, firstNonEmpty(opt.NotifyKind, notify.KindBookingCancelled), data)
+	}()
+	if decision.Percent <= 0 {
+		return out, nil
+	}
+
+	payments, err := db.ListPaymentsByBooking(ctx, b.ID)
+	if err != nil {
+		return nil, err
+	}
+	percent := decision.Percent
+	for i := range payments {
+		p := &payments[i]
+		amount := math.Min(math.Round(p.Amount*float64(percent))/100, p.Refundable())
+		if amount <= 0 {
+			continue
+		}
+		r, err := refund(ctx, db, provider, p, repo.PaymentRefund{
+			Amount:  amount,
+			Reason:  firstNonEmpty(opt.Reason, "booking cancelled"),
+			Policy:  decision.Policy,
+			Percent: &percent,
+		})
+		if err != nil {
+			slog.Warn("cancellation refund failed", "booking_id", b.ID, "payment_id", p.ID, "err", err)
+			out.RefundError = err.Error()
+			continue
+		}
+		out.Refunds = append(out.Refunds, *r)
+		out.RefundAmount = math.Round((out.RefundAmount+r.Amount)*100) / 100
+	}
+	return out, nil
+}
+
+// bookingFacilityType 查询预约所在设施的类型配置
+func bookingFacilityType(ctx context.Context, db *repo.DB, b *repo.Booking) (*repo.FacilityType, error) {
+	unit, err := db.GetResourceUnitByID(ctx, b.ResourceUnitID)
+	if err != nil {
+		return nil, err
+	}
+	facility, err := db.GetFacilityByID(ctx, unit.FacilityID)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetFacilityType(ctx, facility.Type)
+}
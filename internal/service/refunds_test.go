@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 测试退款规则判定（中文说明：24 小时前全额，6 小时前 50%，之后不退）
+func TestEvaluateRefund(t *testing.T) {
+	ft := repo.FacilityType{RefundFullHours: 24, RefundPartialHours: 6, RefundPartialPercent: 50}
+	start := time.Date(2025, 3, 10, 18, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		before  time.Duration
+		policy  string
+		percent int
+	}{
+		{48 * time.Hour, RefundPolicyFull, 100},
+		{24 * time.Hour, RefundPolicyFull, 100},
+		{10 * time.Hour, RefundPolicyPartial, 50},
+		{6 * time.Hour, RefundPolicyPartial, 50},
+		{2 * time.Hour, RefundPolicyNone, 0},
+		{-time.Hour, RefundPolicyNone, 0},
+	}
+	for _, tc := range cases {
+		d := EvaluateRefund(ft, start, start.Add(-tc.before))
+		if d.Policy != tc.policy || d.Percent != tc.percent {
+			t.Fatalf("%v before start: expected %s/%d, got %+v", tc.before, tc.policy, tc.percent, d)
+		}
+	}
+
+	// 未配置部分退款比例时，全额窗口之后不退
+	noPartial := repo.FacilityType{RefundFullHours: 24, RefundPartialHours: 6}
+	if d := EvaluateRefund(noPartial, start, start.Add(-10*time.Hour)); d.Policy != RefundPolicyNone {
+		t.Fatalf("expected none without partial percent, got %+v", d)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// reminderLease 提醒任务的租约名称
+const reminderLease = "booking_reminders"
+
+// ReminderRule 定时提醒规则（中文说明：到点 At 后 Window 内仍可补发，超过则跳过；
+// 例如开场前 10 小时才下单的预约不会再收到 24 小时提醒，只收到 2 小时提醒）
+type ReminderRule struct {
+	Kind    string
+	Offset  time.Duration // 相对开场时间（FromEnd 时相对结束时间）的偏移，负值表示之前
+	FromEnd bool
+	Window  time.Duration
+	// OptedOut 用户是否退订此类提醒
+	OptedOut func(c *repo.NotificationContact) bool
+}
+
+// At 该预约的提醒时间
+func (r ReminderRule) At(b repo.Booking) time.Time {
+	if r.FromEnd {
+		return b.EndTime.Add(r.Offset)
+	}
+	return b.StartTime.Add(r.Offset)
+}
+
+// Due 该提醒此刻是否应发送
+func (r ReminderRule) Due(b repo.Booking, now time.Time) bool {
+	at := r.At(b)
+	return !now.Before(at) && now.Before(at.Add(r.Window))
+}
+
+func reminderOptedOut(c *repo.NotificationContact) bool { return c.ReminderOptOut }
+func ratingOptedOut(c *repo.NotificationContact) bool   { return c.RatingOptOut }
+
+// DefaultReminderRules 开场前 24 小时、2 小时提醒与结束后 30 分钟的评分邀请
+var DefaultReminderRules = []ReminderRule{
+	{Kind: notify.KindReminder24h, Offset: -24 * time.Hour, Window: time.Hour, OptedOut: reminderOptedOut},
+	{Kind: notify.KindReminder2h, Offset: -2 * time.Hour, Window: 30 * time.Minute, OptedOut: reminderOptedOut},
+	{Kind: notify.KindRatingPrompt, Offset: 30 * time.Minute, FromEnd: true, Window: 6 * time.Hour, OptedOut: ratingOptedOut},
+}
+
+// SendDueReminders 扫描到期的提醒并通知预约人，返回发送数量
+// 中文说明：每种提醒先在 booking_reminders 登记再入队，重复扫描或多实例不会重复发送；入队失败时撤销登记，下次扫描重试
+func SendDueReminders(ctx context.Context, db *repo.DB, notifier notify.Notifier, rules []ReminderRule, now time.Time) (int, error) {
+	sent := 0
+	for _, rule := range rules {
+		// 提醒时间落在 (now-Window, now] 的预约；查询按秒取整放宽一秒，由 Due 精确判断
+		from, to := now.Add(-rule.Window-rule.Offset), now.Add(-rule.Offset)
+		list := db.ListBookingsStartingBetween
+		if rule.FromEnd {
+			list = db.ListBookingsEndingBetween
+		}
+		bookings, err := list(ctx, from, to.Add(time.Second))
+		if err != nil {
+			return sent, err
+		}
+		for _, b := range bookings {
+			if !rule.Due(b, now) {
+				continue
+			}
+			ok, err := sendReminder(ctx, db, notifier, rule, b)
+			if err != nil {
+				slog.Warn("send reminder failed", "booking_id", b.ID, "kind", rule.Kind, "err", err)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+	return sent, nil
+}
+
+func sendReminder(ctx context.Context, db *repo.DB, notifier notify.Notifier, rule ReminderRule, b repo.Booking) (bool, error) {
+	contact, err := db.GetNotificationContact(ctx, b.UserID)
+	if err != nil {
+		return false, err
+	}
+	if rule.OptedOut != nil && rule.OptedOut(contact) {
+		return false, nil
+	}
+	claimed, err := db.ClaimBookingReminder(ctx, b.ID, rule.Kind)
+	if err != nil || !claimed {
+		return false, err
+	}
+	if err := notifier.Notify(ctx, bookingNotification(b, rule.Kind, nil)); err != nil {
+		if rerr := db.ReleaseBookingReminder(ctx, b.ID, rule.Kind); rerr != nil {
+			slog.Warn("release reminder failed", "booking_id", b.ID, "kind", rule.Kind, "err", rerr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// RunReminderScheduler 定期发送预约提醒，直到 ctx 结束
+// 中文说明：多实例部署时通过数据库租约选出一个实例执行扫描（租期为 3 个周期，持有者每周期续约，退出后由其它实例接管）
+func RunReminderScheduler(ctx context.Context, db *repo.DB, notifier notify.Notifier, interval time.Duration) {
+	holder, err := instanceName()
+	if err != nil {
+		slog.Error("reminder scheduler disabled", "err", err)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			leader, err := db.AcquireSchedulerLease(ctx, reminderLease, holder, 3*interval)
+			if err != nil {
+				slog.Warn("acquire reminder lease failed", "err", err)
+				continue
+			}
+			if !leader {
+				continue
+			}
+			if n, err := SendDueReminders(ctx, db, notifier, DefaultReminderRules, now); err != nil {
+				slog.Warn("send reminders failed", "err", err)
+			} else if n > 0 {
+				slog.Info("sent booking reminders", "count", n)
+			}
+		}
+	}
+}
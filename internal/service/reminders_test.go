@@ -0,0 +1,53 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+func reminderRule(kind string) ReminderRule {
+	for _, r := range DefaultReminderRules {
+		if r.Kind == kind {
+			return r
+		}
+	}
+	panic("no rule " + kind)
+}
+
+// 测试提醒窗口：到点后窗口内发送，错过窗口（如临近开场才下单）不再补发
+func TestReminderRuleDue(t *testing.T) {
+	start := time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)
+	b := repo.Booking{StartTime: start, EndTime: start.Add(time.Hour)}
+	r24, r2, rating := reminderRule(notify.KindReminder24h), reminderRule(notify.KindReminder2h), reminderRule(notify.KindRatingPrompt)
+
+	cases := []struct {
+		rule ReminderRule
+		now  time.Time
+		want bool
+	}{
+		{r24, start.Add(-24 * time.Hour), true},
+		{r24, start.Add(-24*time.Hour - time.Second), false},
+		{r24, start.Add(-23*time.Hour - 30*time.Minute), true},
+		{r24, start.Add(-10 * time.Hour), false},
+		{r2, start.Add(-2 * time.Hour), true},
+		{r2, start.Add(-time.Hour), false},
+		{rating, start.Add(time.Hour), false},
+		{rating, start.Add(90 * time.Minute), true},
+		{rating, start.Add(8 * time.Hour), false},
+	}
+	for i, c := range cases {
+		if got := c.rule.Due(b, c.now); got != c.want {
+			t.Fatalf("case %d (%s at %v): expected %v", i, c.rule.Kind, c.now, c.want)
+		}
+	}
+}
+
+func TestReminderOptOut(t *testing.T) {
+	c := &repo.NotificationContact{ReminderOptOut: true}
+	if !reminderRule(notify.KindReminder2h).OptedOut(c) || reminderRule(notify.KindRatingPrompt).OptedOut(c) {
+		t.Fatal("reminder opt-out should not affect rating prompts")
+	}
+}
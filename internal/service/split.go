@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// DefaultSplitDeadline 未指定截止时间时分摊支付的期限（不晚于开场时间）
+const DefaultSplitDeadline = 24 * time.Hour
+
+// MaxSplitInvitees 单个预约最多邀请的分摊人数
+const MaxSplitInvitees = 15
+
+// ErrSplitUnavailable 预约不满足分摊支付条件
+var ErrSplitUnavailable = errors.New("split payment not available")
+
+// ErrShareClosed 份额已付清、已补足或已释放，不能再支付
+var ErrShareClosed = errors.New("share is no longer payable")
+
+// Invitee 分摊受邀人（中文说明：UserID 与 Email 二选一）
+type Invitee struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// SplitRequest 创建预约时的分摊请求（中文说明：Deadline 为零值时使用默认期限）
+type SplitRequest struct {
+	Invitees []Invitee
+	Deadline time.Time
+}
+
+// SplitAmounts 将金额按人数均分到分（中文说明：除不尽的零头计入第一份，即发起人份额）
+func SplitAmounts(total float64, n int) []float64 {
+	if n <= 0 {
+		return nil
+	}
+	cents := int64(math.Round(total * 100))
+	each := cents / int64(n)
+	res := make([]float64, n)
+	for i := range res {
+		res[i] = float64(each) / 100
+	}
+	res[0] = float64(each+cents%int64(n)) / 100
+	return res
+}
+
+// ValidateInvitees 校验受邀人（中文说明：每人须且只能提供 user_id 或 email，不能重复，不能邀请发起人自己）
+func ValidateInvitees(organizerID string, invitees []Invitee) error {
+	if len(invitees) == 0 {
+		return fmt.Errorf("%w: at least one invitee required", ErrSplitUnavailable)
+	}
+	if len(invitees) > MaxSplitInvitees {
+		return fmt.Errorf("%w: at most %d invitees", ErrSplitUnavailable, MaxSplitInvitees)
+	}
+	seen := map[string]bool{organizerID: true}
+	for _, inv := range invitees {
+		key := inv.UserID
+		if (inv.UserID == "") == (inv.Email == "") {
+			return fmt.Errorf("%w: each invitee needs exactly one of user_id or email", ErrSplitUnavailable)
+		}
+		if inv.Email != "" {
+			if !strings.Contains(inv.Email, "@") {
+				return fmt.Errorf("%w: invalid email %q", ErrSplitUnavailable, inv.Email)
+			}
+			key = strings.ToLower(inv.Email)
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: duplicate invitee %s", ErrSplitUnavailable, key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// SplitDeadline 计算分摊截止时间（中文说明：默认 24 小时且不晚于开场；指定值须在当前时间之后、开场之前）
+func SplitDeadline(requested, now, start time.Time) (time.Time, error) {
+	if requested.IsZero() {
+		d := now.Add(DefaultSplitDeadline)
+		if d.After(start) {
+			d = start
+		}
+		return d, nil
+	}
+	if !requested.After(now) || requested.After(start) {
+		return time.Time{}, fmt.Errorf("%w: deadline must be between now and the start time", ErrSplitUnavailable)
+	}
+	return requested, nil
+}
+
+// newToken 生成 128 位随机凭证（份额支付链接、场地签到码、日历订阅地址）
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ShareLink 份额的支付链接路径
+func ShareLink(token string) string {
+	return "/shares/" + token
+}
+
+// createShares 为 pending 预约写入发起人与受邀人的份额
+func createShares(ctx context.Context, db *repo.DB, b *repo.Booking, organizerID string, req SplitRequest, deadline time.Time) ([]repo.BookingShare, error) {
+	amounts := SplitAmounts(b.Price, len(req.Invitees)+1)
+	shares := make([]repo.BookingShare, len(amounts))
+	for i, amount := range amounts {
+		token, err := newToken()
+		if err != nil {
+			return nil, err
+		}
+		s := repo.BookingShare{BookingID: b.ID, Amount: amount, Token: token, Deadline: deadline}
+		if i == 0 {
+			s.UserID = organizerID
+			s.IsOrganizer = true
+		} else {
+			s.UserID = req.Invitees[i-1].UserID
+			s.Email = strings.ToLower(req.Invitees[i-1].Email)
+		}
+		shares[i] = s
+	}
+	return db.CreateBookingShares(ctx, shares)
+}
+
+// PayShare 为份额创建支付意图（中文说明：凭支付链接调用，未注册受邀人也可支付）
+func PayShare(ctx context.Context, db *repo.DB, provider payment.Provider, currency string, share *repo.BookingShare, now time.Time) (*repo.Payment, string, error) {
+	if provider == nil {
+		return nil, "", fmt.Errorf("%w: payments not configured", ErrSplitUnavailable)
+	}
+	if share.Status != repo.SharePending || !now.Before(share.Deadline) {
+		return nil, "", ErrShareClosed
+	}
+	b, err := db.GetBookingByID(ctx, share.BookingID)
+	if err != nil {
+		return nil, "", err
+	}
+	if b.Status != "pending" {
+		return nil, "", ErrShareClosed
+	}
+	shareID := share.ID
+	return startPayment(ctx, db, provider, currency, repo.Payment{
+		BookingID: share.BookingID,
+		UserID:    share.UserID,
+		Kind:      repo.PaymentKindShare,
+		ShareID:   &shareID,
+		Amount:    share.Amount,
+	}, fmt.Sprintf("Booking #%d share", share.BookingID))
+}
+
+// CoverShares 发起人补足所有未付份额（中文说明：支付成功后未付份额标记为 covered，预约随即确认）
+func CoverShares(ctx context.Context, db *repo.DB, provider payment.Provider, currency string, b *repo.Booking, now time.Time) (*repo.Payment, string, error) {
+	if provider == nil {
+		return nil, "", fmt.Errorf("%w: payments not configured", ErrSplitUnavailable)
+	}
+	if b.Status != "pending" {
+		return nil, "", fmt.Errorf("%w: booking is %s", ErrSplitUnavailable, b.Status)
+	}
+	shares, err := db.ListBookingShares(ctx, b.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	remaining := 0.0
+	for _, s := range shares {
+		if s.Status != repo.SharePending {
+			continue
+		}
+		if !now.Before(s.Deadline) {
+			return nil, "", fmt.Errorf("%w: deadline has passed", ErrSplitUnavailable)
+		}
+		remaining += s.Amount
+	}
+	remaining = math.Round(remaining*100) / 100
+	if remaining <= 0 {
+		return nil, "", fmt.Errorf("%w: no unpaid shares", ErrSplitUnavailable)
+	}
+	return startPayment(ctx, db, provider, currency, repo.Payment{
+		BookingID: b.ID,
+		UserID:    b.UserID,
+		Kind:      repo.PaymentKindCover,
+		Amount:    remaining,
+	}, fmt.Sprintf("Booking #%d remaining shares", b.ID))
+}
+
+// startPayment 创建支付意图并写入支付记录
+func startPayment(ctx context.Context, db *repo.DB, provider payment.Provider, currency string, p repo.Payment, description string) (*repo.Payment, string, error) {
+	intent, err := provider.CreateIntent(ctx, payment.IntentRequest{
+		Reference:   fmt.Sprintf("%d", p.BookingID),
+		Amount:      p.Amount,
+		Currency:    currency,
+		Description: description,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("create payment: %w", err)
+	}
+	p.Provider = provider.Name()
+	p.ProviderRef = intent.Ref
+	p.Currency = currency
+	p.Status = repo.PaymentRequiresPayment
+	created, err := db.CreatePayment(ctx, p)
+	if err != nil {
+		return nil, "", err
+	}
+	return created, intent.ClientSecret, nil
+}
+
+// settleSharePayment 份额支付成功：标记份额已付；份额已不可支付时全额退回
+func settleSharePayment(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, p *repo.Payment) error {
+	if p.ShareID == nil {
+		return errors.New("share payment without share")
+	}
+	paid, err := db.TransitionBookingShares(ctx, p.BookingID, *p.ShareID, repo.SharePending, repo.SharePaid)
+	if err != nil {
+		return err
+	}
+	if len(paid) == 0 {
+		_, err := refund(ctx, db, provider, p, repo.PaymentRefund{Reason: "share no longer payable", Policy: RefundPolicyFull})
+		return err
+	}
+	return confirmIfSettled(ctx, db, notifier, provider, p)
+}
+
+// settleCoverPayment 补足支付成功：剩余份额标记为 covered，多付部分（期间有人付清）退回
+func settleCoverPayment(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, p *repo.Payment) error {
+	covered, err := db.TransitionBookingShares(ctx, p.BookingID, 0, repo.SharePending, repo.ShareCovered)
+	if err != nil {
+		return err
+	}
+	sum := 0.0
+	for _, s := range covered {
+		sum += s.Amount
+	}
+	if excess := math.Round((p.Amount-sum)*100) / 100; excess > 0 {
+		r, err := refund(ctx, db, provider, p, repo.PaymentRefund{Amount: excess, Reason: "shares paid before cover completed", Policy: RefundPolicyFull})
+		if err != nil {
+			return err
+		}
+		p.RefundedAmount = math.Round((p.RefundedAmount+r.Amount)*100) / 100
+		if p.RefundedAmount >= p.Amount {
+			p.Status = repo.PaymentRefunded
+		} else {
+			p.Status = repo.PaymentPartiallyRefunded
+		}
+	}
+	// 受邀人尚未完成的支付意图不再需要
+	if err := CancelOpenPayments(ctx, db, p.BookingID); err != nil {
+		return err
+	}
+	return confirmIfSettled(ctx, db, notifier, provider, p)
+}
+
+// confirmIfSettled 所有份额付清或补足后确认预约；预约已不在占位状态时退回该笔支付
+func confirmIfSettled(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, p *repo.Payment) error {
+	shares, err := db.ListBookingShares(ctx, p.BookingID)
+	if err != nil {
+		return err
+	}
+	for _, s := range shares {
+		if s.Status == repo.SharePending {
+			return nil
+		}
+	}
+	confirmed, err := db.ConfirmBooking(ctx, p.BookingID)
+	if err != nil {
+		return err
+	}
+	if confirmed {
+		notifyConfirmed(ctx, db, notifier, p.BookingID)
+		return nil
+	}
+	if p.Refundable() <= 0 {
+		return nil
+	}
+	_, err = refund(ctx, db, provider, p, repo.PaymentRefund{Reason: "booking no longer held", Policy: RefundPolicyFull})
+	return err
+}
+
+// ExpireSplitBookings 释放截止时间已过仍有未付份额的预约，返回释放数量
+// 中文说明：未付份额标记为 released，预约取消并全额退回已付份额
+func ExpireSplitBookings(ctx context.Context, db *repo.DB, notifier notify.Notifier, provider payment.Provider, now time.Time) (int, error) {
+	ids, err := db.ListOverdueShareBookings(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	full := 100
+	for _, id := range ids {
+		if _, err := db.TransitionBookingShares(ctx, id, 0, repo.SharePending, repo.ShareReleased); err != nil {
+			return n, err
+		}
+		b, err := db.GetBookingByID(ctx, id)
+		if err != nil {
+			return n, err
+		}
+		if b.Status != "pending" {
+			continue
+		}
+		out, err := CancelBookingWithRefund(ctx, db, notifier, provider, b, now, CancelOptions{Reason: "split payment deadline passed", OverridePercent: &full})
+		if err != nil {
+			return n, err
+		}
+		if out.RefundError != "" {
+			slog.Warn("split release refund failed", "booking_id", id, "err", out.RefundError)
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// 测试分摊金额（中文说明：按分均分，零头计入发起人份额，总和不变）
+func TestSplitAmounts(t *testing.T) {
+	got := SplitAmounts(100, 3)
+	want := []float64{33.34, 33.33, 33.33}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if got := SplitAmounts(60, 4); got[0] != 15 || got[3] != 15 {
+		t.Fatalf("expected equal shares, got %v", got)
+	}
+	if SplitAmounts(10, 0) != nil {
+		t.Fatal("expected nil for zero shares")
+	}
+}
+
+func TestValidateInvitees(t *testing.T) {
+	ok := []Invitee{{UserID: "u2"}, {Email: "a@example.com"}}
+	if err := ValidateInvitees("u1", ok); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	bad := map[string][]Invitee{
+		"empty":        nil,
+		"both":         {{UserID: "u2", Email: "a@example.com"}},
+		"neither":      {{}},
+		"organizer":    {{UserID: "u1"}},
+		"duplicate":    {{Email: "A@example.com"}, {Email: "a@example.com"}},
+		"invalid mail": {{Email: "nobody"}},
+	}
+	for name, inv := range bad {
+		if err := ValidateInvitees("u1", inv); !errors.Is(err, ErrSplitUnavailable) {
+			t.Fatalf("%s: expected ErrSplitUnavailable, got %v", name, err)
+		}
+	}
+}
+
+// 测试分摊截止时间（中文说明：默认 24 小时且不晚于开场）
+func TestSplitDeadline(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	farStart := now.Add(72 * time.Hour)
+	if d, err := SplitDeadline(time.Time{}, now, farStart); err != nil || !d.Equal(now.Add(DefaultSplitDeadline)) {
+		t.Fatalf("expected default deadline, got %v %v", d, err)
+	}
+	soon := now.Add(3 * time.Hour)
+	if d, _ := SplitDeadline(time.Time{}, now, soon); !d.Equal(soon) {
+		t.Fatalf("default deadline must not pass start, got %v", d)
+	}
+	if _, err := SplitDeadline(now.Add(-time.Minute), now, farStart); !errors.Is(err, ErrSplitUnavailable) {
+		t.Fatalf("expected error for past deadline, got %v", err)
+	}
+	if _, err := SplitDeadline(farStart.Add(time.Hour), now, farStart); !errors.Is(err, ErrSplitUnavailable) {
+		t.Fatalf("expected error for deadline after start, got %v", err)
+	}
+}
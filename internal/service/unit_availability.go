@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// unitVenue 单元所在场馆（营业时间与时区）
+func unitVenue(ctx context.Context, db *repo.DB, u repo.ResourceUnit) (*repo.Venue, error) {
+	venueID := u.VenueID
+	if venueID == 0 {
+		f, err := db.GetFacilityByID(ctx, u.FacilityID)
+		if err != nil {
+			return nil, err
+		}
+		venueID = f.VenueID
+	}
+	return db.GetVenueByID(ctx, venueID)
+}
+
+// UnitFreeRanges 计算单元在某天的空闲时段（中文说明：day 的年月日按场馆时区解释，场馆营业时间扣除当天预约与封场）
+func UnitFreeRanges(ctx context.Context, db *repo.DB, u repo.ResourceUnit, day time.Time, minDur time.Duration) ([]TimeRange, error) {
+	v, err := unitVenue(ctx, db, u)
+	if err != nil {
+		return nil, err
+	}
+	return unitFreeRangesWithin(ctx, db, u, OpeningHoursForDay(day, *v), minDur)
+}
+
+// unitFreeRangesWithin 营业窗口 oh 扣除其中的预约与封场
+func unitFreeRangesWithin(ctx context.Context, db *repo.DB, u repo.ResourceUnit, oh TimeRange, minDur time.Duration) ([]TimeRange, error) {
+	bookings, err := db.ListBookingsForUnitBetween(ctx, u.ID, oh.Start, oh.End)
+	if err != nil {
+		return nil, err
+	}
+	blackouts, err := db.ListBlackoutsForUnitOrFacilityBetween(ctx, u.FacilityID, u.ID, oh.Start, oh.End)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []TimeRange
+	for _, b := range bookings {
+		blocks = append(blocks, TimeRange{Start: b.StartTime, End: b.EndTime})
+	}
+	for _, b := range blackouts {
+		blocks = append(blocks, TimeRange{Start: b.StartTime, End: b.EndTime})
+	}
+	return SubtractRanges(oh, blocks, minDur), nil
+}
+
+// fits 判断 [start, end) 是否完整落在某个空闲段内
+func fits(free []TimeRange, start, end time.Time) bool {
+	for _, f := range free {
+		if !start.Before(f.Start) && !end.After(f.End) {
+			return true
+		}
+	}
+	return false
+}
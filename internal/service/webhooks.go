@@ -0,0 +1,203 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// Webhook 请求头（中文说明：签名格式与支付回调相同，t=<unix 秒>,v1=<hex(HMAC-SHA256(secret, "t.body"))>）
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Webhook 投递参数
+const (
+	WebhookBatchSize   = 20
+	WebhookLease       = 5 * time.Minute // 领取后租期，需大于一批请求的总超时（20 × 10 秒）
+	WebhookTimeout     = 10 * time.Second
+	MaxWebhookAttempts = 10
+	maxWebhookBackoff  = 6 * time.Hour
+)
+
+// WebhookBackoff 第 attempts 次投递失败后的等待时间（中文说明：30 秒起按 2 倍递增，最长 6 小时，10 次共约 4 小时）
+func WebhookBackoff(attempts int) time.Duration {
+	return expBackoff(attempts, 30*time.Second, maxWebhookBackoff)
+}
+
+// NewWebhookSecret 生成 Webhook 签名密钥
+func NewWebhookSecret() (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
+
+// webhookPayload Webhook 请求体（中文说明：data.object 为变更后的实体，data.previous 为变更前的实体）
+type webhookPayload struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+func newWebhookPayload(id string, e events.Event) ([]byte, error) {
+	data := map[string]interface{}{"object": e.Data}
+	if e.Previous != nil {
+		data["previous"] = e.Previous
+	}
+	return json.Marshal(webhookPayload{ID: id, Type: e.Type, CreatedAt: e.OccurredAt, Data: data})
+}
+
+// WebhookPublisher 为订阅了事件类型的地址写入投递记录（中文说明：实现 events.Publisher，由 RunWebhookDispatch 投递）
+type WebhookPublisher struct {
+	db *repo.DB
+}
+
+// NewWebhookPublisher 创建 Webhook 发布器
+func NewWebhookPublisher(db *repo.DB) *WebhookPublisher {
+	return &WebhookPublisher{db: db}
+}
+
+// Publish 实现 events.Publisher 接口
+func (w *WebhookPublisher) Publish(ctx context.Context, e events.Event) {
+	endpoints, err := w.db.ListSubscribedWebhookEndpoints(ctx, e.Type)
+	if err != nil {
+		slog.Warn("list webhook endpoints failed", "event", e.Type, "err", err)
+		return
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+	token, err := newToken()
+	if err != nil {
+		slog.Warn("webhook event id failed", "event", e.Type, "err", err)
+		return
+	}
+	eventID := "evt_" + token
+	body, err := newWebhookPayload(eventID, e)
+	if err != nil {
+		slog.Warn("encode webhook payload failed", "event", e.Type, "err", err)
+		return
+	}
+	deliveries := make([]repo.WebhookDelivery, len(endpoints))
+	for i, ep := range endpoints {
+		deliveries[i] = repo.WebhookDelivery{EndpointID: ep.ID, EventID: eventID, EventType: e.Type, Payload: body}
+	}
+	if err := w.db.EnqueueWebhookDeliveries(ctx, deliveries); err != nil {
+		slog.Warn("enqueue webhook deliveries failed", "event", e.Type, "event_id", eventID, "err", err)
+	}
+}
+
+// deliverWebhook 以时间戳 ts 签名并 POST 一次投递，返回响应状态码（未收到响应时为 0）；非 2xx 视为失败
+func deliverWebhook(ctx context.Context, client *http.Client, ep repo.WebhookEndpoint, d repo.WebhookDelivery, ts time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sport_backend-webhooks/1")
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookDeliveryHeader, d.EventID)
+	req.Header.Set(WebhookSignatureHeader, payment.Sign(ep.Secret, d.Payload, ts))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// DispatchWebhooks 投递一批到期的 Webhook，返回成功数量
+// 中文说明：每次尝试都记录到 webhook_delivery_attempts；失败按 WebhookBackoff 退避，超过 MaxWebhookAttempts 次标记为 failed
+func DispatchWebhooks(ctx context.Context, db *repo.DB, client *http.Client, now time.Time) (int, error) {
+	list, err := db.ClaimWebhookDeliveries(ctx, WebhookBatchSize, WebhookLease)
+	if err != nil || len(list) == 0 {
+		return 0, err
+	}
+	ids := make([]int64, 0, len(list))
+	for _, d := range list {
+		ids = append(ids, d.EndpointID)
+	}
+	endpoints, err := db.GetWebhookEndpointsByIDs(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, d := range list {
+		ep, ok := endpoints[d.EndpointID]
+		if !ok || !ep.IsActive {
+			// 地址已停用：不再投递，管理员重新启用后可重放
+			if err := db.MarkWebhookRetry(ctx, d.ID, nil, "endpoint disabled", nil); err != nil {
+				slog.Warn("record webhook failure failed", "id", d.ID, "err", err)
+			}
+			continue
+		}
+		started := time.Now()
+		code, sendErr := deliverWebhook(ctx, client, ep, d, started)
+		attempt := repo.WebhookAttempt{DeliveryID: d.ID, Attempt: d.Attempts, DurationMs: int(time.Since(started).Milliseconds())}
+		var status *int
+		if code > 0 {
+			status = &code
+			attempt.StatusCode = status
+		}
+		if sendErr != nil {
+			attempt.Error = sendErr.Error()
+		}
+		if err := db.RecordWebhookAttempt(ctx, attempt); err != nil {
+			slog.Warn("record webhook attempt failed", "id", d.ID, "err", err)
+		}
+		if sendErr == nil {
+			if err := db.MarkWebhookDelivered(ctx, d.ID, code, now); err != nil {
+				slog.Warn("mark webhook delivered failed", "id", d.ID, "err", err)
+			}
+			sent++
+			continue
+		}
+		var next *time.Time
+		if d.Attempts < MaxWebhookAttempts {
+			t := now.Add(WebhookBackoff(d.Attempts))
+			next = &t
+		}
+		slog.Warn("deliver webhook failed", "id", d.ID, "endpoint_id", d.EndpointID, "attempts", d.Attempts, "err", sendErr)
+		if err := db.MarkWebhookRetry(ctx, d.ID, status, sendErr.Error(), next); err != nil {
+			slog.Warn("record webhook failure failed", "id", d.ID, "err", err)
+		}
+	}
+	return sent, nil
+}
+
+// RunWebhookDispatch 定期投递 Webhook，直到 ctx 结束
+func RunWebhookDispatch(ctx context.Context, db *repo.DB, interval time.Duration) {
+	client := &http.Client{Timeout: WebhookTimeout}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := DispatchWebhooks(ctx, db, client, now); err != nil {
+				slog.Warn("dispatch webhooks failed", "err", err)
+			} else if n > 0 {
+				slog.Info("delivered webhooks", "count", n)
+			}
+		}
+	}
+}
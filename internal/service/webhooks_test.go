@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Juny09/sport_backend/internal/events"
+	"github.com/Juny09/sport_backend/internal/payment"
+	"github.com/Juny09/sport_backend/internal/repo"
+)
+
+// 测试 Webhook 退避：30 秒起翻倍，封顶 6 小时
+func TestWebhookBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		10: 256 * time.Minute,
+		11: 6 * time.Hour,
+		50: 6 * time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := WebhookBackoff(attempts); got != want {
+			t.Fatalf("attempts %d: expected %v, got %v", attempts, want, got)
+		}
+	}
+}
+
+// 测试请求体：新建事件没有 previous，改签事件带变更前的实体
+func TestNewWebhookPayload(t *testing.T) {
+	at := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	before := repo.Booking{ID: 7, StartTime: at}
+	after := repo.Booking{ID: 7, StartTime: at.Add(time.Hour)}
+
+	body, err := newWebhookPayload("evt_1", events.Event{Type: events.BookingRescheduled, OccurredAt: at, Data: after, Previous: before})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object   *repo.Booking `json:"object"`
+			Previous *repo.Booking `json:"previous"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "evt_1" || got.Type != events.BookingRescheduled {
+		t.Fatalf("unexpected envelope: %s", body)
+	}
+	if got.Data.Object == nil || !got.Data.Object.StartTime.Equal(after.StartTime) {
+		t.Fatalf("expected object to be the new booking: %s", body)
+	}
+	if got.Data.Previous == nil || !got.Data.Previous.StartTime.Equal(before.StartTime) {
+		t.Fatalf("expected previous booking: %s", body)
+	}
+
+	body, err = newWebhookPayload("evt_2", events.Event{Type: events.BookingCreated, OccurredAt: at, Data: after})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw.Data["previous"]; ok {
+		t.Fatalf("created event should not carry previous: %s", body)
+	}
+}
+
+// 测试投递：签名可由接收方按支付回调相同的方式校验，非 2xx 视为失败
+func TestDeliverWebhook(t *testing.T) {
+	const secret = "whsec_test"
+	status := http.StatusNoContent
+	var verifyErr error
+	var event, delivery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = payment.VerifySignature(secret, body, r.Header.Get(WebhookSignatureHeader), time.Now())
+		event, delivery = r.Header.Get(WebhookEventHeader), r.Header.Get(WebhookDeliveryHeader)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	ep := repo.WebhookEndpoint{ID: 1, URL: srv.URL, Secret: secret, IsActive: true}
+	d := repo.WebhookDelivery{ID: 3, EndpointID: 1, EventID: "evt_1", EventType: events.BookingCreated, Payload: json.RawMessage(`{"id":"evt_1"}`)}
+
+	code, err := deliverWebhook(context.Background(), srv.Client(), ep, d, time.Now())
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("expected success, got %d %v", code, err)
+	}
+	if verifyErr != nil {
+		t.Fatalf("signature did not verify: %v", verifyErr)
+	}
+	if event != events.BookingCreated || delivery != "evt_1" {
+		t.Fatalf("unexpected headers: event=%q delivery=%q", event, delivery)
+	}
+
+	status = http.StatusInternalServerError
+	code, err = deliverWebhook(context.Background(), srv.Client(), ep, d, time.Now())
+	if err == nil || code != http.StatusInternalServerError {
+		t.Fatalf("expected failure with status 500, got %d %v", code, err)
+	}
+}
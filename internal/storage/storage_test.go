@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+)
+
+// 测试本地存储读写与缩略图尺寸（中文说明：key 不能越出根目录）
+func TestLocalStoreAndThumbnail(t *testing.T) {
+	s := NewLocalStore(t.TempDir(), "/media/")
+	ctx := context.Background()
+
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 200; y++ {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	thumb, err := Thumbnail(buf.Bytes(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 100 || cfg.Height != 50 {
+		t.Fatalf("expected 100x50 thumbnail, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	if err := s.Put(ctx, "facilities/1/../../a.jpg", bytes.NewReader(thumb), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := s.Open(ctx, "a.jpg")
+	if err != nil {
+		t.Fatalf("cleaned key should stay inside root: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(got, thumb) {
+		t.Fatalf("content mismatch")
+	}
+	if u := s.URL("a.jpg"); u != "/media/a.jpg" {
+		t.Fatalf("unexpected url %s", u)
+	}
+	if err := s.Delete(ctx, "a.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, "a.jpg"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// 测试解压炸弹：头部声明超大尺寸的图片不解码，直接返回 ErrImageTooLarge
+func TestThumbnailRejectsHugeDimensions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	// 改写 IHDR 宽高为 100000x100000 并重算 CRC（签名 8 字节 + 长度 4 字节后为 "IHDR" 与 13 字节数据）
+	data := buf.Bytes()
+	ihdr := data[12 : 12+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:8], 100000)
+	binary.BigEndian.PutUint32(ihdr[8:12], 100000)
+	binary.BigEndian.PutUint32(data[12+4+13:], crc32.ChecksumIEEE(ihdr))
+
+	if _, err := Thumbnail(data, 100); !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("expected ErrImageTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package ticket
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// version 票据格式版本前缀
+const version = "T1"
+
+// sigBytes 截断后的签名长度（128 位，兼顾二维码尺寸与安全性）
+const sigBytes = 16
+
+// 错误定义
+var (
+	ErrInvalidTicket  = errors.New("invalid ticket")
+	ErrTicketNotValid = errors.New("ticket not valid yet")
+	ErrTicketExpired  = errors.New("ticket expired")
+)
+
+// Claims 票据内容（中文说明：预约 ID、单元与时间窗口；时间精确到秒）
+type Claims struct {
+	BookingID int64     `json:"booking_id"`
+	UnitID    int64     `json:"unit_id"`
+	Start     time.Time `json:"start_time"`
+	End       time.Time `json:"end_time"`
+}
+
+// CheckWindow 校验票据在 now 时刻可用（中文说明：开场前 opensBefore 起至结束时间）
+func (c Claims) CheckWindow(now time.Time, opensBefore time.Duration) error {
+	if now.Before(c.Start.Add(-opensBefore)) {
+		return ErrTicketNotValid
+	}
+	if !now.Before(c.End) {
+		return ErrTicketExpired
+	}
+	return nil
+}
+
+// Signer 票据签发与校验（中文说明：HMAC-SHA256，密钥仅服务端持有；校验不依赖数据库）
+type Signer struct {
+	key []byte
+}
+
+// NewSigner 创建签发器（中文说明：由 secret 派生票据专用密钥，与其它用途的签名互不通用）
+func NewSigner(secret string) *Signer {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("booking-ticket"))
+	return &Signer{key: mac.Sum(nil)}
+}
+
+// Sign 签发紧凑票据：T1.<base64url(载荷)>.<base64url(签名)>
+// 中文说明：载荷为变长整数编码的预约 ID、单元 ID、开始时间（unix 秒）与时长（秒）
+func (s *Signer) Sign(c Claims) string {
+	buf := make([]byte, 0, 4*binary.MaxVarintLen64)
+	buf = binary.AppendUvarint(buf, uint64(c.BookingID))
+	buf = binary.AppendUvarint(buf, uint64(c.UnitID))
+	buf = binary.AppendVarint(buf, c.Start.Unix())
+	buf = binary.AppendUvarint(buf, uint64(c.End.Sub(c.Start)/time.Second))
+	payload := base64.RawURLEncoding.EncodeToString(buf)
+	return version + "." + payload + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+func (s *Signer) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(version + "." + payload))
+	return mac.Sum(nil)[:sigBytes]
+}
+
+// Verify 校验签名并解析票据（中文说明：不检查时间窗口，见 Claims.CheckWindow）
+func (s *Signer) Verify(token string) (Claims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 || parts[0] != version {
+		return Claims{}, ErrInvalidTicket
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, s.sign(parts[1])) {
+		return Claims{}, ErrInvalidTicket
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidTicket
+	}
+	var vals [4]int64
+	for i := range vals {
+		var n int
+		if i == 2 {
+			vals[i], n = binary.Varint(buf)
+		} else {
+			var u uint64
+			u, n = binary.Uvarint(buf)
+			vals[i] = int64(u)
+		}
+		if n <= 0 {
+			return Claims{}, ErrInvalidTicket
+		}
+		buf = buf[n:]
+	}
+	if len(buf) != 0 {
+		return Claims{}, ErrInvalidTicket
+	}
+	start := time.Unix(vals[2], 0).UTC()
+	return Claims{
+		BookingID: vals[0],
+		UnitID:    vals[1],
+		Start:     start,
+		End:       start.Add(time.Duration(vals[3]) * time.Second),
+	}, nil
+}
+
+// QRCode 将票据渲染为 PNG 二维码（size 为边长像素）
+func QRCode(token string, size int) ([]byte, error) {
+	png, err := qrcode.Encode(token, qrcode.Medium, size)
+	if err != nil {
+		return nil, fmt.Errorf("render qr code: %w", err)
+	}
+	return png, nil
+}
@@ -0,0 +1,61 @@
+package ticket
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 测试票据签发与校验：往返一致、篡改与错误密钥被拒绝、时间窗口
+func TestSignVerify(t *testing.T) {
+	s := NewSigner("secret")
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	c := Claims{BookingID: 1234, UnitID: 7, Start: start, End: start.Add(90 * time.Minute)}
+
+	token := s.Sign(c)
+	if len(token) > 64 {
+		t.Fatalf("token should be compact, got %d chars: %s", len(token), token)
+	}
+	got, err := s.Verify(token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if got != c {
+		t.Fatalf("expected %+v, got %+v", c, got)
+	}
+
+	if _, err := NewSigner("other").Verify(token); !errors.Is(err, ErrInvalidTicket) {
+		t.Fatalf("expected wrong key rejected, got %v", err)
+	}
+	forged := s.Sign(Claims{BookingID: 1235, UnitID: 7, Start: start, End: c.End})
+	mixed := token[:len(token)-22] + forged[len(forged)-22:]
+	if _, err := s.Verify(mixed); !errors.Is(err, ErrInvalidTicket) {
+		t.Fatalf("expected signature mismatch, got %v", err)
+	}
+	for _, bad := range []string{"", "T1.abc", "T2" + token[2:], token + "x"} {
+		if _, err := s.Verify(bad); !errors.Is(err, ErrInvalidTicket) {
+			t.Fatalf("expected %q rejected, got %v", bad, err)
+		}
+	}
+
+	if err := c.CheckWindow(start.Add(-31*time.Minute), 30*time.Minute); !errors.Is(err, ErrTicketNotValid) {
+		t.Fatalf("expected not valid yet, got %v", err)
+	}
+	if err := c.CheckWindow(start.Add(-30*time.Minute), 30*time.Minute); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+	if err := c.CheckWindow(c.End, 30*time.Minute); !errors.Is(err, ErrTicketExpired) {
+		t.Fatalf("expected expired, got %v", err)
+	}
+}
+
+func TestQRCode(t *testing.T) {
+	png, err := QRCode(NewSigner("secret").Sign(Claims{BookingID: 1, UnitID: 1, Start: time.Unix(0, 0), End: time.Unix(3600, 0)}), 256)
+	if err != nil {
+		t.Fatalf("qr: %v", err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Fatal("expected PNG output")
+	}
+}
@@ -2,13 +2,22 @@ package main
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 	"os"
+	"time"
+	_ "time/tzdata" // 内置时区数据，日历按场馆时区输出不依赖系统 zoneinfo
 
 	"github.com/Juny09/sport_backend/internal/auth"
 	"github.com/Juny09/sport_backend/internal/config"
+	"github.com/Juny09/sport_backend/internal/events"
 	httpserver "github.com/Juny09/sport_backend/internal/http"
+	"github.com/Juny09/sport_backend/internal/notify"
+	"github.com/Juny09/sport_backend/internal/payment"
 	"github.com/Juny09/sport_backend/internal/repo"
+	"github.com/Juny09/sport_backend/internal/service"
+	"github.com/Juny09/sport_backend/internal/storage"
+	"github.com/Juny09/sport_backend/internal/ticket"
 	"github.com/joho/godotenv"
 )
 
@@ -19,6 +28,7 @@ func main() {
 
 	// 使用默认日志；后续可替换为结构化日志
 	logger := config.NewLogger()
+	slog.SetDefault(logger)
 
 	cfg, err := config.Load()
 	if err != nil {
@@ -37,9 +47,89 @@ func main() {
 		os.Exit(1)
 	}
 	defer db.Close()
+	if cfg.SupabaseServiceKey != "" {
+		db.SetServiceRoleKey(cfg.SupabaseURL, cfg.SupabaseServiceKey)
+	} else {
+		logger.Warn("SUPABASE_SERVICE_ROLE_KEY not set; wallet payments are disabled and email notifications cannot resolve recipients")
+	}
 
 	// 初始化路由
-	r := httpserver.NewRouter(db, cfg.SupabaseJWTSecret, authClient)
+	opts := []httpserver.Option{
+		httpserver.WithStorage(storage.NewLocalStore(cfg.StorageDir, cfg.MediaBaseURL)),
+		httpserver.WithTrustedProxies(cfg.TrustedProxies),
+	}
+	var provider payment.Provider
+	switch cfg.PaymentProvider {
+	case "fake":
+		if cfg.PaymentSecret == "" {
+			logger.Error("PAYMENT_WEBHOOK_SECRET is required for the fake payment provider")
+			os.Exit(1)
+		}
+		provider = payment.NewFakeProvider(cfg.PaymentSecret)
+	case "none", "":
+	default:
+		logger.Error("unknown payment provider", "provider", cfg.PaymentProvider)
+		os.Exit(1)
+	}
+	if provider != nil {
+		opts = append(opts, httpserver.WithPayments(provider, cfg.PaymentCurrency))
+	}
+	if cfg.PaymentSimulate {
+		logger.Warn("payment simulation endpoints enabled; do not use in production")
+		opts = append(opts, httpserver.WithPaymentSimulation())
+	}
+	noShow := service.NoShowPolicy{
+		CheckInOpens: time.Duration(cfg.CheckInOpensMin) * time.Minute,
+		Grace:        time.Duration(cfg.NoShowGraceMin) * time.Minute,
+		BanThreshold: cfg.NoShowBanCount,
+		BanWindow:    time.Duration(cfg.NoShowWindowDays) * 24 * time.Hour,
+		BanDuration:  time.Duration(cfg.NoShowBanDays) * 24 * time.Hour,
+	}
+	opts = append(opts, httpserver.WithNoShowPolicy(noShow))
+	if cfg.TicketSecret != "" {
+		opts = append(opts, httpserver.WithTicketSigner(ticket.NewSigner(cfg.TicketSecret)))
+	}
+	// 邮件通知：配置 SMTP 时经发件箱投递，否则仅记录日志
+	var notifier notify.Notifier = notify.NewLogNotifier(logger)
+	var mailer notify.Mailer
+	if cfg.SMTPHost != "" {
+		notifier = service.NewOutboxNotifier(db)
+		mailer = notify.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	}
+	opts = append(opts, httpserver.WithNotifier(notifier))
+	// 预约与封场写操作发布的事件：写入 Webhook 投递队列，并经事件总线推送给实时订阅（多实例时经 broker 转发）
+	var broker events.Broker
+	switch cfg.EventBroker {
+	case "db":
+		b, err := service.NewDBBroker(db, service.BusPollInterval)
+		if err != nil {
+			logger.Error("event broker init error", "err", err)
+			os.Exit(1)
+		}
+		broker = b
+	case "local", "":
+	default:
+		logger.Error("unknown event broker", "broker", cfg.EventBroker)
+		os.Exit(1)
+	}
+	bus := events.NewBus(broker)
+	db.SetPublisher(events.Publishers{service.NewWebhookPublisher(db), bus})
+	opts = append(opts, httpserver.WithEventBus(bus))
+	go bus.Run(context.Background())
+	r := httpserver.NewRouter(db, cfg.SupabaseJWTSecret, authClient, opts...)
+
+	// 后台任务：释放超时未支付的预约占位与分摊逾期的预约
+	go service.RunPaymentHoldExpiry(context.Background(), db, notifier, provider, time.Minute)
+	// 后台任务：标记爽约预约并释放剩余时段
+	go service.RunNoShowSweep(context.Background(), db, noShow, time.Minute)
+	// 后台任务：开场前提醒与结束后评分邀请（多实例时仅租约持有者执行）
+	go service.RunReminderScheduler(context.Background(), db, notifier, time.Minute)
+	// 后台任务：投递对外 Webhook（失败按指数退避重试）
+	go service.RunWebhookDispatch(context.Background(), db, 15*time.Second)
+	// 后台任务：投递发件箱中的邮件
+	if mailer != nil {
+		go service.RunOutboxDispatch(context.Background(), db, mailer, 30*time.Second)
+	}
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,